@@ -1,12 +1,18 @@
 package account
 
 import (
+	"errors"
+	"time"
+
 	"github.com/gopherd/doge/service/module"
 
 	"github.com/gopherd/gopherd/auth"
+	"github.com/gopherd/gopherd/auth/config"
+	"github.com/gopherd/gopherd/auth/naming"
 )
 
 type Service interface {
+	Config() *config.Config
 	OOSModule() auth.OOSModule
 }
 
@@ -21,7 +27,8 @@ func New(service Service) interface {
 // accountModule implements auth.AccountModule
 type accountModule struct {
 	*module.BasicModule
-	service Service
+	service    Service
+	namePolicy *naming.Policy
 }
 
 func newAccountModule(service Service) *accountModule {
@@ -35,6 +42,9 @@ func (mod *accountModule) Init() error {
 	if err := mod.BasicModule.Init(); err != nil {
 		return err
 	}
+	if err := mod.initNamePolicy(); err != nil {
+		return err
+	}
 	return mod.service.OOSModule().CreateSchema(newAccount())
 }
 
@@ -42,9 +52,26 @@ func (mod *accountModule) Contains(by ...auth.Field) (bool, error) {
 	return mod.service.OOSModule().HasObject(tableName, by...)
 }
 
-func (mod *accountModule) Store(provider string, account auth.Account) error {
-	_, err := mod.service.OOSModule().UpdateObject(account)
-	return err
+// Store stores the account, another tag is assigned if the name and tag has
+// been taken by another account concurrently. The tag is stored with the name,
+// so a null tag is stored if the name assigned without discriminator.
+func (mod *accountModule) Store(provider string, account auth.Account, fields ...string) error {
+	var columns []any
+	for _, field := range fields {
+		columns = append(columns, field)
+		if field == fieldName {
+			columns = append(columns, fieldTag)
+		}
+	}
+	for i := 0; ; i++ {
+		_, err := mod.service.OOSModule().UpdateObject(account, columns...)
+		if !errors.Is(err, auth.ErrDuplicateKey) || account.GetTag() == 0 || i >= maxTagAttempts {
+			return err
+		}
+		if err := mod.assignTag(account, account.GetName()); err != nil {
+			return err
+		}
+	}
 }
 
 func (mod *accountModule) Load(by ...auth.Field) (auth.Account, error) {
@@ -74,3 +101,16 @@ func (mod *accountModule) LoadOrCreate(provider, key, device string) (auth.Accou
 	}
 	return a, true, nil
 }
+
+const registrationReportSQL = "SELECT DATE_FORMAT(`register_at`, '%Y-%m-%d') AS `day`," +
+	" `register_channel` AS `channel`, `register_source` AS `source`, `register_os` AS `os`, COUNT(*) AS `count`" +
+	" FROM `" + tableName + "` WHERE `register_at` >= ? AND `register_at` < ?" +
+	" GROUP BY `day`, `channel`, `source`, `os` ORDER BY `day`, `channel`, `source`, `os`"
+
+func (mod *accountModule) RegistrationReport(from, to time.Time) ([]auth.RegistrationStat, error) {
+	var stats []auth.RegistrationStat
+	if err := mod.service.OOSModule().Query(&stats, registrationReportSQL, from, to); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
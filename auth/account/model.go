@@ -2,25 +2,32 @@ package account
 
 import (
 	"time"
+
+	"github.com/gopherd/gopherd/auth"
 )
 
 const tableName = "account"
 
 // Account implements auth.Account
 type Account struct {
-	ID           int64                `gorm:"primaryKey;column:id"`
-	DeviceID     string               `gorm:"uniqueIndex;column:device_id;not null"`
-	Banned       bool                 `gorm:"column:banned"`
-	BannedReason string               `gorm:"column:banned_reason"`
-	RegisterAt   time.Time            `gorm:"column:register_at"`
-	RegisterIp   string               `gorm:"column:register_ip"`
-	LastLoginAt  time.Time            `gorm:"column:last_login_at"`
-	LastLoginIp  string               `gorm:"column:last_login_ip"`
-	Name         string               `gorm:"column:name"`
-	Avatar       string               `gorm:"column:avatar"`
-	Gender       int                  `gorm:"column:gender"`
-	Location     string               `gorm:"location"`
-	Providers    map[string]*provider `gorm:"-"`
+	ID              int64                `gorm:"primaryKey;column:id"`
+	DeviceID        string               `gorm:"uniqueIndex;column:device_id;not null"`
+	Banned          bool                 `gorm:"column:banned"`
+	BannedReason    string               `gorm:"column:banned_reason"`
+	RegisterAt      time.Time            `gorm:"index;column:register_at"`
+	RegisterIp      string               `gorm:"column:register_ip"`
+	RegisterChannel int                  `gorm:"column:register_channel"`
+	RegisterSource  string               `gorm:"column:register_source"`
+	RegisterOS      string               `gorm:"column:register_os"`
+	RegisterModel   string               `gorm:"column:register_model"`
+	LastLoginAt     time.Time            `gorm:"column:last_login_at"`
+	LastLoginIp     string               `gorm:"column:last_login_ip"`
+	Name            string               `gorm:"uniqueIndex:idx_name_tag;column:name"`
+	Tag             *int                 `gorm:"uniqueIndex:idx_name_tag;column:tag"` // null if no discriminator, so names without tags aren't unique
+	Avatar          string               `gorm:"column:avatar"`
+	Gender          int                  `gorm:"column:gender"`
+	Location        string               `gorm:"location"`
+	Providers       map[string]*provider `gorm:"-"`
 }
 
 func newAccount() *Account {
@@ -59,6 +66,39 @@ func (a *Account) GetProviders() map[string]string {
 	return m
 }
 
+func (a *Account) GetAttribution() auth.Attribution {
+	return auth.Attribution{
+		Channel: a.RegisterChannel,
+		Source:  a.RegisterSource,
+		OS:      a.RegisterOS,
+		Model:   a.RegisterModel,
+	}
+}
+
+func (a *Account) SetAttribution(x auth.Attribution) {
+	a.RegisterChannel = x.Channel
+	a.RegisterSource = x.Source
+	a.RegisterOS = x.OS
+	a.RegisterModel = x.Model
+}
+
+// GetTag returns 0 if the tag is null
+func (a *Account) GetTag() int {
+	if a.Tag == nil {
+		return 0
+	}
+	return *a.Tag
+}
+
+// SetTag sets the tag null if x is 0
+func (a *Account) SetTag(x int) {
+	if x == 0 {
+		a.Tag = nil
+	} else {
+		a.Tag = &x
+	}
+}
+
 type provider struct {
 	ID       int64  `gorm:"primaryKey;column:id"`
 	Uid      int64  `gorm:"column:uid;not null"`
@@ -0,0 +1,82 @@
+package account
+
+import (
+	"math/rand"
+	"os"
+	"strconv"
+
+	"github.com/gopherd/doge/erron"
+
+	"github.com/gopherd/gopherd/auth"
+	"github.com/gopherd/gopherd/auth/api"
+	"github.com/gopherd/gopherd/auth/naming"
+)
+
+const (
+	fieldName = "name"
+	fieldTag  = "tag"
+
+	maxTag         = 9999
+	maxTagAttempts = 16
+)
+
+var errNameUnavailable = erron.Errnof(api.NameUnavailable, "name unavailable")
+
+func (mod *accountModule) initNamePolicy() error {
+	cfg := mod.service.Config().Naming
+	var words []string
+	if cfg.WordsFilepath != "" {
+		f, err := os.Open(cfg.WordsFilepath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if words, err = naming.ReadWords(f); err != nil {
+			return err
+		}
+	}
+	mod.namePolicy = naming.New(cfg.Policy, words...)
+	return nil
+}
+
+func (mod *accountModule) ValidateName(name string) (string, error) {
+	return mod.namePolicy.Validate(name)
+}
+
+func (mod *accountModule) SanitizeName(name string) string {
+	return mod.namePolicy.Sanitize(name)
+}
+
+// AssignName allocates a random tag which is not used by other accounts with
+// the same name. Concurrent assignments may allocate the same tag rarely, which
+// is rejected by the unique index and another tag is assigned by Store.
+func (mod *accountModule) AssignName(account auth.Account, name string) error {
+	if !mod.service.Config().Naming.Discriminator {
+		account.SetName(name)
+		account.SetTag(0)
+		return nil
+	}
+	if name == account.GetName() && account.GetTag() > 0 {
+		return nil
+	}
+	return mod.assignTag(account, name)
+}
+
+func (mod *accountModule) assignTag(account auth.Account, name string) error {
+	for i := 0; i < maxTagAttempts; i++ {
+		tag := 1 + rand.Intn(maxTag)
+		found, err := mod.service.OOSModule().HasObject(tableName,
+			auth.Field{Name: fieldName, Value: name},
+			auth.Field{Name: fieldTag, Value: strconv.Itoa(tag)},
+		)
+		if err != nil {
+			return err
+		}
+		if !found {
+			account.SetName(name)
+			account.SetTag(tag)
+			return nil
+		}
+	}
+	return errNameUnavailable
+}
@@ -0,0 +1,152 @@
+package account
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gopherd/gopherd/auth"
+	"github.com/gopherd/gopherd/auth/config"
+)
+
+// testOOS stores an account row like gorm: non-zero fields are updated
+// unless fields selected. It rejects the first updates by duplicate key errors.
+type testOOS struct {
+	auth.OOSModule
+	duplicates int
+	updates    int
+	row        Account
+}
+
+func (oos *testOOS) GetObject(obj auth.Object, by ...auth.Field) (bool, error) {
+	*obj.(*Account) = oos.row
+	return true, nil
+}
+
+func (oos *testOOS) HasObject(tableName string, by ...auth.Field) (bool, error) {
+	return false, nil
+}
+
+func (oos *testOOS) UpdateObject(obj auth.Object, fields ...any) (int64, error) {
+	oos.updates++
+	if oos.updates <= oos.duplicates {
+		return 0, fmt.Errorf("%w: name and tag", auth.ErrDuplicateKey)
+	}
+	selected := make(map[string]bool)
+	for _, field := range fields {
+		selected[field.(string)] = true
+	}
+	src := reflect.ValueOf(obj).Elem()
+	dst := reflect.ValueOf(&oos.row).Elem()
+	for i := 0; i < src.NumField(); i++ {
+		f := src.Type().Field(i)
+		column := strings.ToLower(f.Name)
+		for _, x := range strings.Split(f.Tag.Get("gorm"), ";") {
+			if strings.HasPrefix(x, "column:") {
+				column = x[len("column:"):]
+			}
+		}
+		if len(fields) > 0 && selected[column] || len(fields) == 0 && !src.Field(i).IsZero() {
+			dst.Field(i).Set(src.Field(i))
+		}
+	}
+	return 1, nil
+}
+
+type testService struct {
+	config *config.Config
+	oos    *testOOS
+}
+
+func (s *testService) Config() *config.Config    { return s.config }
+func (s *testService) OOSModule() auth.OOSModule { return s.oos }
+
+func TestStoreRetriesDuplicateTag(t *testing.T) {
+	cfg := new(config.Config).Default().(*config.Config)
+	for _, tc := range []struct {
+		discriminator bool
+		duplicates    int
+		updates       int
+		ok            bool
+	}{
+		{true, 0, 1, true},
+		{true, 2, 3, true},
+		{true, maxTagAttempts + 1, maxTagAttempts + 1, false},
+		{false, 1, 1, false},
+	} {
+		cfg.Naming.Discriminator = tc.discriminator
+		service := &testService{config: cfg, oos: &testOOS{duplicates: tc.duplicates}}
+		mod := newAccountModule(service)
+		a := newAccount()
+		if err := mod.AssignName(a, "gopher"); err != nil {
+			t.Fatalf("assign name error: %v", err)
+		}
+		err := mod.Store("", a)
+		if (err == nil) != tc.ok {
+			t.Fatalf("discriminator %v duplicates %d: want ok %v, got error %v", tc.discriminator, tc.duplicates, tc.ok, err)
+		}
+		if service.oos.updates != tc.updates {
+			t.Fatalf("discriminator %v duplicates %d: want %d updates, got %d", tc.discriminator, tc.duplicates, tc.updates, service.oos.updates)
+		}
+	}
+}
+
+func TestStoreFields(t *testing.T) {
+	cfg := new(config.Config).Default().(*config.Config)
+	service := &testService{config: cfg, oos: new(testOOS)}
+	mod := newAccountModule(service)
+	a := newAccount()
+	a.SetName("gopher")
+	a.SetAvatar("https://example.com/a.png")
+	a.SetGender(1)
+	a.SetLocation("Tokyo")
+	if err := mod.Store("", a); err != nil {
+		t.Fatalf("store error: %v", err)
+	}
+
+	// clears fields
+	a.SetAvatar("")
+	a.SetGender(0)
+	if err := mod.Store("", a, "avatar", "gender"); err != nil {
+		t.Fatalf("store fields error: %v", err)
+	}
+	loaded, err := mod.Load(auth.ByID(a.GetID()))
+	if err != nil {
+		t.Fatalf("load error: %v", err)
+	}
+	if loaded.GetName() != "gopher" || loaded.GetAvatar() != "" || loaded.GetGender() != 0 || loaded.GetLocation() != "Tokyo" {
+		t.Fatalf("unexpected account %+v", loaded)
+	}
+}
+
+func TestStoreRename(t *testing.T) {
+	for _, discriminator := range []bool{true, false} {
+		cfg := new(config.Config).Default().(*config.Config)
+		cfg.Naming.Discriminator = true
+		service := &testService{config: cfg, oos: new(testOOS)}
+		mod := newAccountModule(service)
+		a := newAccount()
+		if err := mod.AssignName(a, "gopher"); err != nil {
+			t.Fatalf("assign name error: %v", err)
+		}
+		if err := mod.Store("", a); err != nil {
+			t.Fatalf("store error: %v", err)
+		}
+
+		cfg.Naming.Discriminator = discriminator
+		if err := mod.AssignName(a, "gordon"); err != nil {
+			t.Fatalf("assign name error: %v", err)
+		}
+		if err := mod.Store("", a, "name"); err != nil {
+			t.Fatalf("store name error: %v", err)
+		}
+		loaded, err := mod.Load(auth.ByID(a.GetID()))
+		if err != nil {
+			t.Fatalf("load error: %v", err)
+		}
+		if loaded.GetName() != "gordon" || loaded.GetTag() != a.GetTag() || (a.GetTag() > 0) != discriminator {
+			t.Fatalf("discriminator %v: want gordon#%d, got %s#%d", discriminator, a.GetTag(), loaded.GetName(), loaded.GetTag())
+		}
+	}
+}
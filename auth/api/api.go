@@ -4,6 +4,7 @@
 package api
 
 import (
+	"context"
 	"net/http"
 	"net/url"
 
@@ -14,6 +15,35 @@ var (
 	_ = query.ParseURL
 	_ = http.MethodGet
 	_ url.Values
+	_ context.Context
+)
+
+// Default routes of apis
+const (
+	RouteAuthorize          = "/auth/authorize"
+	RouteLink               = "/auth/link"
+	RouteSmsCode            = "/auth/smscode"
+	RouteProfile            = "/auth/profile"
+	RouteVerify2fa          = "/auth/verify2fa"
+	RouteTwoFactorEnroll    = "/auth/2fa/enroll"
+	RouteTwoFactorActivate  = "/auth/2fa/activate"
+	RouteTwoFactorDisable   = "/auth/2fa/disable"
+	RouteWebAuthnRegister   = "/auth/webauthn/register"
+	RouteWebAuthnLogin      = "/auth/webauthn/login"
+	RouteRefresh            = "/auth/refresh"
+	RouteSessions           = "/auth/sessions"
+	RouteRevokeSession      = "/auth/sessions/revoke"
+	RouteToken              = "/auth/token"
+	RouteIntrospect         = "/auth/introspect"
+	RouteUserinfo           = "/auth/userinfo"
+	RouteJWKS               = "/auth/jwks"         // JSON Web Key Set, not a protocol
+	RouteOpenAPI            = "/auth/openapi.json" // OpenAPI document, not a protocol
+	RouteMetrics            = "/metrics"           // prometheus metrics, not a protocol
+	RouteRegistrationReport = "/auth/report/registrations"
+	RouteRoles              = "/auth/admin/roles"
+	RouteGrantRole          = "/auth/admin/roles/grant"
+	RouteRevokeRole         = "/auth/admin/roles/revoke"
+	RouteImpersonate        = "/auth/admin/impersonate"
 )
 
 const (
@@ -24,129 +54,1461 @@ const (
 
 // Authorize
 type AuthorizeRequest struct {
-	Channel int    `json:"channel"`
-	Type    string `json:"type"`
-	Account string `json:"account"`
-	Secret  string `json:"secret"`
-	Device  string `json:"device"`
-	Os      string `json:"os"`
-	Model   string `json:"model"`
-	Source  string `json:"source"`
-	Name    string `json:"name"`
-	Avatar  string `json:"avatar"`
-	Gender  int    `json:"gender"`
+	Channel   int    `json:"channel" required:"true"`
+	Type      string `json:"type" required:"true"`
+	Account   string `json:"account" required:"true"`
+	Secret    string `json:"secret,omitempty"`
+	Device    string `json:"device,omitempty"`
+	Os        string `json:"os,omitempty"`
+	Model     string `json:"model,omitempty"`
+	Source    string `json:"source,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Avatar    string `json:"avatar,omitempty"`
+	Gender    int    `json:"gender,omitempty"`
+	Scope     string `json:"scope,omitempty"`     // space-separated requested scopes, e.g. "game chat"
+	Challenge string `json:"challenge,omitempty"` // challenge of ChallengeRequiredResponse
+	Solution  string `json:"solution,omitempty"`  // solution of the challenge
+	State     string `json:"state,omitempty"`     // state of ChallengeRequiredResponse, resumes the risky authorize without secret
 }
 
-func (argv *AuthorizeRequest) form(r *http.Request) url.Values {
-	const defaultMaxMemory = 32 << 20 // 32 MB
-	if r.Form == nil {
-		r.ParseMultipartForm(defaultMaxMemory)
-	}
-	return r.Form
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *AuthorizeRequest) AppendProto(b []byte) []byte {
+	b = appendInt(b, 0, int64(x.Channel))
+	b = appendString(b, 1, x.Type)
+	b = appendString(b, 2, x.Account)
+	b = appendString(b, 3, x.Secret)
+	b = appendString(b, 4, x.Device)
+	b = appendString(b, 5, x.Os)
+	b = appendString(b, 6, x.Model)
+	b = appendString(b, 7, x.Source)
+	b = appendString(b, 8, x.Name)
+	b = appendString(b, 9, x.Avatar)
+	b = appendInt(b, 10, int64(x.Gender))
+	b = appendString(b, 11, x.Scope)
+	b = appendString(b, 12, x.Challenge)
+	b = appendString(b, 13, x.Solution)
+	b = appendString(b, 14, x.State)
+	return b
 }
 
+// Parse parses arguments from form, JSON or protobuf body of the request
 func (argv *AuthorizeRequest) Parse(r *http.Request) error {
-	var err error
-	if argv.Channel, err = query.RequiredInt(argv.form(r), "channel"); err != nil {
+	form, err := parseForm(r,
+		"channel",
+		"type",
+		"account",
+		"secret",
+		"device",
+		"os",
+		"model",
+		"source",
+		"name",
+		"avatar",
+		"gender",
+		"scope",
+		"challenge",
+		"solution",
+		"state",
+	)
+	if err != nil {
 		return err
 	}
-	if argv.Type, err = query.RequiredString(argv.form(r), "type"); err != nil {
+	if argv.Channel, err = query.RequiredInt(form, "channel"); err != nil {
 		return err
 	}
-	if argv.Account, err = query.RequiredString(argv.form(r), "account"); err != nil {
+	if argv.Type, err = query.RequiredString(form, "type"); err != nil {
 		return err
 	}
-	argv.Secret = query.String(argv.form(r), "secret", "")
-	argv.Device = query.String(argv.form(r), "device", "")
-	argv.Os = query.String(argv.form(r), "os", "")
-	argv.Model = query.String(argv.form(r), "model", "")
-	argv.Source = query.String(argv.form(r), "source", "")
-	argv.Name = query.String(argv.form(r), "name", "")
-	argv.Avatar = query.String(argv.form(r), "avatar", "")
-	if argv.Gender, err = query.Int(argv.form(r), "gender", 0); err != nil {
+	if argv.Account, err = query.RequiredString(form, "account"); err != nil {
 		return err
 	}
+	argv.Secret = query.String(form, "secret", "")
+	argv.Device = query.String(form, "device", "")
+	argv.Os = query.String(form, "os", "")
+	argv.Model = query.String(form, "model", "")
+	argv.Source = query.String(form, "source", "")
+	argv.Name = query.String(form, "name", "")
+	argv.Avatar = query.String(form, "avatar", "")
+	if argv.Gender, err = query.Int(form, "gender", 0); err != nil {
+		return err
+	}
+	argv.Scope = query.String(form, "scope", "")
+	argv.Challenge = query.String(form, "challenge", "")
+	argv.Solution = query.String(form, "solution", "")
+	argv.State = query.String(form, "state", "")
 	return err
 }
 
+// Authorize calls the api routed by RouteAuthorize
+func (c *Client) Authorize(ctx context.Context, req *AuthorizeRequest) (*AuthorizeResponse, error) {
+	resp := new(AuthorizeResponse)
+	if err := c.Call(ctx, RouteAuthorize, req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func init() {
+	register("Authorize", RouteAuthorize, "// Authorize\n", new(AuthorizeRequest), new(AuthorizeResponse))
+}
+
 type AuthorizeResponse struct {
 	Channel               int               `json:"channel"`
 	AccessToken           string            `json:"access_token"`
 	AccessTokenExpiredAt  int64             `json:"access_token_expired_at"`
 	RefreshToken          string            `json:"refresh_token"`
 	RefreshTokenExpiredAt int64             `json:"refresh_token_expired_at"`
+	Scope                 string            `json:"scope"` // granted scopes
 	OpenId                string            `json:"open_id"`
 	Providers             map[string]string `json:"providers"`
 }
 
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *AuthorizeResponse) AppendProto(b []byte) []byte {
+	b = appendInt(b, 0, int64(x.Channel))
+	b = appendString(b, 1, x.AccessToken)
+	b = appendInt(b, 2, x.AccessTokenExpiredAt)
+	b = appendString(b, 3, x.RefreshToken)
+	b = appendInt(b, 4, x.RefreshTokenExpiredAt)
+	b = appendString(b, 5, x.Scope)
+	b = appendString(b, 6, x.OpenId)
+	b = appendStringMap(b, 7, x.Providers)
+	return b
+}
+
 // Link account
 type LinkRequest struct {
-	Type    string `json:"type"`
-	Token   string `json:"token"`
-	Account string `json:"account"`
-	Secret  string `json:"secret"`
-	Name    string `json:"name"`
-	Avatar  string `json:"avatar"`
-	Gender  int    `json:"gender"`
+	Type    string `json:"type" required:"true"`
+	Token   string `json:"token" required:"true"`
+	Account string `json:"account" required:"true"`
+	Secret  string `json:"secret,omitempty"`
+	Name    string `json:"name,omitempty"`
+	Avatar  string `json:"avatar,omitempty"`
+	Gender  int    `json:"gender,omitempty"`
 }
 
-func (argv *LinkRequest) form(r *http.Request) url.Values {
-	const defaultMaxMemory = 32 << 20 // 32 MB
-	if r.Form == nil {
-		r.ParseMultipartForm(defaultMaxMemory)
-	}
-	return r.Form
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *LinkRequest) AppendProto(b []byte) []byte {
+	b = appendString(b, 0, x.Type)
+	b = appendString(b, 1, x.Token)
+	b = appendString(b, 2, x.Account)
+	b = appendString(b, 3, x.Secret)
+	b = appendString(b, 4, x.Name)
+	b = appendString(b, 5, x.Avatar)
+	b = appendInt(b, 6, int64(x.Gender))
+	return b
 }
 
+// Parse parses arguments from form, JSON or protobuf body of the request
 func (argv *LinkRequest) Parse(r *http.Request) error {
-	var err error
-	if argv.Type, err = query.RequiredString(argv.form(r), "type"); err != nil {
+	form, err := parseForm(r,
+		"type",
+		"token",
+		"account",
+		"secret",
+		"name",
+		"avatar",
+		"gender",
+	)
+	if err != nil {
 		return err
 	}
-	if argv.Token, err = query.RequiredString(argv.form(r), "token"); err != nil {
+	if argv.Type, err = query.RequiredString(form, "type"); err != nil {
 		return err
 	}
-	if argv.Account, err = query.RequiredString(argv.form(r), "account"); err != nil {
+	if argv.Token, err = query.RequiredString(form, "token"); err != nil {
 		return err
 	}
-	argv.Secret = query.String(argv.form(r), "secret", "")
-	argv.Name = query.String(argv.form(r), "name", "")
-	argv.Avatar = query.String(argv.form(r), "avatar", "")
-	if argv.Gender, err = query.Int(argv.form(r), "gender", 0); err != nil {
+	if argv.Account, err = query.RequiredString(form, "account"); err != nil {
+		return err
+	}
+	argv.Secret = query.String(form, "secret", "")
+	argv.Name = query.String(form, "name", "")
+	argv.Avatar = query.String(form, "avatar", "")
+	if argv.Gender, err = query.Int(form, "gender", 0); err != nil {
 		return err
 	}
 	return err
 }
 
+// Link calls the api routed by RouteLink
+func (c *Client) Link(ctx context.Context, req *LinkRequest) (*LinkResponse, error) {
+	resp := new(LinkResponse)
+	if err := c.Call(ctx, RouteLink, req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func init() {
+	register("Link", RouteLink, "// Link account\n", new(LinkRequest), new(LinkResponse))
+}
+
 type LinkResponse struct {
 	OpenId string `json:"open_id"`
 }
 
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *LinkResponse) AppendProto(b []byte) []byte {
+	b = appendString(b, 0, x.OpenId)
+	return b
+}
+
 // SMS code
 type SmsCodeRequest struct {
-	Channel int    `json:"channel"`
-	Mobile  string `json:"mobile"`
+	Channel   int    `json:"channel" required:"true"`
+	Mobile    string `json:"mobile" required:"true"`
+	Challenge string `json:"challenge,omitempty"` // challenge of ChallengeRequiredResponse
+	Solution  string `json:"solution,omitempty"`  // solution of the challenge
 }
 
-func (argv *SmsCodeRequest) form(r *http.Request) url.Values {
-	const defaultMaxMemory = 32 << 20 // 32 MB
-	if r.Form == nil {
-		r.ParseMultipartForm(defaultMaxMemory)
-	}
-	return r.Form
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *SmsCodeRequest) AppendProto(b []byte) []byte {
+	b = appendInt(b, 0, int64(x.Channel))
+	b = appendString(b, 1, x.Mobile)
+	b = appendString(b, 2, x.Challenge)
+	b = appendString(b, 3, x.Solution)
+	return b
 }
 
+// Parse parses arguments from form, JSON or protobuf body of the request
 func (argv *SmsCodeRequest) Parse(r *http.Request) error {
-	var err error
-	if argv.Channel, err = query.RequiredInt(argv.form(r), "channel"); err != nil {
+	form, err := parseForm(r,
+		"channel",
+		"mobile",
+		"challenge",
+		"solution",
+	)
+	if err != nil {
+		return err
+	}
+	if argv.Channel, err = query.RequiredInt(form, "channel"); err != nil {
 		return err
 	}
-	if argv.Mobile, err = query.RequiredString(argv.form(r), "mobile"); err != nil {
+	if argv.Mobile, err = query.RequiredString(form, "mobile"); err != nil {
 		return err
 	}
+	argv.Challenge = query.String(form, "challenge", "")
+	argv.Solution = query.String(form, "solution", "")
 	return err
 }
 
+// SmsCode calls the api routed by RouteSmsCode
+func (c *Client) SmsCode(ctx context.Context, req *SmsCodeRequest) (*SmsCodeResponse, error) {
+	resp := new(SmsCodeResponse)
+	if err := c.Call(ctx, RouteSmsCode, req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func init() {
+	register("SmsCode", RouteSmsCode, "// SMS code\n", new(SmsCodeRequest), new(SmsCodeResponse))
+}
+
 type SmsCodeResponse struct {
 	Seconds int `json:"seconds"`
 }
+
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *SmsCodeResponse) AppendProto(b []byte) []byte {
+	b = appendInt(b, 0, int64(x.Seconds))
+	return b
+}
+
+// Profile: GET reads profile of the token's account,
+// POST updates fields present in the request and responds the updated profile
+type ProfileRequest struct {
+	Token    string `json:"token,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Avatar   string `json:"avatar,omitempty"`
+	Gender   int    `json:"gender,omitempty"`
+	Location string `json:"location,omitempty"`
+}
+
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *ProfileRequest) AppendProto(b []byte) []byte {
+	b = appendString(b, 0, x.Token)
+	b = appendString(b, 1, x.Name)
+	b = appendString(b, 2, x.Avatar)
+	b = appendInt(b, 3, int64(x.Gender))
+	b = appendString(b, 4, x.Location)
+	return b
+}
+
+// Parse parses arguments from form, JSON or protobuf body of the request
+func (argv *ProfileRequest) Parse(r *http.Request) error {
+	form, err := parseForm(r,
+		"token",
+		"name",
+		"avatar",
+		"gender",
+		"location",
+	)
+	if err != nil {
+		return err
+	}
+	argv.Token = query.String(form, "token", "")
+	argv.Name = query.String(form, "name", "")
+	argv.Avatar = query.String(form, "avatar", "")
+	if argv.Gender, err = query.Int(form, "gender", 0); err != nil {
+		return err
+	}
+	argv.Location = query.String(form, "location", "")
+	return err
+}
+
+// Profile calls the api routed by RouteProfile
+func (c *Client) Profile(ctx context.Context, req *ProfileRequest) (*ProfileResponse, error) {
+	resp := new(ProfileResponse)
+	if err := c.Call(ctx, RouteProfile, req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func init() {
+	register("Profile", RouteProfile, "// Profile: GET reads profile of the token's account,\n// POST updates fields present in the request and responds the updated profile\n", new(ProfileRequest), new(ProfileResponse))
+}
+
+type ProfileResponse struct {
+	Id       int64  `json:"id"`
+	Name     string `json:"name"`
+	Tag      int    `json:"tag"` // discriminator of name, 0 if disabled
+	Avatar   string `json:"avatar"`
+	Gender   int    `json:"gender"`
+	Location string `json:"location"`
+}
+
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *ProfileResponse) AppendProto(b []byte) []byte {
+	b = appendInt(b, 0, x.Id)
+	b = appendString(b, 1, x.Name)
+	b = appendInt(b, 2, int64(x.Tag))
+	b = appendString(b, 3, x.Avatar)
+	b = appendInt(b, 4, int64(x.Gender))
+	b = appendString(b, 5, x.Location)
+	return b
+}
+
+// Second factor required: responded by authorize if two-factor authentication
+// enabled, the challenge should be sent to verify2fa with a code
+type SecondFactorRequiredResponse struct {
+	Error              int    `json:"error"`
+	Description        string `json:"description"`
+	Challenge          string `json:"challenge"`
+	ChallengeExpiredAt int64  `json:"challenge_expired_at"`
+	Message            string `json:"message"` // localized message for users
+}
+
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *SecondFactorRequiredResponse) AppendProto(b []byte) []byte {
+	b = appendInt(b, 0, int64(x.Error))
+	b = appendString(b, 1, x.Description)
+	b = appendString(b, 2, x.Challenge)
+	b = appendInt(b, 3, x.ChallengeExpiredAt)
+	b = appendString(b, 4, x.Message)
+	return b
+}
+
+// Rate limited: responded by apis if requests exceed rate limits, the
+// Retry-After header is set too
+type RateLimitedResponse struct {
+	Error       int    `json:"error"`
+	Description string `json:"description"`
+	RetryAfter  int    `json:"retry_after"` // seconds
+	Message     string `json:"message"`     // localized message for users
+}
+
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *RateLimitedResponse) AppendProto(b []byte) []byte {
+	b = appendInt(b, 0, int64(x.Error))
+	b = appendString(b, 1, x.Description)
+	b = appendInt(b, 2, int64(x.RetryAfter))
+	b = appendString(b, 3, x.Message)
+	return b
+}
+
+// Challenge required: responded by smscode and risky authorize if challenges
+// enabled, the request should be sent again with challenge and solution,
+// and state if responded by authorize, in which case secret isn't required.
+// Solution of a pow challenge is a string which makes
+// sha256(challenge + ":" + solution) begin with difficulty zero bits, and
+// solution of a captcha is the response token of the captcha widget.
+type ChallengeRequiredResponse struct {
+	Error              int    `json:"error"`
+	Description        string `json:"description"`
+	Type               string `json:"type"`       // pow or captcha
+	Challenge          string `json:"challenge"`  // empty for captcha
+	Difficulty         int    `json:"difficulty"` // leading zero bits of pow
+	SiteKey            string `json:"site_key"`   // site key of captcha
+	ChallengeExpiredAt int64  `json:"challenge_expired_at"`
+	Message            string `json:"message"` // localized message for users
+	State              string `json:"state"`   // state of the risky authorize, posted back with the solution
+}
+
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *ChallengeRequiredResponse) AppendProto(b []byte) []byte {
+	b = appendInt(b, 0, int64(x.Error))
+	b = appendString(b, 1, x.Description)
+	b = appendString(b, 2, x.Type)
+	b = appendString(b, 3, x.Challenge)
+	b = appendInt(b, 4, int64(x.Difficulty))
+	b = appendString(b, 5, x.SiteKey)
+	b = appendInt(b, 6, x.ChallengeExpiredAt)
+	b = appendString(b, 7, x.Message)
+	b = appendString(b, 8, x.State)
+	return b
+}
+
+// Verify second factor and responds AuthorizeResponse, Verify2faResponse is
+// an alias of AuthorizeResponse
+type Verify2faRequest struct {
+	Challenge string `json:"challenge" required:"true"`
+	Code      string `json:"code" required:"true"` // TOTP code or recovery code
+}
+
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *Verify2faRequest) AppendProto(b []byte) []byte {
+	b = appendString(b, 0, x.Challenge)
+	b = appendString(b, 1, x.Code)
+	return b
+}
+
+// Parse parses arguments from form, JSON or protobuf body of the request
+func (argv *Verify2faRequest) Parse(r *http.Request) error {
+	form, err := parseForm(r,
+		"challenge",
+		"code",
+	)
+	if err != nil {
+		return err
+	}
+	if argv.Challenge, err = query.RequiredString(form, "challenge"); err != nil {
+		return err
+	}
+	if argv.Code, err = query.RequiredString(form, "code"); err != nil {
+		return err
+	}
+	return err
+}
+
+// Verify2fa calls the api routed by RouteVerify2fa
+func (c *Client) Verify2fa(ctx context.Context, req *Verify2faRequest) (*Verify2faResponse, error) {
+	resp := new(Verify2faResponse)
+	if err := c.Call(ctx, RouteVerify2fa, req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func init() {
+	register("Verify2fa", RouteVerify2fa, "// Verify second factor and responds AuthorizeResponse, Verify2faResponse is\n// an alias of AuthorizeResponse\n", new(Verify2faRequest), new(Verify2faResponse))
+}
+
+// Two-factor authentication enrollment, the secret takes effect after activated
+type TwoFactorEnrollRequest struct {
+	Token string `json:"token,omitempty"`
+}
+
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *TwoFactorEnrollRequest) AppendProto(b []byte) []byte {
+	b = appendString(b, 0, x.Token)
+	return b
+}
+
+// Parse parses arguments from form, JSON or protobuf body of the request
+func (argv *TwoFactorEnrollRequest) Parse(r *http.Request) error {
+	form, err := parseForm(r,
+		"token",
+	)
+	if err != nil {
+		return err
+	}
+	argv.Token = query.String(form, "token", "")
+	return err
+}
+
+// TwoFactorEnroll calls the api routed by RouteTwoFactorEnroll
+func (c *Client) TwoFactorEnroll(ctx context.Context, req *TwoFactorEnrollRequest) (*TwoFactorEnrollResponse, error) {
+	resp := new(TwoFactorEnrollResponse)
+	if err := c.Call(ctx, RouteTwoFactorEnroll, req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func init() {
+	register("TwoFactorEnroll", RouteTwoFactorEnroll, "// Two-factor authentication enrollment, the secret takes effect after activated\n", new(TwoFactorEnrollRequest), new(TwoFactorEnrollResponse))
+}
+
+type TwoFactorEnrollResponse struct {
+	Secret        string   `json:"secret"`
+	Uri           string   `json:"uri"` // otpauth uri
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *TwoFactorEnrollResponse) AppendProto(b []byte) []byte {
+	b = appendString(b, 0, x.Secret)
+	b = appendString(b, 1, x.Uri)
+	b = appendStrings(b, 2, x.RecoveryCodes)
+	return b
+}
+
+// Activate two-factor authentication
+type TwoFactorActivateRequest struct {
+	Token string `json:"token,omitempty"`
+	Code  string `json:"code" required:"true"`
+}
+
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *TwoFactorActivateRequest) AppendProto(b []byte) []byte {
+	b = appendString(b, 0, x.Token)
+	b = appendString(b, 1, x.Code)
+	return b
+}
+
+// Parse parses arguments from form, JSON or protobuf body of the request
+func (argv *TwoFactorActivateRequest) Parse(r *http.Request) error {
+	form, err := parseForm(r,
+		"token",
+		"code",
+	)
+	if err != nil {
+		return err
+	}
+	argv.Token = query.String(form, "token", "")
+	if argv.Code, err = query.RequiredString(form, "code"); err != nil {
+		return err
+	}
+	return err
+}
+
+// TwoFactorActivate calls the api routed by RouteTwoFactorActivate
+func (c *Client) TwoFactorActivate(ctx context.Context, req *TwoFactorActivateRequest) (*TwoFactorActivateResponse, error) {
+	resp := new(TwoFactorActivateResponse)
+	if err := c.Call(ctx, RouteTwoFactorActivate, req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func init() {
+	register("TwoFactorActivate", RouteTwoFactorActivate, "// Activate two-factor authentication\n", new(TwoFactorActivateRequest), new(TwoFactorActivateResponse))
+}
+
+type TwoFactorActivateResponse struct {
+	Enabled bool `json:"enabled"`
+}
+
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *TwoFactorActivateResponse) AppendProto(b []byte) []byte {
+	b = appendBool(b, 0, x.Enabled)
+	return b
+}
+
+// Disable two-factor authentication
+type TwoFactorDisableRequest struct {
+	Token string `json:"token,omitempty"`
+	Code  string `json:"code" required:"true"`
+}
+
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *TwoFactorDisableRequest) AppendProto(b []byte) []byte {
+	b = appendString(b, 0, x.Token)
+	b = appendString(b, 1, x.Code)
+	return b
+}
+
+// Parse parses arguments from form, JSON or protobuf body of the request
+func (argv *TwoFactorDisableRequest) Parse(r *http.Request) error {
+	form, err := parseForm(r,
+		"token",
+		"code",
+	)
+	if err != nil {
+		return err
+	}
+	argv.Token = query.String(form, "token", "")
+	if argv.Code, err = query.RequiredString(form, "code"); err != nil {
+		return err
+	}
+	return err
+}
+
+// TwoFactorDisable calls the api routed by RouteTwoFactorDisable
+func (c *Client) TwoFactorDisable(ctx context.Context, req *TwoFactorDisableRequest) (*TwoFactorDisableResponse, error) {
+	resp := new(TwoFactorDisableResponse)
+	if err := c.Call(ctx, RouteTwoFactorDisable, req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func init() {
+	register("TwoFactorDisable", RouteTwoFactorDisable, "// Disable two-factor authentication\n", new(TwoFactorDisableRequest), new(TwoFactorDisableResponse))
+}
+
+type TwoFactorDisableResponse struct {
+	Enabled bool `json:"enabled"`
+}
+
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *TwoFactorDisableResponse) AppendProto(b []byte) []byte {
+	b = appendBool(b, 0, x.Enabled)
+	return b
+}
+
+// WebAuthn registration options, the registration completes by linking
+// with type webauthn. Binary fields are base64url encoded.
+type WebAuthnRegisterRequest struct {
+	Token string `json:"token,omitempty"`
+}
+
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *WebAuthnRegisterRequest) AppendProto(b []byte) []byte {
+	b = appendString(b, 0, x.Token)
+	return b
+}
+
+// Parse parses arguments from form, JSON or protobuf body of the request
+func (argv *WebAuthnRegisterRequest) Parse(r *http.Request) error {
+	form, err := parseForm(r,
+		"token",
+	)
+	if err != nil {
+		return err
+	}
+	argv.Token = query.String(form, "token", "")
+	return err
+}
+
+// WebAuthnRegister calls the api routed by RouteWebAuthnRegister
+func (c *Client) WebAuthnRegister(ctx context.Context, req *WebAuthnRegisterRequest) (*WebAuthnRegisterResponse, error) {
+	resp := new(WebAuthnRegisterResponse)
+	if err := c.Call(ctx, RouteWebAuthnRegister, req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func init() {
+	register("WebAuthnRegister", RouteWebAuthnRegister, "// WebAuthn registration options, the registration completes by linking\n// with type webauthn. Binary fields are base64url encoded.\n", new(WebAuthnRegisterRequest), new(WebAuthnRegisterResponse))
+}
+
+type WebAuthnRegisterResponse struct {
+	Challenge          string   `json:"challenge"`
+	RpId               string   `json:"rp_id"`
+	RpName             string   `json:"rp_name"`
+	UserId             string   `json:"user_id"`
+	UserName           string   `json:"user_name"`
+	Algorithms         []int    `json:"algorithms"` // COSE algorithms of pubKeyCredParams
+	ExcludeCredentials []string `json:"exclude_credentials"`
+	Timeout            int64    `json:"timeout"` // milliseconds
+	UserVerification   string   `json:"user_verification"`
+}
+
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *WebAuthnRegisterResponse) AppendProto(b []byte) []byte {
+	b = appendString(b, 0, x.Challenge)
+	b = appendString(b, 1, x.RpId)
+	b = appendString(b, 2, x.RpName)
+	b = appendString(b, 3, x.UserId)
+	b = appendString(b, 4, x.UserName)
+	b = appendInts(b, 5, x.Algorithms)
+	b = appendStrings(b, 6, x.ExcludeCredentials)
+	b = appendInt(b, 7, x.Timeout)
+	b = appendString(b, 8, x.UserVerification)
+	return b
+}
+
+// WebAuthn login options, the login completes by authorizing with type webauthn
+type WebAuthnLoginRequest struct {
+}
+
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *WebAuthnLoginRequest) AppendProto(b []byte) []byte {
+	return b
+}
+
+// Parse parses arguments from form, JSON or protobuf body of the request
+func (argv *WebAuthnLoginRequest) Parse(r *http.Request) error {
+	_, err := parseForm(r)
+	if err != nil {
+		return err
+	}
+	return err
+}
+
+// WebAuthnLogin calls the api routed by RouteWebAuthnLogin
+func (c *Client) WebAuthnLogin(ctx context.Context, req *WebAuthnLoginRequest) (*WebAuthnLoginResponse, error) {
+	resp := new(WebAuthnLoginResponse)
+	if err := c.Call(ctx, RouteWebAuthnLogin, req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func init() {
+	register("WebAuthnLogin", RouteWebAuthnLogin, "// WebAuthn login options, the login completes by authorizing with type webauthn\n", new(WebAuthnLoginRequest), new(WebAuthnLoginResponse))
+}
+
+type WebAuthnLoginResponse struct {
+	Challenge        string `json:"challenge"`
+	RpId             string `json:"rp_id"`
+	Timeout          int64  `json:"timeout"` // milliseconds
+	UserVerification string `json:"user_verification"`
+}
+
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *WebAuthnLoginResponse) AppendProto(b []byte) []byte {
+	b = appendString(b, 0, x.Challenge)
+	b = appendString(b, 1, x.RpId)
+	b = appendInt(b, 2, x.Timeout)
+	b = appendString(b, 3, x.UserVerification)
+	return b
+}
+
+// Refresh tokens, the refresh token is rotated and can't be used again
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" required:"true"`
+}
+
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *RefreshRequest) AppendProto(b []byte) []byte {
+	b = appendString(b, 0, x.RefreshToken)
+	return b
+}
+
+// Parse parses arguments from form, JSON or protobuf body of the request
+func (argv *RefreshRequest) Parse(r *http.Request) error {
+	form, err := parseForm(r,
+		"refresh_token",
+	)
+	if err != nil {
+		return err
+	}
+	if argv.RefreshToken, err = query.RequiredString(form, "refresh_token"); err != nil {
+		return err
+	}
+	return err
+}
+
+// Refresh calls the api routed by RouteRefresh
+func (c *Client) Refresh(ctx context.Context, req *RefreshRequest) (*RefreshResponse, error) {
+	resp := new(RefreshResponse)
+	if err := c.Call(ctx, RouteRefresh, req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func init() {
+	register("Refresh", RouteRefresh, "// Refresh tokens, the refresh token is rotated and can't be used again\n", new(RefreshRequest), new(RefreshResponse))
+}
+
+type RefreshResponse struct {
+	AccessToken           string `json:"access_token"`
+	AccessTokenExpiredAt  int64  `json:"access_token_expired_at"`
+	RefreshToken          string `json:"refresh_token"`
+	RefreshTokenExpiredAt int64  `json:"refresh_token_expired_at"`
+	Scope                 string `json:"scope"` // granted scopes
+}
+
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *RefreshResponse) AppendProto(b []byte) []byte {
+	b = appendString(b, 0, x.AccessToken)
+	b = appendInt(b, 1, x.AccessTokenExpiredAt)
+	b = appendString(b, 2, x.RefreshToken)
+	b = appendInt(b, 3, x.RefreshTokenExpiredAt)
+	b = appendString(b, 4, x.Scope)
+	return b
+}
+
+// Login sessions of devices
+type SessionsRequest struct {
+	Token string `json:"token,omitempty"`
+}
+
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *SessionsRequest) AppendProto(b []byte) []byte {
+	b = appendString(b, 0, x.Token)
+	return b
+}
+
+// Parse parses arguments from form, JSON or protobuf body of the request
+func (argv *SessionsRequest) Parse(r *http.Request) error {
+	form, err := parseForm(r,
+		"token",
+	)
+	if err != nil {
+		return err
+	}
+	argv.Token = query.String(form, "token", "")
+	return err
+}
+
+// Sessions calls the api routed by RouteSessions
+func (c *Client) Sessions(ctx context.Context, req *SessionsRequest) (*SessionsResponse, error) {
+	resp := new(SessionsResponse)
+	if err := c.Call(ctx, RouteSessions, req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func init() {
+	register("Sessions", RouteSessions, "// Login sessions of devices\n", new(SessionsRequest), new(SessionsResponse))
+}
+
+type SessionInfo struct {
+	Id         string `json:"id"`
+	Device     string `json:"device"`
+	Os         string `json:"os"`
+	Model      string `json:"model"`
+	Ip         string `json:"ip"`
+	CreatedAt  int64  `json:"created_at"`
+	LastUsedAt int64  `json:"last_used_at"`
+	ExpiresAt  int64  `json:"expires_at"`
+	Current    bool   `json:"current"` // whether it's the session of the access token
+}
+
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *SessionInfo) AppendProto(b []byte) []byte {
+	b = appendString(b, 0, x.Id)
+	b = appendString(b, 1, x.Device)
+	b = appendString(b, 2, x.Os)
+	b = appendString(b, 3, x.Model)
+	b = appendString(b, 4, x.Ip)
+	b = appendInt(b, 5, x.CreatedAt)
+	b = appendInt(b, 6, x.LastUsedAt)
+	b = appendInt(b, 7, x.ExpiresAt)
+	b = appendBool(b, 8, x.Current)
+	return b
+}
+
+type SessionsResponse struct {
+	Sessions []SessionInfo `json:"sessions"`
+}
+
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *SessionsResponse) AppendProto(b []byte) []byte {
+	for i := range x.Sessions {
+		b = appendMessage(b, 0, &x.Sessions[i])
+	}
+	return b
+}
+
+// Revoke a session, or all sessions if id is empty
+type RevokeSessionRequest struct {
+	Token string `json:"token,omitempty"`
+	Id    string `json:"id,omitempty"`
+}
+
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *RevokeSessionRequest) AppendProto(b []byte) []byte {
+	b = appendString(b, 0, x.Token)
+	b = appendString(b, 1, x.Id)
+	return b
+}
+
+// Parse parses arguments from form, JSON or protobuf body of the request
+func (argv *RevokeSessionRequest) Parse(r *http.Request) error {
+	form, err := parseForm(r,
+		"token",
+		"id",
+	)
+	if err != nil {
+		return err
+	}
+	argv.Token = query.String(form, "token", "")
+	argv.Id = query.String(form, "id", "")
+	return err
+}
+
+// RevokeSession calls the api routed by RouteRevokeSession
+func (c *Client) RevokeSession(ctx context.Context, req *RevokeSessionRequest) (*RevokeSessionResponse, error) {
+	resp := new(RevokeSessionResponse)
+	if err := c.Call(ctx, RouteRevokeSession, req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func init() {
+	register("RevokeSession", RouteRevokeSession, "// Revoke a session, or all sessions if id is empty\n", new(RevokeSessionRequest), new(RevokeSessionResponse))
+}
+
+type RevokeSessionResponse struct {
+	Revoked int64 `json:"revoked"`
+}
+
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *RevokeSessionResponse) AppendProto(b []byte) []byte {
+	b = appendInt(b, 0, x.Revoked)
+	return b
+}
+
+// Token exchanges client credentials of a service client for an access token.
+// Client credentials are client_id and client_secret, HTTP Basic authentication
+// or a client_assertion signed by the client.
+type TokenRequest struct {
+	GrantType       string `json:"grant_type" required:"true"` // client_credentials
+	ClientId        string `json:"client_id,omitempty"`
+	ClientSecret    string `json:"client_secret,omitempty"`
+	ClientAssertion string `json:"client_assertion,omitempty"`
+	Scope           string `json:"scope,omitempty"` // space-separated requested scopes, default: all scopes of the client
+}
+
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *TokenRequest) AppendProto(b []byte) []byte {
+	b = appendString(b, 0, x.GrantType)
+	b = appendString(b, 1, x.ClientId)
+	b = appendString(b, 2, x.ClientSecret)
+	b = appendString(b, 3, x.ClientAssertion)
+	b = appendString(b, 4, x.Scope)
+	return b
+}
+
+// Parse parses arguments from form, JSON or protobuf body of the request
+func (argv *TokenRequest) Parse(r *http.Request) error {
+	form, err := parseForm(r,
+		"grant_type",
+		"client_id",
+		"client_secret",
+		"client_assertion",
+		"scope",
+	)
+	if err != nil {
+		return err
+	}
+	if argv.GrantType, err = query.RequiredString(form, "grant_type"); err != nil {
+		return err
+	}
+	argv.ClientId = query.String(form, "client_id", "")
+	argv.ClientSecret = query.String(form, "client_secret", "")
+	argv.ClientAssertion = query.String(form, "client_assertion", "")
+	argv.Scope = query.String(form, "scope", "")
+	return err
+}
+
+// Token calls the api routed by RouteToken
+func (c *Client) Token(ctx context.Context, req *TokenRequest) (*TokenResponse, error) {
+	resp := new(TokenResponse)
+	if err := c.Call(ctx, RouteToken, req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func init() {
+	register("Token", RouteToken, "// Token exchanges client credentials of a service client for an access token.\n// Client credentials are client_id and client_secret, HTTP Basic authentication\n// or a client_assertion signed by the client.\n", new(TokenRequest), new(TokenResponse))
+}
+
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"` // Bearer
+	ExpiresIn   int64  `json:"expires_in"` // seconds
+	Scope       string `json:"scope"`      // granted scopes
+}
+
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *TokenResponse) AppendProto(b []byte) []byte {
+	b = appendString(b, 0, x.AccessToken)
+	b = appendString(b, 1, x.TokenType)
+	b = appendInt(b, 2, x.ExpiresIn)
+	b = appendString(b, 3, x.Scope)
+	return b
+}
+
+// Introspect reports state of an access token, the caller authenticates as a
+// service client like token
+type IntrospectRequest struct {
+	Token           string `json:"token" required:"true"`
+	ClientId        string `json:"client_id,omitempty"`
+	ClientSecret    string `json:"client_secret,omitempty"`
+	ClientAssertion string `json:"client_assertion,omitempty"`
+}
+
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *IntrospectRequest) AppendProto(b []byte) []byte {
+	b = appendString(b, 0, x.Token)
+	b = appendString(b, 1, x.ClientId)
+	b = appendString(b, 2, x.ClientSecret)
+	b = appendString(b, 3, x.ClientAssertion)
+	return b
+}
+
+// Parse parses arguments from form, JSON or protobuf body of the request
+func (argv *IntrospectRequest) Parse(r *http.Request) error {
+	form, err := parseForm(r,
+		"token",
+		"client_id",
+		"client_secret",
+		"client_assertion",
+	)
+	if err != nil {
+		return err
+	}
+	if argv.Token, err = query.RequiredString(form, "token"); err != nil {
+		return err
+	}
+	argv.ClientId = query.String(form, "client_id", "")
+	argv.ClientSecret = query.String(form, "client_secret", "")
+	argv.ClientAssertion = query.String(form, "client_assertion", "")
+	return err
+}
+
+// Introspect calls the api routed by RouteIntrospect
+func (c *Client) Introspect(ctx context.Context, req *IntrospectRequest) (*IntrospectResponse, error) {
+	resp := new(IntrospectResponse)
+	if err := c.Call(ctx, RouteIntrospect, req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func init() {
+	register("Introspect", RouteIntrospect, "// Introspect reports state of an access token, the caller authenticates as a\n// service client like token\n", new(IntrospectRequest), new(IntrospectResponse))
+}
+
+type IntrospectResponse struct {
+	Active       bool   `json:"active"`
+	Uid          int64  `json:"uid"`
+	Client       string `json:"client"` // id of service client for tokens of service clients
+	Scope        string `json:"scope"`
+	Session      string `json:"session"`
+	IssuedAt     int64  `json:"issued_at"`
+	ExpiredAt    int64  `json:"expired_at"`
+	Revoked      bool   `json:"revoked"`      // whether the session of the token revoked
+	Roles        string `json:"roles"`        // space-separated roles of the account
+	Permissions  string `json:"permissions"`  // space-separated permissions granted by roles
+	Impersonator string `json:"impersonator"` // operator who impersonates the account for impersonation tokens
+}
+
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *IntrospectResponse) AppendProto(b []byte) []byte {
+	b = appendBool(b, 0, x.Active)
+	b = appendInt(b, 1, x.Uid)
+	b = appendString(b, 2, x.Client)
+	b = appendString(b, 3, x.Scope)
+	b = appendString(b, 4, x.Session)
+	b = appendInt(b, 5, x.IssuedAt)
+	b = appendInt(b, 6, x.ExpiredAt)
+	b = appendBool(b, 7, x.Revoked)
+	b = appendString(b, 8, x.Roles)
+	b = appendString(b, 9, x.Permissions)
+	b = appendString(b, 10, x.Impersonator)
+	return b
+}
+
+// Userinfo returns profile of the account of an access token
+type UserinfoRequest struct {
+	Token string `json:"token,omitempty"`
+}
+
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *UserinfoRequest) AppendProto(b []byte) []byte {
+	b = appendString(b, 0, x.Token)
+	return b
+}
+
+// Parse parses arguments from form, JSON or protobuf body of the request
+func (argv *UserinfoRequest) Parse(r *http.Request) error {
+	form, err := parseForm(r,
+		"token",
+	)
+	if err != nil {
+		return err
+	}
+	argv.Token = query.String(form, "token", "")
+	return err
+}
+
+// Userinfo calls the api routed by RouteUserinfo
+func (c *Client) Userinfo(ctx context.Context, req *UserinfoRequest) (*UserinfoResponse, error) {
+	resp := new(UserinfoResponse)
+	if err := c.Call(ctx, RouteUserinfo, req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func init() {
+	register("Userinfo", RouteUserinfo, "// Userinfo returns profile of the account of an access token\n", new(UserinfoRequest), new(UserinfoResponse))
+}
+
+type UserinfoResponse struct {
+	Id       int64  `json:"id"`
+	Name     string `json:"name"`
+	Tag      int    `json:"tag"`
+	Avatar   string `json:"avatar"`
+	Gender   int    `json:"gender"`
+	Location string `json:"location"`
+	Scope    string `json:"scope"` // granted scopes of the access token
+}
+
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *UserinfoResponse) AppendProto(b []byte) []byte {
+	b = appendInt(b, 0, x.Id)
+	b = appendString(b, 1, x.Name)
+	b = appendInt(b, 2, int64(x.Tag))
+	b = appendString(b, 3, x.Avatar)
+	b = appendInt(b, 4, int64(x.Gender))
+	b = appendString(b, 5, x.Location)
+	b = appendString(b, 6, x.Scope)
+	return b
+}
+
+// Registration report: from and to are dates formatted as 2006-01-02, to is inclusive
+type RegistrationReportRequest struct {
+	From string `json:"from" required:"true"`
+	To   string `json:"to,omitempty"`
+}
+
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *RegistrationReportRequest) AppendProto(b []byte) []byte {
+	b = appendString(b, 0, x.From)
+	b = appendString(b, 1, x.To)
+	return b
+}
+
+// Parse parses arguments from form, JSON or protobuf body of the request
+func (argv *RegistrationReportRequest) Parse(r *http.Request) error {
+	form, err := parseForm(r,
+		"from",
+		"to",
+	)
+	if err != nil {
+		return err
+	}
+	if argv.From, err = query.RequiredString(form, "from"); err != nil {
+		return err
+	}
+	argv.To = query.String(form, "to", "")
+	return err
+}
+
+// RegistrationReport calls the api routed by RouteRegistrationReport
+func (c *Client) RegistrationReport(ctx context.Context, req *RegistrationReportRequest) (*RegistrationReportResponse, error) {
+	resp := new(RegistrationReportResponse)
+	if err := c.Call(ctx, RouteRegistrationReport, req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func init() {
+	register("RegistrationReport", RouteRegistrationReport, "// Registration report: from and to are dates formatted as 2006-01-02, to is inclusive\n", new(RegistrationReportRequest), new(RegistrationReportResponse))
+}
+
+type RegistrationStat struct {
+	Day     string `json:"day"`
+	Channel int    `json:"channel"`
+	Source  string `json:"source"`
+	Os      string `json:"os"`
+	Count   int64  `json:"count"`
+}
+
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *RegistrationStat) AppendProto(b []byte) []byte {
+	b = appendString(b, 0, x.Day)
+	b = appendInt(b, 1, int64(x.Channel))
+	b = appendString(b, 2, x.Source)
+	b = appendString(b, 3, x.Os)
+	b = appendInt(b, 4, x.Count)
+	return b
+}
+
+type RegistrationReportResponse struct {
+	Stats []RegistrationStat `json:"stats"`
+}
+
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *RegistrationReportResponse) AppendProto(b []byte) []byte {
+	for i := range x.Stats {
+		b = appendMessage(b, 0, &x.Stats[i])
+	}
+	return b
+}
+
+// Roles lists roles of an account and permissions granted by the roles, it's an admin api
+type RolesRequest struct {
+	Uid int64 `json:"uid" required:"true"`
+}
+
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *RolesRequest) AppendProto(b []byte) []byte {
+	b = appendInt(b, 0, x.Uid)
+	return b
+}
+
+// Parse parses arguments from form, JSON or protobuf body of the request
+func (argv *RolesRequest) Parse(r *http.Request) error {
+	form, err := parseForm(r,
+		"uid",
+	)
+	if err != nil {
+		return err
+	}
+	if argv.Uid, err = query.RequiredInt64(form, "uid"); err != nil {
+		return err
+	}
+	return err
+}
+
+// Roles calls the api routed by RouteRoles
+func (c *Client) Roles(ctx context.Context, req *RolesRequest) (*RolesResponse, error) {
+	resp := new(RolesResponse)
+	if err := c.Call(ctx, RouteRoles, req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func init() {
+	register("Roles", RouteRoles, "// Roles lists roles of an account and permissions granted by the roles, it's an admin api\n", new(RolesRequest), new(RolesResponse))
+}
+
+type RolesResponse struct {
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+}
+
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *RolesResponse) AppendProto(b []byte) []byte {
+	b = appendStrings(b, 0, x.Roles)
+	b = appendStrings(b, 1, x.Permissions)
+	return b
+}
+
+// GrantRole grants a configured role to an account, it's an admin api
+type GrantRoleRequest struct {
+	Uid  int64  `json:"uid" required:"true"`
+	Role string `json:"role" required:"true"`
+}
+
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *GrantRoleRequest) AppendProto(b []byte) []byte {
+	b = appendInt(b, 0, x.Uid)
+	b = appendString(b, 1, x.Role)
+	return b
+}
+
+// Parse parses arguments from form, JSON or protobuf body of the request
+func (argv *GrantRoleRequest) Parse(r *http.Request) error {
+	form, err := parseForm(r,
+		"uid",
+		"role",
+	)
+	if err != nil {
+		return err
+	}
+	if argv.Uid, err = query.RequiredInt64(form, "uid"); err != nil {
+		return err
+	}
+	if argv.Role, err = query.RequiredString(form, "role"); err != nil {
+		return err
+	}
+	return err
+}
+
+// GrantRole calls the api routed by RouteGrantRole
+func (c *Client) GrantRole(ctx context.Context, req *GrantRoleRequest) (*GrantRoleResponse, error) {
+	resp := new(GrantRoleResponse)
+	if err := c.Call(ctx, RouteGrantRole, req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func init() {
+	register("GrantRole", RouteGrantRole, "// GrantRole grants a configured role to an account, it's an admin api\n", new(GrantRoleRequest), new(GrantRoleResponse))
+}
+
+type GrantRoleResponse struct {
+	Granted bool `json:"granted"` // false if the role already granted
+}
+
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *GrantRoleResponse) AppendProto(b []byte) []byte {
+	b = appendBool(b, 0, x.Granted)
+	return b
+}
+
+// RevokeRole revokes a role from an account, it's an admin api. Login sessions
+// of the account are revoked too, so tokens carrying the role can't be used.
+type RevokeRoleRequest struct {
+	Uid  int64  `json:"uid" required:"true"`
+	Role string `json:"role" required:"true"`
+}
+
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *RevokeRoleRequest) AppendProto(b []byte) []byte {
+	b = appendInt(b, 0, x.Uid)
+	b = appendString(b, 1, x.Role)
+	return b
+}
+
+// Parse parses arguments from form, JSON or protobuf body of the request
+func (argv *RevokeRoleRequest) Parse(r *http.Request) error {
+	form, err := parseForm(r,
+		"uid",
+		"role",
+	)
+	if err != nil {
+		return err
+	}
+	if argv.Uid, err = query.RequiredInt64(form, "uid"); err != nil {
+		return err
+	}
+	if argv.Role, err = query.RequiredString(form, "role"); err != nil {
+		return err
+	}
+	return err
+}
+
+// RevokeRole calls the api routed by RouteRevokeRole
+func (c *Client) RevokeRole(ctx context.Context, req *RevokeRoleRequest) (*RevokeRoleResponse, error) {
+	resp := new(RevokeRoleResponse)
+	if err := c.Call(ctx, RouteRevokeRole, req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func init() {
+	register("RevokeRole", RouteRevokeRole, "// RevokeRole revokes a role from an account, it's an admin api. Login sessions\n// of the account are revoked too, so tokens carrying the role can't be used.\n", new(RevokeRoleRequest), new(RevokeRoleResponse))
+}
+
+type RevokeRoleResponse struct {
+	Revoked bool `json:"revoked"` // false if the role not granted
+}
+
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *RevokeRoleResponse) AppendProto(b []byte) []byte {
+	b = appendBool(b, 0, x.Revoked)
+	return b
+}
+
+// Impersonate mints a short-lived impersonation token for customer service to
+// act as the account, it's an admin api and every issuance is audited
+type ImpersonateRequest struct {
+	Uid      int64  `json:"uid" required:"true"`
+	Operator string `json:"operator" required:"true"` // id of the staff, self-declared under the admin key and not verified
+	Reason   string `json:"reason" required:"true"`
+	Scope    string `json:"scope,omitempty"` // space-separated scopes, default: impersonation.scope
+	Ttl      int64  `json:"ttl,omitempty"`   // seconds, default and max: impersonation.ttl
+}
+
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *ImpersonateRequest) AppendProto(b []byte) []byte {
+	b = appendInt(b, 0, x.Uid)
+	b = appendString(b, 1, x.Operator)
+	b = appendString(b, 2, x.Reason)
+	b = appendString(b, 3, x.Scope)
+	b = appendInt(b, 4, x.Ttl)
+	return b
+}
+
+// Parse parses arguments from form, JSON or protobuf body of the request
+func (argv *ImpersonateRequest) Parse(r *http.Request) error {
+	form, err := parseForm(r,
+		"uid",
+		"operator",
+		"reason",
+		"scope",
+		"ttl",
+	)
+	if err != nil {
+		return err
+	}
+	if argv.Uid, err = query.RequiredInt64(form, "uid"); err != nil {
+		return err
+	}
+	if argv.Operator, err = query.RequiredString(form, "operator"); err != nil {
+		return err
+	}
+	if argv.Reason, err = query.RequiredString(form, "reason"); err != nil {
+		return err
+	}
+	argv.Scope = query.String(form, "scope", "")
+	if argv.Ttl, err = query.Int64(form, "ttl", 0); err != nil {
+		return err
+	}
+	return err
+}
+
+// Impersonate calls the api routed by RouteImpersonate
+func (c *Client) Impersonate(ctx context.Context, req *ImpersonateRequest) (*ImpersonateResponse, error) {
+	resp := new(ImpersonateResponse)
+	if err := c.Call(ctx, RouteImpersonate, req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func init() {
+	register("Impersonate", RouteImpersonate, "// Impersonate mints a short-lived impersonation token for customer service to\n// act as the account, it's an admin api and every issuance is audited\n", new(ImpersonateRequest), new(ImpersonateResponse))
+}
+
+type ImpersonateResponse struct {
+	AccessToken          string `json:"access_token"`
+	AccessTokenExpiredAt int64  `json:"access_token_expired_at"`
+	Scope                string `json:"scope"`
+}
+
+// AppendProto appends x encoded in protobuf wire format to b, field numbers
+// are declaration orders of fields starting from 1
+func (x *ImpersonateResponse) AppendProto(b []byte) []byte {
+	b = appendString(b, 0, x.AccessToken)
+	b = appendInt(b, 1, x.AccessTokenExpiredAt)
+	b = appendString(b, 2, x.Scope)
+	return b
+}
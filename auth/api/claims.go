@@ -0,0 +1,13 @@
+package api
+
+// keys of values of access token claims
+const (
+	ClaimProviders    = "providers"    // providers linked to the account
+	ClaimSession      = "session"      // id of the login session
+	ClaimScope        = "scope"        // granted scopes, it's added to userdata of gatepb.Login by gated
+	ClaimClient       = "client"       // id of the service client
+	ClaimRoles        = "roles"        // space-separated roles of the account
+	ClaimPermissions  = "permissions"  // space-separated permissions granted by roles
+	ClaimImpersonator = "impersonator" // operator who impersonates the account, only in impersonation tokens
+	ClaimOperatorIP   = "operator_ip"  // ip of the operator, only in impersonation tokens which have no ip of the account
+)
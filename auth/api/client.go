@@ -0,0 +1,174 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gopherd/doge/net/httputil"
+)
+
+// Verify2faResponse is responded by verify2fa which completes authorization
+type Verify2faResponse = AuthorizeResponse
+
+// Error represents an errno responded by apis
+type Error struct {
+	Code        int
+	Description string
+	Message     string // localized message for users
+	Body        []byte // raw response, e.g. SecondFactorRequiredResponse of authorize
+}
+
+// Error implements error Error method
+func (e *Error) Error() string {
+	return fmt.Sprintf("errno %d: %s", e.Code, e.Description)
+}
+
+// Errno returns the errno, so erron.GetErrno works with it
+func (e *Error) Errno() int {
+	return e.Code
+}
+
+// Client is a typed client of authd apis, requests are sent as JSON objects
+type Client struct {
+	BaseURL    string            // e.g. https://auth.example.com
+	HTTPClient *http.Client      // http.DefaultClient used if nil
+	Header     http.Header       // headers added to every request, e.g. X-Admin-Key for admin apis
+	Routes     map[string]string // overrides default routes, keyed by default routes
+}
+
+// NewClient creates a client of authd at baseURL
+func NewClient(baseURL string) *Client {
+	return &Client{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		Header:  make(http.Header),
+	}
+}
+
+// Call sends req to the route and decodes the response into resp, an *Error
+// returned if the api responds an errno. Apis which read by GET are called by
+// GET with the token sent in header Authorization, others are posted as JSON
+// objects without zero fields of req.
+func (c *Client) Call(ctx context.Context, route string, req, resp any) error {
+	keys, read := readRoutes[route]
+	if r, ok := c.Routes[route]; ok {
+		route = r
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	if !read {
+		return c.do(ctx, http.MethodPost, route, nil, "", body, resp)
+	}
+	var fields map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&fields); err != nil {
+		return err
+	}
+	token, _ := fields["token"].(string)
+	query := make(url.Values)
+	for _, key := range keys {
+		if value, ok := fields[key]; ok && key != "token" {
+			query.Set(key, fmt.Sprint(value))
+		}
+	}
+	return c.do(ctx, http.MethodGet, route, query, token, nil, resp)
+}
+
+// UpdateProfile updates fields of the profile named by fields, e.g. "avatar",
+// which are sent even if zero so that they can be cleared
+func (c *Client) UpdateProfile(ctx context.Context, req *ProfileRequest, fields ...string) (*ProfileResponse, error) {
+	values := map[string]any{"token": req.Token}
+	for _, field := range fields {
+		switch field {
+		case "name":
+			values[field] = req.Name
+		case "avatar":
+			values[field] = req.Avatar
+		case "gender":
+			values[field] = req.Gender
+		case "location":
+			values[field] = req.Location
+		default:
+			return nil, fmt.Errorf("profile: unknown field %q", field)
+		}
+	}
+	body, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	route := RouteProfile
+	if r, ok := c.Routes[route]; ok {
+		route = r
+	}
+	resp := new(ProfileResponse)
+	if err := c.do(ctx, http.MethodPost, route, nil, "", body, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *Client) do(ctx context.Context, method, route string, query url.Values, token string, body []byte, resp any) error {
+	target := c.BaseURL + route
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	r, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return err
+	}
+	for k, vs := range c.Header {
+		r.Header[k] = append(r.Header[k], vs...)
+	}
+	if body != nil {
+		r.Header.Set("Content-Type", httputil.MIMEApplicationJSON)
+	}
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	r.Header.Set("Accept", httputil.MIMEApplicationJSON)
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(r)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s: unexpected status %s", r.Method, r.URL.Path, res.Status)
+	}
+	var errno struct {
+		Error       int    `json:"error"`
+		Description string `json:"description"`
+		Message     string `json:"message"`
+	}
+	if err := json.Unmarshal(data, &errno); err != nil {
+		return err
+	}
+	if errno.Error != 0 {
+		return &Error{
+			Code:        errno.Error,
+			Description: errno.Description,
+			Message:     errno.Message,
+			Body:        data,
+		}
+	}
+	return json.Unmarshal(data, resp)
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gopherd/doge/erron"
+)
+
+func TestClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Admin-Key") != "secret" {
+			Response(w, erron.Errnof(Unauthorized, "unauthorized"))
+			return
+		}
+		req := new(GrantRoleRequest)
+		if err := req.Parse(r); err != nil {
+			Response(w, erron.Errno(BadArgument, err))
+			return
+		}
+		if r.URL.Path != "/admin/grant" || req.Uid != 1001 || req.Role != "gm" {
+			Response(w, erron.Errnof(BadArgument, "unexpected request %s %+v", r.URL.Path, req))
+			return
+		}
+		Response(w, &GrantRoleResponse{Granted: true})
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL + "/")
+	c.Routes = map[string]string{RouteGrantRole: "/admin/grant"}
+	req := &GrantRoleRequest{Uid: 1001, Role: "gm"}
+	_, err := c.GrantRole(context.Background(), req)
+	var e *Error
+	if !errors.As(err, &e) || e.Code != Unauthorized || erron.GetErrno(err) != Unauthorized {
+		t.Fatalf("want errno %d, got %v", Unauthorized, err)
+	}
+
+	c.Header.Set("X-Admin-Key", "secret")
+	resp, err := c.GrantRole(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Granted {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+func TestClientProfile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := new(ProfileRequest)
+		if err := req.Parse(r); err != nil {
+			Response(w, erron.Errno(BadArgument, err))
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			if r.Header.Get("Authorization") != "Bearer token" || len(r.Form) != 0 {
+				Response(w, erron.Errnof(BadArgument, "unexpected request %v %v", r.Header, r.Form))
+				return
+			}
+			Response(w, &ProfileResponse{Id: 1001, Avatar: "https://example.com/a.png"})
+		case http.MethodPost:
+			_, name := r.Form["name"]
+			_, avatar := r.Form["avatar"]
+			if name || !avatar || req.Token != "token" || req.Avatar != "" {
+				Response(w, erron.Errnof(BadArgument, "unexpected request %v", r.Form))
+				return
+			}
+			Response(w, &ProfileResponse{Id: 1001})
+		default:
+			Response(w, erron.Errnof(BadArgument, "method %s not allowed", r.Method))
+		}
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL)
+	resp, err := c.Profile(context.Background(), &ProfileRequest{Token: "token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Id != 1001 || resp.Avatar == "" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if _, err := c.UpdateProfile(context.Background(), &ProfileRequest{Token: "token", Name: "ignored"}, "avatar"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.UpdateProfile(context.Background(), &ProfileRequest{Token: "token"}, "unknown"); err == nil {
+		t.Fatal("unknown field should be rejected")
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	doc := OpenAPI("authd", "v1", map[string]string{RouteAuthorize: "/login"})
+	paths := doc["paths"].(map[string]any)
+	if _, ok := paths["/login"]; !ok {
+		t.Fatal("configured route of authorize not found")
+	}
+	if _, ok := paths[RouteAuthorize]; ok {
+		t.Fatal("default route of authorize should be replaced")
+	}
+	if len(paths) != len(Operations()) {
+		t.Fatalf("want %d paths, got %d", len(Operations()), len(paths))
+	}
+	profile := paths[RouteProfile].(map[string]any)
+	if _, ok := profile["get"]; !ok {
+		t.Fatal("GET of profile not documented")
+	}
+	if _, ok := paths[RouteLink].(map[string]any)["get"]; ok {
+		t.Fatal("GET of link should not be documented")
+	}
+	schemas := doc["components"].(map[string]any)["schemas"].(map[string]any)
+	authorize := schemas["AuthorizeRequest"].(map[string]any)
+	required := authorize["required"].([]string)
+	if len(required) != 3 || required[0] != "account" || required[1] != "channel" || required[2] != "type" {
+		t.Fatalf("unexpected required fields: %v", required)
+	}
+	sessions := schemas["SessionsResponse"].(map[string]any)["properties"].(map[string]any)["sessions"].(map[string]any)
+	if sessions["items"].(map[string]any)["$ref"] != "#/components/schemas/SessionInfo" {
+		t.Fatalf("unexpected sessions schema: %v", sessions)
+	}
+	if _, ok := schemas["SessionInfo"]; !ok {
+		t.Fatal("schema of SessionInfo not found")
+	}
+}
@@ -0,0 +1,371 @@
+package api
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gopherd/doge/net/httputil"
+)
+
+// MIMEApplicationProtobuf is content type of protobuf request and response
+// bodies, application/protobuf is accepted too
+const MIMEApplicationProtobuf = "application/x-protobuf"
+
+const defaultMaxMemory = 32 << 20 // 32 MB
+
+var errMalformedProtobuf = errors.New("malformed protobuf body")
+
+// ProtoAppender is implemented by generated protocols and structs
+type ProtoAppender interface {
+	AppendProto(b []byte) []byte
+}
+
+func mediaType(contentType string) string {
+	t, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	return t
+}
+
+func isProtobuf(t string) bool {
+	return t == MIMEApplicationProtobuf || t == "application/protobuf"
+}
+
+// parseForm parses arguments of the request into r.Form by content type of the
+// body. Fields of JSON objects are keyed by their names, and fields of protobuf
+// messages are keyed by keys[number-1]. Other bodies are parsed as forms.
+// Arguments of the body precede arguments of the url query.
+func parseForm(r *http.Request, keys ...string) (url.Values, error) {
+	if r.Form != nil {
+		return r.Form, nil
+	}
+	t := mediaType(r.Header.Get("Content-Type"))
+	if t != httputil.MIMEApplicationJSON && !isProtobuf(t) {
+		r.ParseMultipartForm(defaultMaxMemory)
+		return r.Form, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, defaultMaxMemory))
+	if err != nil {
+		return nil, err
+	}
+	values := make(url.Values)
+	if t == httputil.MIMEApplicationJSON {
+		err = decodeJSON(values, body)
+	} else {
+		err = decodeProtobuf(values, body, keys)
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.PostForm = values
+	r.Form = make(url.Values, len(values))
+	for k, vs := range values {
+		r.Form[k] = append(r.Form[k], vs...)
+	}
+	for k, vs := range r.URL.Query() {
+		r.Form[k] = append(r.Form[k], vs...)
+	}
+	return r.Form, nil
+}
+
+func decodeJSON(values url.Values, body []byte) error {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+	var fields map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&fields); err != nil {
+		return err
+	}
+	for key, field := range fields {
+		if elems, ok := field.([]any); ok {
+			for _, elem := range elems {
+				if err := addJSONValue(values, key, elem); err != nil {
+					return err
+				}
+			}
+		} else if err := addJSONValue(values, key, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func addJSONValue(values url.Values, key string, value any) error {
+	switch v := value.(type) {
+	case nil:
+	case string:
+		values.Add(key, v)
+	case json.Number:
+		values.Add(key, v.String())
+	case bool:
+		values.Add(key, strconv.FormatBool(v))
+	default:
+		// objects are kept as JSON text
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		values.Add(key, string(data))
+	}
+	return nil
+}
+
+func decodeProtobuf(values url.Values, body []byte, keys []string) error {
+	for len(body) > 0 {
+		tag, n := binary.Uvarint(body)
+		if n <= 0 {
+			return errMalformedProtobuf
+		}
+		body = body[n:]
+		num, wireType := tag>>3, tag&7
+		var value string
+		switch wireType {
+		case 0: // varint
+			v, n := binary.Uvarint(body)
+			if n <= 0 {
+				return errMalformedProtobuf
+			}
+			body = body[n:]
+			value = strconv.FormatInt(int64(v), 10)
+		case 2: // length-delimited
+			size, n := binary.Uvarint(body)
+			if n <= 0 || size > uint64(len(body)-n) {
+				return errMalformedProtobuf
+			}
+			value = string(body[n : n+int(size)])
+			body = body[n+int(size):]
+		case 1, 5: // fixed64 and fixed32 are not used by protocols
+			size := 8
+			if wireType == 5 {
+				size = 4
+			}
+			if len(body) < size {
+				return errMalformedProtobuf
+			}
+			body = body[size:]
+			continue
+		default:
+			return errMalformedProtobuf
+		}
+		// unknown fields are ignored
+		if num >= 1 && num <= uint64(len(keys)) {
+			values.Add(keys[num-1], value)
+		}
+	}
+	return nil
+}
+
+func appendVarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+func appendTag(b []byte, index int, wireType uint64) []byte {
+	return appendVarint(b, uint64(index+1)<<3|wireType)
+}
+
+func appendBytes(b []byte, index int, v []byte) []byte {
+	b = appendTag(b, index, 2)
+	b = appendVarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func appendString(b []byte, index int, v string) []byte {
+	if v == "" {
+		return b
+	}
+	b = appendTag(b, index, 2)
+	b = appendVarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func appendInt(b []byte, index int, v int64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = appendTag(b, index, 0)
+	return appendVarint(b, uint64(v))
+}
+
+func appendBool(b []byte, index int, v bool) []byte {
+	if !v {
+		return b
+	}
+	b = appendTag(b, index, 0)
+	return append(b, 1)
+}
+
+func appendStrings(b []byte, index int, v []string) []byte {
+	for _, s := range v {
+		b = appendTag(b, index, 2)
+		b = appendVarint(b, uint64(len(s)))
+		b = append(b, s...)
+	}
+	return b
+}
+
+func appendInts(b []byte, index int, v []int) []byte {
+	for _, x := range v {
+		b = appendTag(b, index, 0)
+		b = appendVarint(b, uint64(int64(x)))
+	}
+	return b
+}
+
+// appendStringMap appends entries of the map sorted by keys, each entry is a
+// message with key as field 1 and value as field 2
+func appendStringMap(b []byte, index int, v map[string]string) []byte {
+	keys := make([]string, 0, len(v))
+	for k := range v {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var entry []byte
+	for _, k := range keys {
+		entry = appendString(entry[:0], 0, k)
+		entry = appendString(entry, 1, v[k])
+		b = appendBytes(b, index, entry)
+	}
+	return b
+}
+
+func appendMessage(b []byte, index int, m ProtoAppender) []byte {
+	return appendBytes(b, index, m.AppendProto(nil))
+}
+
+// appendError appends the generic error response: error as field 1,
+// description as field 2 and message as field 3
+func appendError(b []byte, errno int, description, message string) []byte {
+	b = appendInt(b, 0, int64(errno))
+	b = appendString(b, 1, description)
+	return appendString(b, 2, message)
+}
+
+// acceptsProtobuf reports whether the request prefers protobuf to JSON responses
+func acceptsProtobuf(r *http.Request) bool {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		t, params, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err != nil || params["q"] == "0" {
+			continue
+		}
+		if isProtobuf(t) {
+			return true
+		}
+		if t == httputil.MIMEApplicationJSON {
+			return false
+		}
+	}
+	return false
+}
+
+type negotiatedResponseWriter struct {
+	http.ResponseWriter
+	protobuf bool
+	langs    []string
+	errno    int // errno of the response written by Response
+}
+
+// NegotiateResponseWriter returns a writer which makes Response respond
+// protobuf if the request accepts protobuf and localize error messages in
+// languages requested by X-Lang and Accept-Language
+func NegotiateResponseWriter(w http.ResponseWriter, r *http.Request) http.ResponseWriter {
+	return &negotiatedResponseWriter{
+		ResponseWriter: w,
+		protobuf:       acceptsProtobuf(r),
+		langs:          Languages(r),
+	}
+}
+
+// Localize returns the message of errno in languages negotiated by
+// NegotiateResponseWriter, or in DefaultLanguage if w isn't negotiated
+func Localize(w http.ResponseWriter, errno int) string {
+	if nw, ok := w.(*negotiatedResponseWriter); ok {
+		return Message(errno, nw.langs...)
+	}
+	return Message(errno)
+}
+
+// ResponseErrno returns errno of the response written by Response, 0 if the
+// response isn't an error or w isn't negotiated by NegotiateResponseWriter
+func ResponseErrno(w http.ResponseWriter) int {
+	if nw, ok := w.(*negotiatedResponseWriter); ok {
+		return nw.errno
+	}
+	return 0
+}
+
+// errnoOf returns errno of the response value: the error field of errors or
+// error responses such as SecondFactorRequiredResponse
+func errnoOf(value any) int {
+	if e, ok := value.(*errorResponse); ok {
+		return e.Error
+	}
+	v := reflect.Indirect(reflect.ValueOf(value))
+	if v.Kind() != reflect.Struct {
+		return 0
+	}
+	if f := v.FieldByName("Error"); f.IsValid() && f.Kind() == reflect.Int {
+		return int(f.Int())
+	}
+	return 0
+}
+
+// errorResponse is the generic error response
+type errorResponse struct {
+	Error       int    `json:"error"`
+	Description string `json:"description,omitempty"`
+	Message     string `json:"message,omitempty"` // localized message for users
+}
+
+// Response writes the value in protobuf if protobuf negotiated by
+// NegotiateResponseWriter, otherwise in JSON. Values which can't be encoded
+// in protobuf are always written in JSON. Errno errors are responded as the
+// generic error response with the localized message.
+func Response(w http.ResponseWriter, value any) error {
+	if v, ok := value.(interface {
+		error
+		Errno() int
+	}); ok {
+		value = &errorResponse{
+			Error:       v.Errno(),
+			Description: v.Error(),
+			Message:     Localize(w, v.Errno()),
+		}
+	}
+	nw, ok := w.(*negotiatedResponseWriter)
+	if ok {
+		nw.errno = errnoOf(value)
+	}
+	if !ok || !nw.protobuf {
+		return httputil.JSONResponse(w, value)
+	}
+	var body []byte
+	switch v := value.(type) {
+	case ProtoAppender:
+		body = v.AppendProto(nil)
+	case *errorResponse:
+		body = appendError(nil, v.Error, v.Description, v.Message)
+	default:
+		return httputil.JSONResponse(w, value)
+	}
+	w.Header().Set("Content-Type", MIMEApplicationProtobuf)
+	w.WriteHeader(http.StatusOK)
+	_, err := w.Write(body)
+	return err
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gopherd/doge/erron"
+)
+
+func TestParseJSON(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/auth/authorize?os=ios",
+		strings.NewReader(`{"channel":3,"type":"device","account":"abc","gender":2,"scope":null}`))
+	r.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req := new(AuthorizeRequest)
+	if err := req.Parse(r); err != nil {
+		t.Fatal(err)
+	}
+	if req.Channel != 3 || req.Type != "device" || req.Account != "abc" || req.Gender != 2 || req.Os != "ios" || req.Scope != "" {
+		t.Fatalf("unexpected request: %+v", req)
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/auth/authorize", strings.NewReader(`{"type":"device"}`))
+	r.Header.Set("Content-Type", "application/json")
+	if err := new(AuthorizeRequest).Parse(r); err == nil {
+		t.Fatal("error expected for missing required arguments")
+	}
+}
+
+func TestParseProtobuf(t *testing.T) {
+	want := &ImpersonateRequest{
+		Uid:      1 << 40,
+		Operator: "alice",
+		Reason:   "reproduce issue #42",
+		Ttl:      300,
+	}
+	r := httptest.NewRequest(http.MethodPost, "/auth/admin/impersonate", bytes.NewReader(want.AppendProto(nil)))
+	r.Header.Set("Content-Type", MIMEApplicationProtobuf)
+	got := new(ImpersonateRequest)
+	if err := got.Parse(r); err != nil {
+		t.Fatal(err)
+	}
+	if *got != *want {
+		t.Fatalf("want %+v, got %+v", want, got)
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/auth/admin/impersonate", bytes.NewReader([]byte{0x0a, 0x05, 'a'}))
+	r.Header.Set("Content-Type", MIMEApplicationProtobuf)
+	if err := new(ImpersonateRequest).Parse(r); err == nil {
+		t.Fatal("error expected for truncated body")
+	}
+}
+
+func TestParseForm(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/auth/refresh", strings.NewReader("refresh_token=xyz"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req := new(RefreshRequest)
+	if err := req.Parse(r); err != nil {
+		t.Fatal(err)
+	}
+	if req.RefreshToken != "xyz" {
+		t.Fatalf("unexpected refresh token %q", req.RefreshToken)
+	}
+}
+
+func TestResponse(t *testing.T) {
+	resp := &RolesResponse{Roles: []string{"gm", "tester"}, Permissions: []string{"gm.kick"}}
+	for _, tc := range []struct {
+		accept      string
+		contentType string
+	}{
+		{"", "application/json"},
+		{"application/json, application/x-protobuf", "application/json"},
+		{"application/x-protobuf", MIMEApplicationProtobuf},
+		{"application/protobuf;q=0.9, application/json;q=0.5", MIMEApplicationProtobuf},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/auth/admin/roles", nil)
+		r.Header.Set("Accept", tc.accept)
+		w := httptest.NewRecorder()
+		if err := Response(NegotiateResponseWriter(w, r), resp); err != nil {
+			t.Fatal(err)
+		}
+		if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, tc.contentType) {
+			t.Fatalf("accept %q: want content type %q, got %q", tc.accept, tc.contentType, ct)
+		}
+		if tc.contentType == MIMEApplicationProtobuf && !bytes.Equal(w.Body.Bytes(), resp.AppendProto(nil)) {
+			t.Fatalf("accept %q: unexpected body %x", tc.accept, w.Body.Bytes())
+		}
+	}
+}
+
+func TestAppendProto(t *testing.T) {
+	resp := &AuthorizeResponse{
+		Channel:   1,
+		Providers: map[string]string{"b": "2", "a": "1"},
+	}
+	want := []byte{
+		0x08, 0x01, // channel
+		0x42, 0x06, 0x0a, 0x01, 'a', 0x12, 0x01, '1', // providers["a"]
+		0x42, 0x06, 0x0a, 0x01, 'b', 0x12, 0x01, '2', // providers["b"]
+	}
+	if got := resp.AppendProto(nil); !bytes.Equal(got, want) {
+		t.Fatalf("want %x, got %x", want, got)
+	}
+}
+
+func TestResponseErrno(t *testing.T) {
+	for _, tc := range []struct {
+		value any
+		want  int
+	}{
+		{&RolesResponse{}, 0},
+		{erron.Errnof(Banned, "banned"), Banned},
+		{&SecondFactorRequiredResponse{Error: SecondFactorRequired}, SecondFactorRequired},
+		{RateLimitedResponse{Error: TooManyRequests}, TooManyRequests},
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/auth/authorize", nil)
+		w := NegotiateResponseWriter(httptest.NewRecorder(), r)
+		if err := Response(w, tc.value); err != nil {
+			t.Fatal(err)
+		}
+		if got := ResponseErrno(w); got != tc.want {
+			t.Fatalf("%T: want errno %d, got %d", tc.value, tc.want, got)
+		}
+	}
+}
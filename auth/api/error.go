@@ -11,4 +11,16 @@ const (
 	Banned                              = 201
 	AccountFound                        = 202
 	AccountNotFoundOrPasswordMismatched = 203
+	SuspiciousLogin                     = 204
+	VerificationRequired                = 205
+	RegionBlocked                       = 206
+	NameUnavailable                     = 207
+	SecondFactorRequired                = 208
+	InvalidSecondFactor                 = 209
+	ScopeDenied                         = 210
+	InvalidClient                       = 211
+	PermissionDenied                    = 212
+	TooManyRequests                     = 213
+	ChallengeRequired                   = 214
+	InvalidChallenge                    = 215
 )
@@ -0,0 +1,178 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLanguage is the language of messages if none of requested languages supported
+const DefaultLanguage = "en"
+
+// messages is the catalog of localized messages keyed by language and errno
+var messages = map[string]map[int]string{
+	"en": {
+		InternalServerError:                 "Server error, please try again later.",
+		BadArgument:                         "Invalid request.",
+		Unauthorized:                        "Please log in again.",
+		BadAuthorization:                    "Authorization failed.",
+		Banned:                              "Your account has been banned.",
+		AccountFound:                        "The account already exists.",
+		AccountNotFoundOrPasswordMismatched: "Incorrect account or password.",
+		SuspiciousLogin:                     "Suspicious login detected, please verify your identity.",
+		VerificationRequired:                "Verification required.",
+		RegionBlocked:                       "The service is not available in your region.",
+		NameUnavailable:                     "The name is unavailable.",
+		SecondFactorRequired:                "Please enter your verification code.",
+		InvalidSecondFactor:                 "Invalid verification code.",
+		ScopeDenied:                         "Access to the requested scope is denied.",
+		InvalidClient:                       "Invalid client credentials.",
+		PermissionDenied:                    "Permission denied.",
+		TooManyRequests:                     "Too many requests, please try again later.",
+		ChallengeRequired:                   "Please complete the verification.",
+		InvalidChallenge:                    "Verification failed, please try again.",
+	},
+	"zh": {
+		InternalServerError:                 "服务器错误，请稍后再试。",
+		BadArgument:                         "请求参数错误。",
+		Unauthorized:                        "请重新登录。",
+		BadAuthorization:                    "授权失败。",
+		Banned:                              "您的账号已被封禁。",
+		AccountFound:                        "账号已存在。",
+		AccountNotFoundOrPasswordMismatched: "账号或密码错误。",
+		SuspiciousLogin:                     "检测到异常登录，请验证您的身份。",
+		VerificationRequired:                "需要验证。",
+		RegionBlocked:                       "您所在的地区暂不提供服务。",
+		NameUnavailable:                     "该名称不可用。",
+		SecondFactorRequired:                "请输入验证码。",
+		InvalidSecondFactor:                 "验证码错误。",
+		ScopeDenied:                         "无权访问请求的权限范围。",
+		InvalidClient:                       "客户端凭证无效。",
+		PermissionDenied:                    "权限不足。",
+		TooManyRequests:                     "请求过于频繁，请稍后再试。",
+		ChallengeRequired:                   "请完成安全验证。",
+		InvalidChallenge:                    "安全验证失败，请重试。",
+	},
+	"zh-tw": {
+		InternalServerError:                 "伺服器錯誤，請稍後再試。",
+		BadArgument:                         "請求參數錯誤。",
+		Unauthorized:                        "請重新登入。",
+		BadAuthorization:                    "授權失敗。",
+		Banned:                              "您的帳號已被停權。",
+		AccountFound:                        "帳號已存在。",
+		AccountNotFoundOrPasswordMismatched: "帳號或密碼錯誤。",
+		SuspiciousLogin:                     "偵測到異常登入，請驗證您的身分。",
+		VerificationRequired:                "需要驗證。",
+		RegionBlocked:                       "您所在的地區暫不提供服務。",
+		NameUnavailable:                     "該名稱無法使用。",
+		SecondFactorRequired:                "請輸入驗證碼。",
+		InvalidSecondFactor:                 "驗證碼錯誤。",
+		ScopeDenied:                         "無權存取請求的權限範圍。",
+		InvalidClient:                       "用戶端憑證無效。",
+		PermissionDenied:                    "權限不足。",
+		TooManyRequests:                     "請求過於頻繁，請稍後再試。",
+		ChallengeRequired:                   "請完成安全驗證。",
+		InvalidChallenge:                    "安全驗證失敗，請重試。",
+	},
+}
+
+// languageAliases maps language tags to languages of the catalog
+var languageAliases = map[string]string{
+	"zh-hant": "zh-tw",
+	"zh-hk":   "zh-tw",
+	"zh-mo":   "zh-tw",
+	"zh-hans": "zh",
+	"zh-cn":   "zh",
+	"zh-sg":   "zh",
+}
+
+// AddMessages adds or overrides messages of the language, it should be called
+// before serving
+func AddMessages(lang string, msgs map[int]string) {
+	lang = strings.ToLower(lang)
+	m, ok := messages[lang]
+	if !ok {
+		m = make(map[int]string, len(msgs))
+		messages[lang] = m
+	}
+	for code, msg := range msgs {
+		m[code] = msg
+	}
+}
+
+// Message returns the message of errno in the first supported language of
+// langs, messages in DefaultLanguage returned if no language supported.
+// Empty string returned if errno not found in the catalog.
+func Message(errno int, langs ...string) string {
+	for _, lang := range langs {
+		if msg, ok := lookupMessage(errno, lang); ok {
+			return msg
+		}
+	}
+	return messages[DefaultLanguage][errno]
+}
+
+// lookupMessage looks up the message by the language tag, e.g. zh-Hant-TW is
+// looked up as zh-hant-tw, zh-hant and then zh
+func lookupMessage(errno int, lang string) (string, bool) {
+	lang = strings.ToLower(strings.ReplaceAll(lang, "_", "-"))
+	for lang != "" {
+		tag := lang
+		if alias, ok := languageAliases[tag]; ok {
+			tag = alias
+		}
+		if m, ok := messages[tag]; ok {
+			msg, ok := m[errno]
+			return msg, ok
+		}
+		i := strings.LastIndexByte(lang, '-')
+		if i < 0 {
+			break
+		}
+		lang = lang[:i]
+	}
+	return "", false
+}
+
+// Languages returns requested languages of the request: X-Lang followed by
+// languages of Accept-Language sorted by quality values
+func Languages(r *http.Request) []string {
+	var langs []string
+	if lang := strings.TrimSpace(r.Header.Get("X-Lang")); lang != "" {
+		langs = append(langs, lang)
+	}
+	return append(langs, parseAcceptLanguage(r.Header.Get("Accept-Language"))...)
+}
+
+func parseAcceptLanguage(s string) []string {
+	type language struct {
+		tag string
+		q   float64
+	}
+	var languages []language
+	for _, part := range strings.Split(s, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if v := strings.TrimSpace(params); strings.HasPrefix(v, "q=") {
+			if f, err := strconv.ParseFloat(v[2:], 64); err == nil {
+				q = f
+			}
+		}
+		if q > 0 {
+			languages = append(languages, language{tag, q})
+		}
+	}
+	sort.SliceStable(languages, func(i, j int) bool {
+		return languages[i].q > languages[j].q
+	})
+	tags := make([]string, len(languages))
+	for i := range languages {
+		tags[i] = languages[i].tag
+	}
+	return tags
+}
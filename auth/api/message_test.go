@@ -0,0 +1,69 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gopherd/doge/erron"
+)
+
+func TestLanguages(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/auth/authorize", nil)
+	r.Header.Set("X-Lang", "ja")
+	r.Header.Set("Accept-Language", "en;q=0.5, zh-TW, fr;q=0, *;q=0.1, de;q=0.8")
+	want := []string{"ja", "zh-TW", "de", "en"}
+	if got := Languages(r); !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+}
+
+func TestMessage(t *testing.T) {
+	for _, tc := range []struct {
+		langs []string
+		want  string
+	}{
+		{nil, messages["en"][Banned]},
+		{[]string{"fr"}, messages["en"][Banned]},
+		{[]string{"fr", "zh-CN"}, messages["zh"][Banned]},
+		{[]string{"zh_Hant_HK"}, messages["zh-tw"][Banned]},
+		{[]string{"zh-Hant-TW"}, messages["zh-tw"][Banned]},
+		{[]string{"zh-Hans-SG"}, messages["zh"][Banned]},
+	} {
+		if got := Message(Banned, tc.langs...); got != tc.want {
+			t.Fatalf("langs %v: want %q, got %q", tc.langs, tc.want, got)
+		}
+	}
+	if got := Message(-1, "zh"); got != "" {
+		t.Fatalf("unexpected message %q of unknown errno", got)
+	}
+}
+
+func TestErrorResponse(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/auth/authorize", nil)
+	r.Header.Set("Accept-Language", "zh-CN,zh;q=0.9,en;q=0.8")
+	w := httptest.NewRecorder()
+	if err := Response(NegotiateResponseWriter(w, r), erron.Errno(AccountFound, errors.New("account found"))); err != nil {
+		t.Fatal(err)
+	}
+	var got errorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := errorResponse{Error: AccountFound, Description: "account found", Message: messages["zh"][AccountFound]}
+	if got != want {
+		t.Fatalf("want %+v, got %+v", want, got)
+	}
+
+	r.Header.Set("Accept", MIMEApplicationProtobuf)
+	w = httptest.NewRecorder()
+	if err := Response(NegotiateResponseWriter(w, r), erron.Errno(AccountFound, errors.New("account found"))); err != nil {
+		t.Fatal(err)
+	}
+	if body := appendError(nil, want.Error, want.Description, want.Message); string(w.Body.Bytes()) != string(body) {
+		t.Fatalf("want %x, got %x", body, w.Body.Bytes())
+	}
+}
@@ -0,0 +1,216 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Operation describes an api generated from auth.mid
+type Operation struct {
+	Name     string
+	Route    string
+	Doc      string
+	Request  any
+	Response any
+}
+
+var operations []Operation
+
+// readRoutes are routes of apis which read by GET besides POST, mapped to keys
+// of query parameters. The token may be sent in header Authorization instead.
+var readRoutes = map[string][]string{
+	RouteProfile: {"token"},
+}
+
+func register(name, route, doc string, request, response any) {
+	operations = append(operations, Operation{
+		Name:     name,
+		Route:    route,
+		Doc:      doc,
+		Request:  request,
+		Response: response,
+	})
+}
+
+// Operations returns all apis generated from auth.mid
+func Operations() []Operation {
+	return append([]Operation(nil), operations...)
+}
+
+// OpenAPI returns the OpenAPI 3 document of apis, routes of apis are
+// replaced by routes if present
+func OpenAPI(title, version string, routes map[string]string) map[string]any {
+	schemas := map[string]any{
+		"Error": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"error":       map[string]any{"type": "integer"},
+				"description": map[string]any{"type": "string"},
+				"message":     map[string]any{"type": "string"},
+			},
+			"required": []string{"error"},
+		},
+	}
+	paths := make(map[string]any, len(operations))
+	for _, op := range operations {
+		route := op.Route
+		if r, ok := routes[route]; ok {
+			route = r
+		}
+		request := schemaRef(schemas, reflect.TypeOf(op.Request))
+		response := schemaRef(schemas, reflect.TypeOf(op.Response))
+		responses := map[string]any{
+			"200": map[string]any{
+				"description": "the response, or an Error if error is not 0",
+				"content": map[string]any{
+					"application/json": map[string]any{
+						"schema": map[string]any{
+							"oneOf": []any{response, map[string]any{"$ref": "#/components/schemas/Error"}},
+						},
+					},
+				},
+			},
+		}
+		item := map[string]any{
+			"post": map[string]any{
+				"operationId": op.Name,
+				"summary":     summary(op.Doc, op.Name),
+				"requestBody": map[string]any{
+					"content": map[string]any{
+						"application/json":                  map[string]any{"schema": request},
+						"application/x-www-form-urlencoded": map[string]any{"schema": request},
+						MIMEApplicationProtobuf:             map[string]any{"schema": request},
+					},
+				},
+				"responses": responses,
+			},
+		}
+		if keys, ok := readRoutes[op.Route]; ok {
+			parameters := make([]any, 0, len(keys))
+			for _, key := range keys {
+				parameters = append(parameters, map[string]any{
+					"name":   key,
+					"in":     "query",
+					"schema": map[string]any{"type": "string"},
+				})
+			}
+			item["get"] = map[string]any{
+				"operationId": op.Name + "Get",
+				"summary":     summary(op.Doc, op.Name),
+				"parameters":  parameters,
+				"responses":   responses,
+			}
+		}
+		paths[route] = item
+	}
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   title,
+			"version": version,
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas,
+		},
+	}
+}
+
+// ParseRoute parses arguments of the request of the api at the default route,
+// so arguments can be read before the handler parses the request
+func ParseRoute(r *http.Request, route string) (url.Values, error) {
+	for _, op := range operations {
+		if op.Route == route {
+			return parseForm(r, protoKeys(reflect.TypeOf(op.Request))...)
+		}
+	}
+	return parseForm(r)
+}
+
+// protoKeys returns keys of fields of struct type t in declaration orders,
+// which are keys of protobuf field numbers
+func protoKeys(t reflect.Type) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	keys := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		keys = append(keys, strings.Split(t.Field(i).Tag.Get("json"), ",")[0])
+	}
+	return keys
+}
+
+// summary returns the doc comment without comment markers
+func summary(doc, name string) string {
+	var lines []string
+	for _, line := range strings.Split(doc, "\n") {
+		line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "//"))
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return name
+	}
+	return strings.Join(lines, " ")
+}
+
+// schemaRef adds schema of struct type t to schemas and returns reference of it
+func schemaRef(schemas map[string]any, t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	ref := map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+	if _, ok := schemas[t.Name()]; ok {
+		return ref
+	}
+	properties := make(map[string]any, t.NumField())
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		properties[name] = schemaOf(schemas, f.Type)
+		if f.Tag.Get("required") == "true" {
+			required = append(required, name)
+		}
+	}
+	schema := map[string]any{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	schemas[t.Name()] = schema
+	return ref
+}
+
+func schemaOf(schemas map[string]any, t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int32:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Int64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": schemaOf(schemas, t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaOf(schemas, t.Elem())}
+	case reflect.Struct, reflect.Pointer:
+		return schemaRef(schemas, t)
+	default:
+		return map[string]any{}
+	}
+}
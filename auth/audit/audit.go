@@ -0,0 +1,77 @@
+package audit
+
+import (
+	"time"
+
+	"github.com/gopherd/doge/service/module"
+
+	"github.com/gopherd/gopherd/auth"
+)
+
+const tableName = "audit_log"
+
+type auditLog struct {
+	ID        int64     `gorm:"primaryKey;column:id"`
+	Action    string    `gorm:"index;column:action;size:64;not null"`
+	Operator  string    `gorm:"index;column:operator;size:191"`
+	Uid       int64     `gorm:"index;column:uid"`
+	IP        string    `gorm:"column:ip"`
+	Detail    string    `gorm:"column:detail;type:text"`
+	CreatedAt time.Time `gorm:"index;column:created_at"`
+}
+
+func (*auditLog) TableName() string { return tableName }
+
+type Service interface {
+	OOSModule() auth.OOSModule
+}
+
+// New creates an auth.AuditModule
+func New(service Service) interface {
+	module.Module
+	auth.AuditModule
+} {
+	return newAuditModule(service)
+}
+
+// auditModule implements auth.AuditModule
+type auditModule struct {
+	*module.BasicModule
+	service Service
+}
+
+func newAuditModule(service Service) *auditModule {
+	return &auditModule{
+		BasicModule: module.NewBasicModule("audit"),
+		service:     service,
+	}
+}
+
+func (mod *auditModule) Init() error {
+	if err := mod.BasicModule.Init(); err != nil {
+		return err
+	}
+	return mod.service.OOSModule().CreateSchema(new(auditLog))
+}
+
+// Record implements auth.AuditModule Record method
+func (mod *auditModule) Record(entry *auth.AuditEntry) error {
+	if err := mod.service.OOSModule().InsertObject(&auditLog{
+		Action:    entry.Action,
+		Operator:  entry.Operator,
+		Uid:       entry.Uid,
+		IP:        entry.IP,
+		Detail:    entry.Detail,
+		CreatedAt: time.Now(),
+	}); err != nil {
+		return err
+	}
+	mod.Logger().Info().
+		String("action", entry.Action).
+		String("operator", entry.Operator).
+		Int64("uid", entry.Uid).
+		String("ip", entry.IP).
+		String("detail", entry.Detail).
+		Print("audit")
+	return nil
+}
@@ -1,11 +1,15 @@
 package auth
 
 import (
+	"context"
+	"errors"
 	"strconv"
 	"time"
 
 	"github.com/gopherd/gopherd/auth/config"
+	"github.com/gopherd/gopherd/auth/keyring"
 	"github.com/gopherd/gopherd/auth/provider"
+	"github.com/gopherd/gopherd/auth/scope"
 	"github.com/gopherd/jwt"
 	"github.com/gopherd/log"
 )
@@ -24,10 +28,14 @@ type Account interface {
 	SetBanned(bool, string)
 	GetRegister() (time.Time, string)
 	SetRegister(at time.Time, ip string)
+	GetAttribution() Attribution
+	SetAttribution(Attribution)
 	GetLastLogin() (time.Time, string)
 	SetLastLogin(at time.Time, ip string)
 	GetName() string
 	SetName(string)
+	GetTag() int
+	SetTag(int)
 	GetAvatar() string
 	SetAvatar(string)
 	GetGender() int
@@ -39,24 +47,66 @@ type Account interface {
 	GetProviders() map[string]string
 }
 
+// Attribution describes where an account registered from
+type Attribution struct {
+	Channel int
+	Source  string
+	OS      string
+	Model   string
+}
+
+// RegistrationStat represents number of registered accounts grouped by day and attribution
+type RegistrationStat struct {
+	Day     string
+	Channel int
+	Source  string
+	OS      string
+	Count   int64
+}
+
+// Signer signs tokens by the active key and verifies tokens signed by any known key
+type Signer interface {
+	Sign(claims *jwt.Claims) (string, error)
+	Verify(issuer, token string) (*jwt.Claims, error)
+	JWKS() keyring.JWKS
+}
+
 type Service interface {
 	Config() *config.Config
 	Logger() *log.Logger
-	Signer() *jwt.Signer
+	Signer() Signer
 	Provider(name string) (provider.Provider, error)
 	OOSModule() OOSModule
 	AccountModule() AccountModule
 	SMSModule() SMSModule
 	GeoModule() GeoModule
+	RiskModule() RiskModule
+	EventModule() EventModule
+	AvatarModule() AvatarModule
+	TwoFactorModule() TwoFactorModule
+	WebAuthnModule() WebAuthnModule
+	SessionModule() SessionModule
+	ClientModule() ClientModule
+	RoleModule() RoleModule
+	AuditModule() AuditModule
+	RateLimitModule() RateLimitModule
+	ChallengeModule() ChallengeModule
 }
 
 // OOSModule reprensets an object-oriented storage system
+// ErrDuplicateKey is wrapped by errors of OOSModule if a unique key duplicated
+var ErrDuplicateKey = errors.New("duplicate key")
+
 type OOSModule interface {
 	CreateSchema(Object) error
 	GetObject(obj Object, by ...Field) (bool, error)
 	HasObject(tableName string, by ...Field) (bool, error)
 	InsertObject(obj Object) error
 	UpdateObject(obj Object, fields ...any) (int64, error)
+	// DeleteObjects deletes objects matched by fields from table of obj
+	DeleteObjects(obj Object, by ...Field) (int64, error)
+	// Query executes a raw sql statement and scans the result rows into dst
+	Query(dst any, sql string, args ...any) error
 }
 
 type Field struct {
@@ -82,15 +132,280 @@ func ByID(id int64) Field {
 
 type AccountModule interface {
 	Contains(by ...Field) (bool, error)
-	Store(provider string, account Account) error
+	// Store stores non-zero fields of the account, or only the fields if
+	// specified, e.g. name and avatar, whose zero values are stored too
+	Store(provider string, account Account, fields ...string) error
 	Load(by ...Field) (Account, error)
 	LoadOrCreate(provider, key, device string) (Account, bool, error)
+	// ValidateName validates name by name policy and returns the normalized name
+	ValidateName(name string) (string, error)
+	// SanitizeName sanitizes name imported from providers by name policy,
+	// it returns an empty string if the name is unusable
+	SanitizeName(name string) string
+	// AssignName sets validated or sanitized name to account, and allocates
+	// a unique tag for the name if discriminator enabled
+	AssignName(account Account, name string) error
+	// RegistrationReport counts accounts registered in [from, to) by day, channel, source and os
+	RegistrationReport(from, to time.Time) ([]RegistrationStat, error)
 }
 
 type SMSModule interface {
 	GenerateCode(channel int, ip, mobile string) (time.Duration, error)
 }
 
+// Position represents geographic position of an ip
+type Position struct {
+	Country   string // ISO 3166-1 country code
+	Region    string // ISO 3166-2 subdivision code
+	Latitude  float64
+	Longitude float64
+}
+
+// Network represents network information of an ip
+type Network struct {
+	ASN          uint   // autonomous system number
+	Organization string // autonomous system organization
+	ISP          string // available only for ISP database
+	Hosting      bool   // whether the ASN is a known hosting/datacenter network
+}
+
 type GeoModule interface {
 	QueryLocation(ip, lang string) (country, province, city string, err error)
+	QueryPosition(ip string) (Position, error)
+	// Allowed reports whether the ip is allowed by geo policy
+	Allowed(ip string) bool
+	// QueryNetwork queries network information of ip from ASN/ISP database
+	QueryNetwork(ip string) (Network, error)
+}
+
+// RiskDecision represents what to do with a suspicious login
+type RiskDecision int
+
+const (
+	RiskAllow  RiskDecision = iota // allows the login
+	RiskVerify                     // requires step-up verification
+	RiskDeny                       // denies the login
+)
+
+// Risk is the result of login risk evaluation
+type Risk struct {
+	Decision RiskDecision
+	Rules    []string // violated rules
+}
+
+// RiskModule detects suspicious logins by login history
+type RiskModule interface {
+	// Evaluate evaluates login of the account from ip
+	Evaluate(account Account, ip string) (Risk, error)
+	// Record appends a successful login to login history
+	Record(account Account, ip string) error
+}
+
+// Event represents an account event
+type Event interface {
+	Type() string
+}
+
+// event types
+const (
+	EventProfileChanged = "profile_changed"
+)
+
+// ProfileChangedEvent is published after profile of an account changed
+type ProfileChangedEvent struct {
+	Uid      int64    `json:"uid"`
+	Fields   []string `json:"fields"` // names of changed fields
+	Name     string   `json:"name"`
+	Tag      int      `json:"tag"`
+	Avatar   string   `json:"avatar"`
+	Gender   int      `json:"gender"`
+	Location string   `json:"location"`
+}
+
+// Type implements Event Type method
+func (*ProfileChangedEvent) Type() string { return EventProfileChanged }
+
+// EventModule publishes account events to mq
+type EventModule interface {
+	// Publish publishes the event to configured events topic,
+	// it does nothing if the topic is not configured
+	Publish(event Event) error
+}
+
+// AvatarModule mirrors avatars of third-party urls to blob store
+type AvatarModule interface {
+	// Mirror downloads, resizes and stores avatar of source url asynchronously,
+	// and then rewrites avatar of the account to the mirrored url if it's
+	// still the source url
+	Mirror(uid int64, source string)
+}
+
+// TwoFactorModule manages TOTP two-factor authentication of accounts
+type TwoFactorModule interface {
+	// Enabled reports whether two-factor authentication enabled for the account
+	Enabled(uid int64) (bool, error)
+	// Enroll generates a new secret and recovery codes for the account,
+	// which take effect after activated
+	Enroll(uid int64, label string) (secret, uri string, recoveryCodes []string, err error)
+	// Activate enables two-factor authentication if the TOTP code matched
+	Activate(uid int64, code string) error
+	// Disable disables two-factor authentication if the TOTP or recovery code matched
+	Disable(uid int64, code string) error
+	// Verify verifies TOTP code or recovery code, a recovery code can be used only once
+	Verify(uid int64, code string) error
+	// Redeem marks the challenge token of salt redeemed until expiredAt, it
+	// returns false if the token has been redeemed
+	Redeem(salt string, expiredAt int64) bool
+}
+
+// WebAuthnOptions represents options of a webauthn ceremony
+type WebAuthnOptions struct {
+	Challenge        string
+	RPID             string
+	RPName           string
+	UserID           string   // registration only, base64url encoded user handle
+	UserName         string   // registration only
+	Algorithms       []int    // registration only, COSE algorithms
+	Credentials      []string // registration only, registered credential ids to exclude
+	Timeout          time.Duration
+	UserVerification string
+}
+
+// WebAuthnModule implements passkey login as a provider
+type WebAuthnModule interface {
+	provider.Provider
+	// Enabled reports whether webauthn enabled
+	Enabled() bool
+	// BeginRegistration starts a registration ceremony for the account,
+	// which completes by linking the account to provider webauthn
+	BeginRegistration(account Account) (WebAuthnOptions, error)
+	// Register completes a registration ceremony started for the account
+	// and stores the credential, it's called by link only
+	Register(account Account, id, credentials string) (*provider.UserInfo, error)
+	// BeginLogin starts an authentication ceremony, which completes by
+	// authorizing with provider webauthn
+	BeginLogin() (WebAuthnOptions, error)
+}
+
+// Session represents a login session of a device, a session is identified by
+// its refresh token
+type Session struct {
+	ID         string
+	Uid        int64
+	Device     string
+	OS         string
+	Model      string
+	IP         string
+	Salt       string // salt of the latest refresh token, which rotates on refresh
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+	ExpiresAt  time.Time
+}
+
+// SessionModule manages login sessions of devices
+type SessionModule interface {
+	// Open creates or renews the session of the device
+	Open(uid int64, device, os, model, ip string) (*Session, error)
+	// Active reports whether the session is active
+	Active(uid int64, id string) (bool, error)
+	// Refresh verifies salt of the refresh token and rotates it, the session
+	// is revoked if a rotated refresh token is replayed
+	Refresh(uid int64, id, salt, ip string) (*Session, error)
+	// List returns active sessions of the account
+	List(uid int64) ([]*Session, error)
+	// Revoke revokes the session, or all sessions of the account if id is empty,
+	// and disconnects corresponding gated sessions. Issued access tokens of
+	// revoked sessions are rejected by gated too.
+	Revoke(uid int64, id string) (int64, error)
+}
+
+// Client represents an authenticated service client
+type Client struct {
+	ID    string
+	Scope scope.Scope // scopes which can be granted to the client
+	TTL   time.Duration
+}
+
+// ClientModule authenticates registered service clients
+type ClientModule interface {
+	// Authenticate authenticates the client by id and secret
+	Authenticate(id, secret string) (*Client, error)
+	// AuthenticateAssertion authenticates the client by a short-lived JWT assertion
+	// signed by the client, issuer and subject of the assertion must be the client id
+	// and audience must be issuer of authd
+	AuthenticateAssertion(id, assertion string) (*Client, error)
+}
+
+// Grants represents roles of an account and what the roles grant
+type Grants struct {
+	Roles       []string
+	Permissions []string
+	Scope       scope.Scope // scopes which can be granted to the account by the roles
+}
+
+// RoleModule manages roles of accounts
+type RoleModule interface {
+	// Grants returns roles of the account and permissions granted by the roles,
+	// roles which are no longer configured are ignored
+	Grants(uid int64) (*Grants, error)
+	// Grant grants the role to the account, it returns false if already granted
+	Grant(uid int64, role string) (bool, error)
+	// Revoke revokes the role from the account, it returns false if not granted
+	Revoke(uid int64, role string) (bool, error)
+}
+
+// Audit actions
+const (
+	AuditImpersonate = "impersonate"
+)
+
+// AuditEntry represents an audited admin operation
+type AuditEntry struct {
+	Action   string
+	Operator string // who performed the operation
+	Uid      int64  // target account
+	IP       string // ip of the request
+	Detail   string
+}
+
+// AuditModule records admin operations
+type AuditModule interface {
+	// Record persists the entry, operations must not proceed if it fails
+	Record(entry *AuditEntry) error
+}
+
+// RateLimitModule limits rates of requests by rules of routes
+type RateLimitModule interface {
+	// Limit takes tokens of rules of the default route, arg returns the value
+	// which a rule limits by, e.g. ip or device. It returns the duration to
+	// retry after if any rule exceeded, or 0 if the request allowed.
+	Limit(ctx context.Context, route string, arg func(by string) string) time.Duration
+}
+
+// Challenge types
+const (
+	ChallengePoW     = "pow"
+	ChallengeCaptcha = "captcha"
+)
+
+// Challenge represents a challenge to be solved by clients
+type Challenge struct {
+	Type       string
+	Challenge  string // token of pow challenge, empty for captcha
+	Difficulty int    // leading zero bits of pow
+	SiteKey    string // site key of captcha
+	ExpiredAt  int64
+}
+
+// ChallengeModule issues and verifies proof-of-work or captcha challenges
+// required by abuse-prone apis
+type ChallengeModule interface {
+	// Required reports whether challenges are required by the api: smscode or
+	// authorize (risky attempts only)
+	Required(api string) bool
+	// Issue issues a challenge for ip
+	Issue(ip string) (*Challenge, error)
+	// Verify verifies the solution of the challenge issued for ip, a pow
+	// challenge can be solved only once
+	Verify(ctx context.Context, ip, challenge, solution string) error
 }
@@ -0,0 +1,258 @@
+package avatar
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	// image decoders
+	_ "image/gif"
+	_ "image/png"
+
+	"github.com/gopherd/doge/erron"
+	"github.com/gopherd/doge/service/module"
+
+	"github.com/gopherd/gopherd/auth"
+	"github.com/gopherd/gopherd/auth/blob"
+	"github.com/gopherd/gopherd/auth/config"
+)
+
+const (
+	queueSize   = 1024
+	maxPixels   = 25 << 20 // max pixels of source images
+	jpegQuality = 90
+)
+
+type Service interface {
+	Config() *config.Config
+	OOSModule() auth.OOSModule
+	AccountModule() auth.AccountModule
+	EventModule() auth.EventModule
+}
+
+// New creates an auth.AvatarModule
+func New(service Service) interface {
+	module.Module
+	auth.AvatarModule
+} {
+	return newAvatarModule(service)
+}
+
+type job struct {
+	uid    int64
+	source string
+}
+
+// avatarModule implements auth.AvatarModule
+type avatarModule struct {
+	*module.BasicModule
+	service Service
+	store   blob.Store // nil if mirroring disabled
+	client  *http.Client
+
+	jobs chan job
+	quit chan struct{}
+	wg   sync.WaitGroup
+
+	pendingMu sync.Mutex
+	pending   map[job]bool
+}
+
+func newAvatarModule(service Service) *avatarModule {
+	return &avatarModule{
+		BasicModule: module.NewBasicModule("avatar"),
+		service:     service,
+		jobs:        make(chan job, queueSize),
+		quit:        make(chan struct{}),
+		pending:     make(map[job]bool),
+	}
+}
+
+func (mod *avatarModule) Init() error {
+	if err := mod.BasicModule.Init(); err != nil {
+		return err
+	}
+	cfg := mod.service.Config().Avatar
+	if cfg.Store == "" {
+		return nil
+	}
+	store, err := blob.Open(cfg.Store, cfg.Source)
+	if err != nil {
+		return erron.Throwf("open blob store %q error %w", cfg.Store, err)
+	}
+	mod.store = store
+	mod.client = newClient(time.Duration(cfg.Timeout)*time.Second, cfg.AllowedHosts)
+	return nil
+}
+
+func (mod *avatarModule) Start() {
+	mod.BasicModule.Start()
+	if mod.store == nil {
+		return
+	}
+	for i := 0; i < mod.service.Config().Avatar.Workers; i++ {
+		mod.wg.Add(1)
+		go mod.work()
+	}
+}
+
+func (mod *avatarModule) Shutdown() {
+	close(mod.quit)
+	mod.wg.Wait()
+	if mod.store != nil {
+		mod.store.Close()
+	}
+	mod.BasicModule.Shutdown()
+}
+
+// key returns key of mirrored avatar in blob store
+func key(uid int64, source string) string {
+	sum := sha256.Sum256([]byte(source))
+	return "avatars/" + strconv.FormatInt(uid, 10) + "/" + hex.EncodeToString(sum[:8]) + ".jpg"
+}
+
+func (mod *avatarModule) mirrored(source string) bool {
+	return strings.HasPrefix(source, mod.store.URL(""))
+}
+
+// Mirror implements auth.AvatarModule Mirror method
+func (mod *avatarModule) Mirror(uid int64, source string) {
+	if mod.store == nil || mod.mirrored(source) {
+		return
+	}
+	if u, err := url.Parse(source); err != nil || checkURL(u, mod.service.Config().Avatar.AllowedHosts) != nil {
+		return
+	}
+	j := job{uid: uid, source: source}
+	mod.pendingMu.Lock()
+	defer mod.pendingMu.Unlock()
+	if mod.pending[j] {
+		return
+	}
+	select {
+	case <-mod.quit:
+	case mod.jobs <- j:
+		mod.pending[j] = true
+	default:
+		mod.Logger().Warn().
+			Int64("uid", uid).
+			String("source", source).
+			Print("avatar queue full")
+	}
+}
+
+func (mod *avatarModule) work() {
+	defer mod.wg.Done()
+	for {
+		select {
+		case <-mod.quit:
+			return
+		case j := <-mod.jobs:
+			if err := mod.process(j); err != nil {
+				mod.Logger().Warn().
+					Int64("uid", j.uid).
+					String("source", j.source).
+					Error("error", err).
+					Print("mirror avatar error")
+			}
+			mod.pendingMu.Lock()
+			delete(mod.pending, j)
+			mod.pendingMu.Unlock()
+		}
+	}
+}
+
+// process downloads, resizes and stores the avatar, and then rewrites avatar
+// of the account if it's still the source url
+func (mod *avatarModule) process(j job) error {
+	cfg := mod.service.Config().Avatar
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Timeout)*time.Second)
+	defer cancel()
+
+	data, err := mod.download(ctx, j.source, cfg.MaxBytes)
+	if err != nil {
+		return err
+	}
+	if c, _, err := image.DecodeConfig(bytes.NewReader(data)); err != nil {
+		return err
+	} else if c.Width*c.Height > maxPixels {
+		return fmt.Errorf("image too large: %dx%d", c.Width, c.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, thumbnail(img, cfg.Size), &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return err
+	}
+	k := key(j.uid, j.source)
+	if err := mod.store.Put(ctx, k, buf.Bytes(), "image/jpeg"); err != nil {
+		return err
+	}
+
+	account, err := mod.service.AccountModule().Load(auth.ByID(j.uid))
+	if err != nil {
+		return err
+	}
+	if account == nil || account.GetAvatar() != j.source {
+		// avatar changed while mirroring
+		return nil
+	}
+	account.SetAvatar(mod.store.URL(k))
+	if _, err := mod.service.OOSModule().UpdateObject(account, "avatar"); err != nil {
+		return err
+	}
+	mod.Logger().Debug().
+		Int64("uid", j.uid).
+		String("source", j.source).
+		String("avatar", account.GetAvatar()).
+		Print("avatar mirrored")
+	return mod.service.EventModule().Publish(&auth.ProfileChangedEvent{
+		Uid:      account.GetID(),
+		Fields:   []string{"avatar"},
+		Name:     account.GetName(),
+		Tag:      account.GetTag(),
+		Avatar:   account.GetAvatar(),
+		Gender:   account.GetGender(),
+		Location: account.GetLocation(),
+	})
+}
+
+// download downloads the avatar by the client which rejects internal addresses
+func (mod *avatarModule) download(ctx context.Context, source string, maxBytes int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkURL(req.URL, mod.service.Config().Avatar.AllowedHosts); err != nil {
+		return nil, err
+	}
+	resp, err := mod.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download avatar: %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, fmt.Errorf("avatar too large: more than %d bytes", maxBytes)
+	}
+	return data, nil
+}
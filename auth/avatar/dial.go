@@ -0,0 +1,98 @@
+package avatar
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const maxRedirects = 3
+
+var errTooManyRedirects = errors.New("avatar: too many redirects")
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598)
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// CheckIP rejects addresses which mustn't be requested on behalf of users,
+// so avatar urls can't be used to reach internal services
+func CheckIP(ip net.IP) error {
+	if ip == nil ||
+		ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified() ||
+		sharedAddressSpace.Contains(ip) {
+		return fmt.Errorf("avatar: forbidden address %v", ip)
+	}
+	return nil
+}
+
+// dialControl checks the resolved address before connecting
+func dialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	return CheckIP(net.ParseIP(host))
+}
+
+// allowedHost reports whether host is allowed by hosts, all hosts are allowed
+// if hosts is empty. A host starting with "." allows its subdomains.
+func allowedHost(host string, hosts []string) bool {
+	if len(hosts) == 0 {
+		return true
+	}
+	host = strings.ToLower(host)
+	for _, h := range hosts {
+		h = strings.ToLower(h)
+		if host == h || (strings.HasPrefix(h, ".") && strings.HasSuffix(host, h)) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkURL checks scheme and host of the url
+func checkURL(u *url.URL, hosts []string) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("avatar: unsupported scheme %q", u.Scheme)
+	}
+	if !allowedHost(u.Hostname(), hosts) {
+		return fmt.Errorf("avatar: host %q not allowed", u.Hostname())
+	}
+	return nil
+}
+
+// newClient creates a http client which only connects to public addresses
+// of allowed hosts, redirects are checked too
+func newClient(timeout time.Duration, hosts []string) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: dialControl,
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// proxies are not used, otherwise addresses of targets can't be checked
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConns:        16,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return errTooManyRedirects
+			}
+			return checkURL(req.URL, hosts)
+		},
+	}
+}
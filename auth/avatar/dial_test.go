@@ -0,0 +1,67 @@
+package avatar
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCheckIP(t *testing.T) {
+	for _, tc := range []struct {
+		ip      string
+		allowed bool
+	}{
+		{"8.8.8.8", true},
+		{"2001:4860:4860::8888", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.100.100.200", false},
+		{"fd00::1", false},
+		{"fe80::1", false},
+		{"0.0.0.0", false},
+		{"::ffff:127.0.0.1", false},
+	} {
+		if err := CheckIP(net.ParseIP(tc.ip)); (err == nil) != tc.allowed {
+			t.Fatalf("%s: want allowed %v, got error %v", tc.ip, tc.allowed, err)
+		}
+	}
+}
+
+func TestAllowedHost(t *testing.T) {
+	hosts := []string{"avatars.example.com", ".fbcdn.net"}
+	for _, tc := range []struct {
+		host    string
+		allowed bool
+	}{
+		{"avatars.example.com", true},
+		{"AVATARS.example.com", true},
+		{"evil.example.com", false},
+		{"scontent.fbcdn.net", true},
+		{"fbcdn.net.evil.com", false},
+	} {
+		if got := allowedHost(tc.host, hosts); got != tc.allowed {
+			t.Fatalf("%s: want %v, got %v", tc.host, tc.allowed, got)
+		}
+	}
+	if !allowedHost("any.example.org", nil) {
+		t.Fatal("all hosts should be allowed if hosts is empty")
+	}
+}
+
+func TestClientRejectsInternalAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("internal"))
+	}))
+	defer server.Close()
+	resp, err := newClient(time.Second, nil).Get(server.URL)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("request to loopback address should be rejected")
+	}
+}
@@ -0,0 +1,62 @@
+package avatar
+
+import (
+	"image"
+	"image/color"
+)
+
+// thumbnail crops the center square of src and scales it to size x size by
+// averaging source pixels covered by each target pixel. Transparent pixels are
+// composited over white background.
+func thumbnail(src image.Image, size int) *image.RGBA {
+	b := src.Bounds()
+	side := b.Dx()
+	if b.Dy() < side {
+		side = b.Dy()
+	}
+	var (
+		x0    = b.Min.X + (b.Dx()-side)/2
+		y0    = b.Min.Y + (b.Dy()-side)/2
+		dst   = image.NewRGBA(image.Rect(0, 0, size, size))
+		scale = float64(side) / float64(size)
+	)
+	if side == 0 {
+		return dst
+	}
+	for dy := 0; dy < size; dy++ {
+		sy0, sy1 := span(dy, scale)
+		for dx := 0; dx < size; dx++ {
+			sx0, sx1 := span(dx, scale)
+			var r, g, bl, a, n uint64
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					c := color.RGBA64Model.Convert(src.At(x0+sx, y0+sy)).(color.RGBA64)
+					r += uint64(c.R)
+					g += uint64(c.G)
+					bl += uint64(c.B)
+					a += uint64(c.A)
+					n++
+				}
+			}
+			// colors are alpha-premultiplied, so adds white of the remaining alpha
+			white := n*0xffff - a
+			dst.SetRGBA(dx, dy, color.RGBA{
+				R: uint8((r + white) / n >> 8),
+				G: uint8((g + white) / n >> 8),
+				B: uint8((bl + white) / n >> 8),
+				A: 0xff,
+			})
+		}
+	}
+	return dst
+}
+
+// span returns range [from, to) of source pixels covered by target pixel i
+func span(i int, scale float64) (from, to int) {
+	from = int(float64(i) * scale)
+	to = int(float64(i+1) * scale)
+	if to <= from {
+		to = from + 1
+	}
+	return
+}
@@ -0,0 +1,29 @@
+package avatar
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestThumbnail(t *testing.T) {
+	// left half red, right half transparent, with 10px padding of top and bottom
+	src := image.NewNRGBA(image.Rect(0, 0, 40, 60))
+	for y := 0; y < 60; y++ {
+		for x := 0; x < 20; x++ {
+			src.SetNRGBA(x, y, color.NRGBA{R: 0xff, A: 0xff})
+		}
+	}
+	for _, size := range []int{4, 40, 80} {
+		dst := thumbnail(src, size)
+		if dst.Bounds() != image.Rect(0, 0, size, size) {
+			t.Fatalf("thumbnail(%d): unexpected bounds %v", size, dst.Bounds())
+		}
+		if c := dst.RGBAAt(0, 0); c != (color.RGBA{R: 0xff, A: 0xff}) {
+			t.Errorf("thumbnail(%d): want red at left, got %v", size, c)
+		}
+		if c := dst.RGBAAt(size-1, size-1); c != (color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}) {
+			t.Errorf("thumbnail(%d): want white at right, got %v", size, c)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+// Package blob defines a pluggable blob store used to store files such as avatars
+package blob
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Store represents a blob store
+type Store interface {
+	// Put stores data with the key, the existing blob would be overwritten
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// URL returns the public url of key
+	URL(key string) string
+	// Close closes the store
+	Close() error
+}
+
+// Driver opens a Store by driver-specific source
+type Driver func(source string) (Store, error)
+
+var (
+	driversMu sync.RWMutex
+	drivers   = make(map[string]Driver)
+)
+
+// Register makes a blob driver available by the provided name
+func Register(name string, driver Driver) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	if _, dup := drivers[name]; dup {
+		panic("blob: Register " + name + " called twice")
+	}
+	drivers[name] = driver
+}
+
+// Open opens a blob store specified by its driver name and a driver-specific source
+func Open(name string, source string) (Store, error) {
+	driversMu.RLock()
+	driver, ok := drivers[name]
+	driversMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("blob: driver %q not found, forgot import?", name)
+	}
+	return driver(source)
+}
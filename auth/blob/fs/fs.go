@@ -0,0 +1,99 @@
+// Package fs implements a blob store on local filesystem.
+//
+// Source format:
+//
+//	<dir>?url=<base url>
+//
+// e.g. "/var/www/static?url=https://static.example.com", files are served by
+// another http server under base url.
+package fs
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gopherd/gopherd/auth/blob"
+)
+
+const name = "fs"
+
+func init() {
+	blob.Register(name, open)
+}
+
+func open(source string) (blob.Store, error) {
+	dir, query, _ := strings.Cut(source, "?")
+	if dir == "" {
+		return nil, errors.New("fs: directory required")
+	}
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	baseURL := strings.TrimSuffix(values.Get("url"), "/")
+	if baseURL == "" {
+		return nil, errors.New("fs: url required")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &store{dir: dir, baseURL: baseURL}, nil
+}
+
+type store struct {
+	dir     string
+	baseURL string
+}
+
+func (s *store) path(key string) (string, error) {
+	path := filepath.Join(s.dir, filepath.FromSlash(key))
+	if !strings.HasPrefix(path, filepath.Clean(s.dir)+string(filepath.Separator)) {
+		return "", errors.New("fs: invalid key " + key)
+	}
+	return path, nil
+}
+
+// Put implements blob.Store Put method, data is written to a temporary file
+// and then renamed to the target file
+func (s *store) Put(_ context.Context, key string, data []byte, _ string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), ".blob-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp, 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
+
+// URL implements blob.Store URL method
+func (s *store) URL(key string) string {
+	return s.baseURL + "/" + key
+}
+
+// Close implements blob.Store Close method
+func (s *store) Close() error {
+	return nil
+}
@@ -0,0 +1,249 @@
+// Package s3 implements a blob store on S3 compatible object storages.
+//
+// Source format is a query string, e.g.
+//
+//	endpoint=https://s3.us-east-1.amazonaws.com&region=us-east-1&bucket=avatars&access_key=AK&secret_key=SK
+//
+// Options:
+//
+//	endpoint:   endpoint of the object storage, required
+//	region:     region of the bucket, default: us-east-1
+//	bucket:     name of the bucket, required
+//	access_key: access key id, required
+//	secret_key: secret access key, required
+//	path_style: use path-style urls (endpoint/bucket/key) instead of virtual-hosted style, default: false
+//	acl:        canned ACL of put objects, e.g. public-read
+//	url:        public base url of objects, default: url of the bucket
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gopherd/gopherd/auth/blob"
+)
+
+const (
+	name          = "s3"
+	defaultRegion = "us-east-1"
+	service       = "s3"
+	algorithm     = "AWS4-HMAC-SHA256"
+	amzDateLayout = "20060102T150405Z"
+)
+
+func init() {
+	blob.Register(name, open)
+}
+
+func open(source string) (blob.Store, error) {
+	values, err := url.ParseQuery(source)
+	if err != nil {
+		return nil, err
+	}
+	s := &store{
+		region:    values.Get("region"),
+		bucket:    values.Get("bucket"),
+		accessKey: values.Get("access_key"),
+		secretKey: values.Get("secret_key"),
+		acl:       values.Get("acl"),
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}
+	if s.region == "" {
+		s.region = defaultRegion
+	}
+	if s.bucket == "" || s.accessKey == "" || s.secretKey == "" {
+		return nil, errors.New("s3: bucket, access_key and secret_key required")
+	}
+	endpoint, err := url.Parse(values.Get("endpoint"))
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("s3: invalid endpoint %q", values.Get("endpoint"))
+	}
+	pathStyle, _ := strconv.ParseBool(values.Get("path_style"))
+	s.bucketURL = *endpoint
+	if pathStyle {
+		s.bucketURL.Path = strings.TrimSuffix(endpoint.Path, "/") + "/" + s.bucket
+	} else {
+		s.bucketURL.Host = s.bucket + "." + endpoint.Host
+		s.bucketURL.Path = strings.TrimSuffix(endpoint.Path, "/")
+	}
+	s.baseURL = strings.TrimSuffix(values.Get("url"), "/")
+	if s.baseURL == "" {
+		s.baseURL = s.bucketURL.String()
+	}
+	return s, nil
+}
+
+type store struct {
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	acl       string
+	bucketURL url.URL
+	baseURL   string
+	client    *http.Client
+}
+
+// Put implements blob.Store Put method
+func (s *store) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	u := s.bucketURL
+	u.Path += "/" + key
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if s.acl != "" {
+		req.Header.Set("X-Amz-Acl", s.acl)
+	}
+	sum := sha256.Sum256(data)
+	s.sign(req, hex.EncodeToString(sum[:]), time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("s3: put %s: %s: %s", key, resp.Status, body)
+	}
+	return nil
+}
+
+// URL implements blob.Store URL method
+func (s *store) URL(key string) string {
+	return s.baseURL + "/" + key
+}
+
+// Close implements blob.Store Close method
+func (s *store) Close() error {
+	return nil
+}
+
+// sign signs the request by AWS Signature Version 4, all headers of the
+// request and host are signed
+func (s *store) sign(req *http.Request, payloadHash string, now time.Time) {
+	var (
+		amzDate = now.UTC().Format(amzDateLayout)
+		scope   = amzDate[:8] + "/" + s.region + "/" + service + "/aws4_request"
+	)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	// canonical headers
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.Join(v, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k)
+		canonicalHeaders.WriteByte(':')
+		canonicalHeaders.WriteString(strings.TrimSpace(headers[k]))
+		canonicalHeaders.WriteByte('\n')
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		escapePath(req.URL.Path),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	stringToSign := strings.Join([]string{
+		algorithm,
+		amzDate,
+		scope,
+		sha256Hex(canonicalRequest),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), amzDate[:8])
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", algorithm+
+		" Credential="+s.accessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+
+		", Signature="+signature)
+}
+
+func canonicalQuery(values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var pairs []string
+	for _, k := range keys {
+		vs := append([]string(nil), values[k]...)
+		sort.Strings(vs)
+		for _, v := range vs {
+			pairs = append(pairs, escape(k)+"="+escape(v))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+func escapePath(path string) string {
+	if path == "" {
+		return "/"
+	}
+	segments := strings.Split(path, "/")
+	for i := range segments {
+		segments[i] = escape(segments[i])
+	}
+	return strings.Join(segments, "/")
+}
+
+// escape percent-encodes all characters except unreserved characters
+func escape(s string) string {
+	const hexDigits = "0123456789ABCDEF"
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			sb.WriteByte(c)
+		} else {
+			sb.WriteByte('%')
+			sb.WriteByte(hexDigits[c>>4])
+			sb.WriteByte(hexDigits[c&15])
+		}
+	}
+	return sb.String()
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,48 @@
+// Package captcha defines pluggable verifiers of captcha responses
+package captcha
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrRejected is returned by verifiers if a captcha response is rejected
+var ErrRejected = errors.New("captcha: response rejected")
+
+// Verifier verifies captcha responses solved by clients
+type Verifier interface {
+	// Verify verifies the captcha response from ip, ErrRejected returned if
+	// the response is invalid
+	Verify(ctx context.Context, response, ip string) error
+}
+
+// Driver opens a Verifier by driver-specific source
+type Driver func(source string) (Verifier, error)
+
+var (
+	driversMu sync.RWMutex
+	drivers   = make(map[string]Driver)
+)
+
+// Register makes a captcha driver available by the provided name
+func Register(name string, driver Driver) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	if _, dup := drivers[name]; dup {
+		panic("captcha: Register " + name + " called twice")
+	}
+	drivers[name] = driver
+}
+
+// Open opens a verifier specified by its driver name and a driver-specific source
+func Open(name string, source string) (Verifier, error) {
+	driversMu.RLock()
+	driver, ok := drivers[name]
+	driversMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("captcha: driver %q not found, forgot import?", name)
+	}
+	return driver(source)
+}
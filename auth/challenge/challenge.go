@@ -0,0 +1,185 @@
+package challenge
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gopherd/doge/crypto/cryptoutil"
+	"github.com/gopherd/doge/erron"
+	"github.com/gopherd/doge/service/module"
+	"github.com/gopherd/jwt"
+
+	"github.com/gopherd/gopherd/auth"
+	"github.com/gopherd/gopherd/auth/api"
+	"github.com/gopherd/gopherd/auth/captcha"
+	"github.com/gopherd/gopherd/auth/config"
+	"github.com/gopherd/gopherd/auth/pow"
+)
+
+// issuerSuffix is appended to issuer of pow challenge tokens, so challenge
+// tokens can't be used as access tokens
+const issuerSuffix = "#pow"
+
+var (
+	errDisabled         = erron.Errnof(api.BadArgument, "challenges disabled")
+	errInvalidChallenge = erron.Errnof(api.InvalidChallenge, "invalid challenge")
+	errInvalidSolution  = erron.Errnof(api.InvalidChallenge, "invalid solution")
+)
+
+type Service interface {
+	Config() *config.Config
+	Signer() auth.Signer
+}
+
+// New creates an auth.ChallengeModule
+func New(service Service) interface {
+	module.Module
+	auth.ChallengeModule
+} {
+	return newChallengeModule(service)
+}
+
+// challengeModule implements auth.ChallengeModule
+type challengeModule struct {
+	*module.BasicModule
+	service  Service
+	verifier captcha.Verifier // nil unless type is a captcha driver
+
+	// solved holds expiry of solved pow challenges keyed by salt, so they
+	// can't be replayed to this instance
+	solvedMu  sync.Mutex
+	solved    map[string]int64
+	lastSweep int64
+}
+
+func newChallengeModule(service Service) *challengeModule {
+	return &challengeModule{
+		BasicModule: module.NewBasicModule("challenge"),
+		service:     service,
+		solved:      make(map[string]int64),
+	}
+}
+
+func (mod *challengeModule) Init() error {
+	if err := mod.BasicModule.Init(); err != nil {
+		return err
+	}
+	cfg := mod.service.Config().Challenge
+	if cfg.Type == "" || cfg.Type == auth.ChallengePoW {
+		return nil
+	}
+	verifier, err := captcha.Open(cfg.Type, cfg.Source)
+	if err != nil {
+		return erron.Throwf("open captcha %q error %w", cfg.Type, err)
+	}
+	mod.verifier = verifier
+	return nil
+}
+
+func (mod *challengeModule) issuer() string {
+	return mod.service.Config().JWT.Issuer + issuerSuffix
+}
+
+// Required implements auth.ChallengeModule Required method
+func (mod *challengeModule) Required(api string) bool {
+	cfg := mod.service.Config().Challenge
+	if cfg.Type == "" {
+		return false
+	}
+	switch api {
+	case "smscode":
+		return cfg.SMSCode
+	case "authorize":
+		return cfg.Authorize
+	default:
+		return false
+	}
+}
+
+// Issue implements auth.ChallengeModule Issue method
+func (mod *challengeModule) Issue(ip string) (*auth.Challenge, error) {
+	cfg := mod.service.Config().Challenge
+	switch {
+	case cfg.Type == "":
+		return nil, errDisabled
+	case mod.verifier != nil:
+		return &auth.Challenge{
+			Type:    auth.ChallengeCaptcha,
+			SiteKey: cfg.SiteKey,
+		}, nil
+	}
+	claims := new(jwt.Claims)
+	claims.Issuer = mod.issuer()
+	claims.IssuedAt = time.Now().Unix()
+	claims.ExpiresAt = claims.IssuedAt + cfg.TTL
+	claims.Payload = jwt.Payload{
+		Salt: cryptoutil.GenerateSalt(16),
+		IP:   ip,
+		Values: map[string]any{
+			"difficulty": strconv.Itoa(cfg.Difficulty),
+		},
+	}
+	token, err := mod.service.Signer().Sign(claims)
+	if err != nil {
+		return nil, err
+	}
+	return &auth.Challenge{
+		Type:       auth.ChallengePoW,
+		Challenge:  token,
+		Difficulty: cfg.Difficulty,
+		ExpiredAt:  claims.ExpiresAt,
+	}, nil
+}
+
+// Verify implements auth.ChallengeModule Verify method
+func (mod *challengeModule) Verify(ctx context.Context, ip, challenge, solution string) error {
+	if mod.service.Config().Challenge.Type == "" {
+		return errDisabled
+	}
+	if mod.verifier != nil {
+		err := mod.verifier.Verify(ctx, solution, ip)
+		if errors.Is(err, captcha.ErrRejected) {
+			return errInvalidSolution
+		}
+		return err
+	}
+	claims, err := mod.service.Signer().Verify(mod.issuer(), challenge)
+	if err != nil || claims.Payload.IP != ip {
+		return errInvalidChallenge
+	}
+	difficulty, _ := claims.Payload.Values["difficulty"].(string)
+	n, err := strconv.Atoi(difficulty)
+	if err != nil {
+		return errInvalidChallenge
+	}
+	if !pow.Verify(challenge, solution, n) {
+		return errInvalidSolution
+	}
+	if !mod.solve(claims.Payload.Salt, claims.ExpiresAt) {
+		return errInvalidChallenge
+	}
+	return nil
+}
+
+// solve marks the challenge solved, it returns false if already solved
+func (mod *challengeModule) solve(salt string, expiredAt int64) bool {
+	now := time.Now().Unix()
+	mod.solvedMu.Lock()
+	defer mod.solvedMu.Unlock()
+	if now-mod.lastSweep >= 60 {
+		mod.lastSweep = now
+		for k, t := range mod.solved {
+			if t < now {
+				delete(mod.solved, k)
+			}
+		}
+	}
+	if _, ok := mod.solved[salt]; ok {
+		return false
+	}
+	mod.solved[salt] = expiredAt
+	return true
+}
@@ -0,0 +1,182 @@
+package client
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gopherd/doge/erron"
+	"github.com/gopherd/doge/service/module"
+	"github.com/gopherd/doge/time/timer"
+	"github.com/gopherd/jwt"
+
+	"github.com/gopherd/gopherd/auth"
+	"github.com/gopherd/gopherd/auth/api"
+	"github.com/gopherd/gopherd/auth/config"
+	"github.com/gopherd/gopherd/auth/scope"
+)
+
+const (
+	defaultTTL      = 300 // seconds
+	maxAssertionTTL = 300 // seconds
+)
+
+var errInvalidClient = erron.Errnof(api.InvalidClient, "invalid client")
+
+// clientScopes are scopes which can be granted to service clients, scopes of
+// players aren't allowed since client tokens have no account
+const clientScopes = scope.Service
+
+type Service interface {
+	Config() *config.Config
+}
+
+// New creates an auth.ClientModule
+func New(service Service) interface {
+	module.Module
+	auth.ClientModule
+} {
+	return newClientModule(service)
+}
+
+// clientModule implements auth.ClientModule
+type clientModule struct {
+	*module.BasicModule
+	service Service
+	ticker  *timer.Ticker
+
+	verifiersMu sync.Mutex
+	verifiers   map[string]*jwt.Verifier // public key file => verifier
+
+	// ids of used assertions which can't be used again until expired
+	usedMu sync.Mutex
+	used   map[string]int64
+}
+
+func newClientModule(service Service) *clientModule {
+	return &clientModule{
+		BasicModule: module.NewBasicModule("client"),
+		service:     service,
+		ticker:      timer.NewTicker(time.Minute),
+		verifiers:   make(map[string]*jwt.Verifier),
+		used:        make(map[string]int64),
+	}
+}
+
+func (mod *clientModule) Init() error {
+	if err := mod.BasicModule.Init(); err != nil {
+		return err
+	}
+	for _, c := range mod.service.Config().Clients {
+		s, unknown := scope.Parse(c.Scope)
+		if len(unknown) > 0 || s == scope.None || !clientScopes.Has(s) {
+			return erron.Throwf("scope %q of client %s not allowed, allowed scopes: %s", c.Scope, c.ID, clientScopes)
+		}
+	}
+	return nil
+}
+
+// Update overrides BasicModule Update method to remove expired assertions
+func (mod *clientModule) Update(now time.Time, dt time.Duration) {
+	mod.BasicModule.Update(now, dt)
+	if mod.ticker.Next(now) {
+		mod.usedMu.Lock()
+		defer mod.usedMu.Unlock()
+		for id, expiresAt := range mod.used {
+			if expiresAt < now.Unix() {
+				delete(mod.used, id)
+			}
+		}
+	}
+}
+
+func (mod *clientModule) find(id string) *config.Client {
+	if id == "" {
+		return nil
+	}
+	clients := mod.service.Config().Clients
+	for i := range clients {
+		if clients[i].ID == id {
+			return &clients[i]
+		}
+	}
+	return nil
+}
+
+func newClient(c *config.Client) *auth.Client {
+	s, _ := scope.Parse(c.Scope)
+	ttl := c.TTL
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+	return &auth.Client{
+		ID:    c.ID,
+		Scope: s,
+		TTL:   time.Duration(ttl) * time.Second,
+	}
+}
+
+// Authenticate implements auth.ClientModule Authenticate method
+func (mod *clientModule) Authenticate(id, secret string) (*auth.Client, error) {
+	c := mod.find(id)
+	if c == nil || c.Secret == "" || secret == "" {
+		return nil, errInvalidClient
+	}
+	sum := sha256.Sum256([]byte(secret))
+	if subtle.ConstantTimeCompare([]byte(hex.EncodeToString(sum[:])), []byte(strings.ToLower(c.Secret))) != 1 {
+		return nil, errInvalidClient
+	}
+	return newClient(c), nil
+}
+
+func (mod *clientModule) verifier(c *config.Client) (*jwt.Verifier, error) {
+	mod.verifiersMu.Lock()
+	defer mod.verifiersMu.Unlock()
+	key := c.PublicKey + "#" + c.KeyId
+	if v, ok := mod.verifiers[key]; ok {
+		return v, nil
+	}
+	v, err := jwt.NewVerifier(c.PublicKey, c.KeyId)
+	if err != nil {
+		return nil, err
+	}
+	mod.verifiers[key] = v
+	return v, nil
+}
+
+// AuthenticateAssertion implements auth.ClientModule AuthenticateAssertion method
+func (mod *clientModule) AuthenticateAssertion(id, assertion string) (*auth.Client, error) {
+	c := mod.find(id)
+	if c == nil || c.PublicKey == "" {
+		return nil, errInvalidClient
+	}
+	v, err := mod.verifier(c)
+	if err != nil {
+		mod.Logger().Error().
+			String("client", id).
+			String("public_key", c.PublicKey).
+			Error("error", err).
+			Print("load client public key error")
+		return nil, errInvalidClient
+	}
+	claims, err := v.Verify(id, assertion)
+	if err != nil {
+		return nil, erron.Errno(api.InvalidClient, err)
+	}
+	now := time.Now().Unix()
+	if claims.Subject != id || claims.Audience != mod.service.Config().JWT.Issuer || claims.Id == "" ||
+		claims.ExpiresAt <= now || claims.ExpiresAt > now+maxAssertionTTL {
+		return nil, erron.Errnof(api.InvalidClient, "invalid client assertion")
+	}
+	mod.usedMu.Lock()
+	defer mod.usedMu.Unlock()
+	key := id + "#" + claims.Id
+	if _, used := mod.used[key]; used {
+		return nil, erron.Errnof(api.InvalidClient, "client assertion replayed")
+	}
+	mod.used[key] = claims.ExpiresAt
+	return newClient(c), nil
+}
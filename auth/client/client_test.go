@@ -0,0 +1,34 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/gopherd/gopherd/auth/config"
+)
+
+type testService struct {
+	config *config.Config
+}
+
+func (s *testService) Config() *config.Config { return s.config }
+
+func TestInitScopes(t *testing.T) {
+	for _, tc := range []struct {
+		scope string
+		ok    bool
+	}{
+		{"service", true},
+		{"", false},
+		{"game", false},
+		{"service chat", false},
+		{"service admin", false},
+		{"service pay", false},
+	} {
+		cfg := new(config.Config).Default().(*config.Config)
+		cfg.Clients = []config.Client{{ID: "matchd", Scope: tc.scope}}
+		mod := newClientModule(&testService{config: cfg})
+		if err := mod.Init(); (err == nil) != tc.ok {
+			t.Fatalf("scope %q: want ok %v, got error %v", tc.scope, tc.ok, err)
+		}
+	}
+}
@@ -6,8 +6,49 @@ import (
 
 	"github.com/gopherd/doge/config"
 	"github.com/gopherd/doge/net/httputil"
+
+	"github.com/gopherd/gopherd/auth/api"
+	"github.com/gopherd/gopherd/auth/cors"
+	"github.com/gopherd/gopherd/auth/geo/policy"
+	"github.com/gopherd/gopherd/auth/keyring"
+	"github.com/gopherd/gopherd/auth/naming"
+	"github.com/gopherd/gopherd/auth/ratelimit"
 )
 
+// GeoPlace represents a country, subdivision or city of GeoLocation
+type GeoPlace struct {
+	IsoCode string            `json:"iso_code"`
+	Names   map[string]string `json:"names"` // language => name
+}
+
+// GeoLocation represents a custom geoip location
+type GeoLocation struct {
+	Country     GeoPlace `json:"country"`
+	Subdivision GeoPlace `json:"subdivision"`
+	City        GeoPlace `json:"city"`
+	Latitude    float64  `json:"latitude"`
+	Longitude   float64  `json:"longitude"`
+}
+
+// Client represents a registered service client
+type Client struct {
+	ID     string `json:"id"`
+	Secret string `json:"secret"` // sha256 hex of the client secret, secret disabled if empty
+	// PublicKey is the public key file which verifies client assertions,
+	// assertion disabled if empty
+	PublicKey string `json:"public_key"`
+	KeyId     string `json:"key_id"`
+	Scope     string `json:"scope"` // space-separated scopes which can be granted, only service allowed
+	TTL       int64  `json:"ttl"`   // seconds of access tokens, default: 300
+}
+
+// Role represents a role of accounts, e.g. gm or tester, and what it grants
+type Role struct {
+	Name        string   `json:"name"`
+	Permissions []string `json:"permissions"` // e.g. gm.kick, gm.item
+	Scope       string   `json:"scope"`       // space-separated scopes which can be granted to accounts of the role
+}
+
 type Config struct {
 	config.BasicConfig
 
@@ -17,21 +58,202 @@ type Config struct {
 	Proviers        map[string]string `json:"providers"`
 
 	JWT struct {
-		Filename string `json:"filename"`
-		Issuer   string `json:"issuer"`
-		KeyId    string `json:"key_id"`
+		Filename    string        `json:"filename"`
+		Issuer      string        `json:"issuer"`
+		KeyId       string        `json:"key_id"`
+		Keys        []keyring.Key `json:"keys"`          // private keys, filename and key_id used if empty
+		ActiveKeyId string        `json:"active_key_id"` // key to sign tokens, default: key_id
+		JWKSMaxAge  int64         `json:"jwks_max_age"`  // seconds to cache JWKS, default: 300
 	} `json:"jwt"`
 
 	GeoIP struct {
-		Filepath string `json:"filepath"`
+		Filepath       string        `json:"filepath"`
+		ASNFilepath    string        `json:"asn_filepath"`    // optional GeoLite2-ASN or GeoIP2-ISP database
+		HostingASNs    []uint        `json:"hosting_asns"`    // ASNs of hosting/datacenter networks
+		ReloadInterval int64         `json:"reload_interval"` // seconds to check database files modification, 0 to disable
+		CacheSize      int           `json:"cache_size"`      // max number of cached lookup results, 0 to disable
+		Policy         policy.Config `json:"policy"`
+
+		// OverridesFilepath is an optional json file of CIDR to location overrides
+		// which are consulted before databases, e.g.
+		//
+		//	[{"cidr": "203.0.113.0/24", "country": {"iso_code": "JP", "names": {"en": "Japan"}}}]
+		OverridesFilepath string `json:"overrides_filepath"`
+		// PrivateLocation is the location of loopback and private ips,
+		// lookup of these ips fails if it's nil
+		PrivateLocation *GeoLocation `json:"private_location"`
 	} `json:"geoip"`
 
 	Routers struct {
 		Authorize string `json:"authorize"` // default: /auth/authorize
 		Link      string `json:"link"`      // default: /auth/link
 		SMSCode   string `json:"smscode"`   // default: /auth/smscode
+		Profile   string `json:"profile"`   // default: /auth/profile
+
+		TwoFactorEnroll   string `json:"two_factor_enroll"`   // default: /auth/2fa/enroll
+		TwoFactorActivate string `json:"two_factor_activate"` // default: /auth/2fa/activate
+		TwoFactorDisable  string `json:"two_factor_disable"`  // default: /auth/2fa/disable
+		Verify2FA         string `json:"verify2fa"`           // default: /auth/verify2fa
+		WebAuthnRegister  string `json:"webauthn_register"`   // default: /auth/webauthn/register
+		WebAuthnLogin     string `json:"webauthn_login"`      // default: /auth/webauthn/login
+
+		Refresh       string `json:"refresh"`        // default: /auth/refresh
+		Sessions      string `json:"sessions"`       // default: /auth/sessions
+		RevokeSession string `json:"revoke_session"` // default: /auth/sessions/revoke
+		Token         string `json:"token"`          // default: /auth/token
+		Introspect    string `json:"introspect"`     // default: /auth/introspect
+		Userinfo      string `json:"userinfo"`       // default: /auth/userinfo
+		JWKS          string `json:"jwks"`           // default: /auth/jwks
+		OpenAPI       string `json:"openapi"`        // default: /auth/openapi.json
+
+		RegistrationReport string `json:"registration_report"` // default: /auth/report/registrations
+		Roles              string `json:"roles"`               // default: /auth/admin/roles
+		GrantRole          string `json:"grant_role"`          // default: /auth/admin/roles/grant
+		RevokeRole         string `json:"revoke_role"`         // default: /auth/admin/roles/revoke
+		Impersonate        string `json:"impersonate"`         // default: /auth/admin/impersonate
+
+		Metrics string `json:"metrics"` // default: /metrics
 	} `json:"routers"`
 
+	// CORS configures cross-origin resource sharing policies of apis
+	CORS struct {
+		Default cors.Policy            `json:"default"` // policy of apis not in routes
+		Routes  map[string]cors.Policy `json:"routes"`  // policies keyed by default routes, e.g. /auth/authorize
+	} `json:"cors"`
+
+	// RateLimit configures token bucket rate limits of apis
+	RateLimit struct {
+		Store  string                      `json:"store"`  // ratelimit store driver: memory or redis, default: memory
+		Source string                      `json:"source"` // driver-specific source, e.g. 127.0.0.1:6379?prefix=gopherd/ for redis
+		Routes map[string][]ratelimit.Rule `json:"routes"` // rules keyed by default routes, e.g. /auth/authorize
+	} `json:"rate_limit"`
+
+	// Challenge configures proof-of-work or captcha challenges required by
+	// abuse-prone apis
+	Challenge struct {
+		Type       string `json:"type"`       // pow or a captcha driver such as siteverify, challenges disabled if empty
+		Source     string `json:"source"`     // driver-specific source of captcha
+		SiteKey    string `json:"site_key"`   // site key of captcha responded to clients
+		Difficulty int    `json:"difficulty"` // leading zero bits of pow hashes, default: 20
+		TTL        int64  `json:"ttl"`        // seconds of pow challenges and states of risky authorize, default: 300
+		SMSCode    bool   `json:"smscode"`    // whether required by smscode
+		Authorize  bool   `json:"authorize"`  // whether required by authorize if login risk decision is verify
+	} `json:"challenge"`
+
+	// Risk configures rules of suspicious login detection, policy of each rule
+	// is one of allow, verify and deny, default: allow. Policy verify requires
+	// challenge.authorize, second factor satisfies it too if enabled.
+	Risk struct {
+		ImpossibleTravel struct {
+			Policy   string  `json:"policy"`
+			MaxSpeed float64 `json:"max_speed"` // km/h, default: 1000
+		} `json:"impossible_travel"`
+		NewCountry struct {
+			Policy string `json:"policy"`
+		} `json:"new_country"`
+		SharedIP struct {
+			Policy      string `json:"policy"`
+			MaxAccounts int    `json:"max_accounts"` // max accounts per ip in window, default: 10
+			Window      int64  `json:"window"`       // seconds, default: 86400
+		} `json:"shared_ip"`
+		HostingNetwork struct {
+			Policy string `json:"policy"` // requires geoip.asn_filepath and geoip.hosting_asns
+		} `json:"hosting_network"`
+	} `json:"risk"`
+
+	// Naming configures policy of display names, applied to names imported
+	// from providers and names updated by profile api
+	Naming struct {
+		Policy        naming.Config `json:"policy"`
+		WordsFilepath string        `json:"words_filepath"` // optional file of blocked words, one per line
+		// Discriminator enables Discord-style tags of 4 digits so that name#tag is unique
+		Discriminator bool `json:"discriminator"`
+	} `json:"naming"`
+
+	TwoFactor struct {
+		Issuer        string `json:"issuer"`         // issuer shown in authenticator apps, default: jwt.issuer
+		Skew          int    `json:"skew"`           // allowed time steps before and after, default: 1
+		ChallengeTTL  int64  `json:"challenge_ttl"`  // seconds, default: 300
+		RecoveryCodes int    `json:"recovery_codes"` // number of recovery codes, default: 10
+		MaxFailures   int    `json:"max_failures"`   // max failures before locked, default: 5
+		LockDuration  int64  `json:"lock_duration"`  // seconds, default: 900
+	} `json:"two_factor"`
+
+	// WebAuthn configures passkey login, see package webauthn
+	WebAuthn struct {
+		RPID             string   `json:"rp_id"`             // relying party id, e.g. example.com, webauthn disabled if empty
+		RPName           string   `json:"rp_name"`           // relying party name shown by authenticators
+		Origins          []string `json:"origins"`           // allowed origins, e.g. https://www.example.com
+		UserVerification string   `json:"user_verification"` // required, preferred or discouraged, default: preferred
+		Timeout          int64    `json:"timeout"`           // seconds of ceremonies, default: 300
+		// Secret signs challenges, it's required if webauthn enabled and should
+		// be same for all authd instances
+		Secret string `json:"secret"`
+	} `json:"webauthn"`
+
+	// Avatar configures mirroring of avatars to blob store
+	Avatar struct {
+		Store    string `json:"store"`     // blob store driver: fs or s3, mirroring disabled if empty
+		Source   string `json:"source"`    // driver-specific source of blob store
+		Size     int    `json:"size"`      // width and height of mirrored avatars, default: 256
+		MaxBytes int64  `json:"max_bytes"` // max bytes of downloaded avatars, default: 2MB
+		Timeout  int64  `json:"timeout"`   // seconds of mirroring an avatar, default: 30
+		Workers  int    `json:"workers"`   // number of mirroring goroutines, default: 4
+		// AllowedHosts are hosts avatars can be mirrored from, e.g. provider
+		// CDNs, a host starting with "." allows its subdomains. All public
+		// hosts are allowed if empty, internal addresses are always rejected.
+		AllowedHosts []string `json:"allowed_hosts"`
+	} `json:"avatar"`
+
+	// Scope configures scopes granted to access tokens, see package scope
+	Scope struct {
+		Default   string  `json:"default"`   // scopes granted if not requested, default: game chat
+		Grantable string  `json:"grantable"` // scopes which can be requested by all accounts, default: game chat readonly
+		Admins    []int64 `json:"admins"`    // accounts which can request all scopes except service
+	} `json:"scope"`
+
+	// Clients are registered service clients which exchange client credentials
+	// for access tokens by /auth/token
+	Clients []Client `json:"clients"`
+
+	// Roles are roles which can be granted to accounts by admin apis, roles
+	// and permissions of accounts are embedded in access tokens
+	Roles []Role `json:"roles"`
+
+	// Session configures login sessions of devices
+	Session struct {
+		MaxSessions int    `json:"max_sessions"` // max active sessions per account, least recently used are revoked, 0 for unlimited
+		Gated       string `json:"gated"`        // service name of gated which disconnects revoked sessions, default: gated
+	} `json:"session"`
+
+	Events struct {
+		Topic string `json:"topic"` // mq topic of account events, events disabled if empty
+	} `json:"events"`
+
+	Admin struct {
+		// Key is the shared secret required by admin apis in the X-Admin-Key header,
+		// admin apis are disabled if key is empty
+		Key string `json:"key"`
+	} `json:"admin"`
+
+	Metrics struct {
+		// Token is the bearer token required by the metrics api, which should
+		// differ from admin key since it's held by scrapers. Metrics are
+		// disabled if empty.
+		Token string `json:"token"`
+	} `json:"metrics"`
+
+	// Impersonation configures impersonation tokens minted by admin apis for
+	// customer service to act as players
+	Impersonation struct {
+		TTL   int64  `json:"ttl"`   // max seconds of impersonation tokens, default: 900
+		Scope string `json:"scope"` // space-separated scopes which can be granted, default: game readonly
+	} `json:"impersonation"`
+
+	// Messages adds or overrides localized messages of error responses, keyed
+	// by language and errno, e.g. {"ja": {"201": "..."}}
+	Messages map[string]map[int]string `json:"messages"`
+
 	DB struct {
 		DSN string `json:"dsn"` // mysql dsn
 	}
@@ -49,5 +271,33 @@ func (*Config) Default() config.Configurator {
 		home = "."
 	}
 	c.GeoIP.Filepath = filepath.Join(home, "geoip", "GeoLite2-City.mmdb")
+	c.GeoIP.ReloadInterval = 3600
+	c.GeoIP.CacheSize = 4096
+	c.TwoFactor.Skew = 1
+	c.TwoFactor.ChallengeTTL = 300
+	c.TwoFactor.RecoveryCodes = 10
+	c.TwoFactor.MaxFailures = 5
+	c.TwoFactor.LockDuration = 900
+	c.WebAuthn.UserVerification = "preferred"
+	c.WebAuthn.Timeout = 300
+	c.Scope.Default = "game chat"
+	c.Scope.Grantable = "game chat readonly"
+	c.Session.Gated = "gated"
+	// authorize was allowed from any origin before cors policies configurable
+	c.CORS.Routes = map[string]cors.Policy{
+		api.RouteAuthorize: {AllowedOrigins: []string{"*"}},
+	}
+	c.RateLimit.Store = "memory"
+	c.Challenge.Difficulty = 20
+	c.Challenge.TTL = 300
+	c.Impersonation.TTL = 900
+	c.Impersonation.Scope = "game readonly"
+	c.Avatar.Size = 256
+	c.Avatar.MaxBytes = 2 << 20
+	c.Avatar.Timeout = 30
+	c.Avatar.Workers = 4
+	c.Risk.ImpossibleTravel.MaxSpeed = 1000
+	c.Risk.SharedIP.MaxAccounts = 10
+	c.Risk.SharedIP.Window = 86400
 	return c
 }
@@ -0,0 +1,151 @@
+// Package cors implements cross-origin resource sharing policies of http apis
+package cors
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Policy represents a CORS policy, CORS disabled if AllowedOrigins is empty
+type Policy struct {
+	// AllowedOrigins are origins allowed to request, e.g. https://example.com,
+	// "*" allows all origins and https://*.example.com allows subdomains
+	AllowedOrigins []string `json:"allowed_origins"`
+	// AllowedMethods are methods allowed by preflight requests, default: GET and POST
+	AllowedMethods []string `json:"allowed_methods"`
+	// AllowedHeaders are headers allowed by preflight requests, "*" allows
+	// all requested headers, default: requested headers
+	AllowedHeaders []string `json:"allowed_headers"`
+	// ExposedHeaders are response headers exposed to scripts
+	ExposedHeaders []string `json:"exposed_headers"`
+	// AllowCredentials allows requests with credentials, e.g. cookies. The
+	// request origin instead of "*" is responded if it's true.
+	AllowCredentials bool `json:"allow_credentials"`
+	// MaxAge is seconds preflight results can be cached, 0 for unspecified
+	MaxAge int `json:"max_age"`
+}
+
+// Enabled reports whether the policy is enabled
+func (p *Policy) Enabled() bool {
+	return len(p.AllowedOrigins) > 0
+}
+
+// AllowOrigin reports whether the origin allowed
+func (p *Policy) AllowOrigin(origin string) bool {
+	for _, allowed := range p.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+		if i := strings.Index(allowed, "*."); i >= 0 {
+			prefix, suffix := allowed[:i], allowed[i+1:]
+			if len(origin) > len(prefix)+len(suffix) &&
+				strings.HasPrefix(strings.ToLower(origin), strings.ToLower(prefix)) &&
+				strings.HasSuffix(strings.ToLower(origin), strings.ToLower(suffix)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *Policy) allowMethod(method string) bool {
+	if len(p.AllowedMethods) == 0 {
+		return method == http.MethodGet || method == http.MethodPost
+	}
+	for _, m := range p.AllowedMethods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Policy) allowHeaders(requested string) bool {
+	if len(p.AllowedHeaders) == 0 {
+		return true
+	}
+	for _, h := range strings.Split(requested, ",") {
+		h = strings.TrimSpace(h)
+		if h == "" {
+			continue
+		}
+		allowed := false
+		for _, a := range p.AllowedHeaders {
+			if a == "*" || strings.EqualFold(a, h) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return true
+}
+
+// Handle sets CORS headers of the response by the policy and reports whether
+// the request is a preflight request, which has been responded and shouldn't
+// be handled anymore
+func (p *Policy) Handle(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+	header := w.Header()
+	header.Add("Vary", "Origin")
+	if preflight {
+		header.Add("Vary", "Access-Control-Request-Method")
+		header.Add("Vary", "Access-Control-Request-Headers")
+	}
+	if origin == "" || !p.AllowOrigin(origin) {
+		if preflight {
+			w.WriteHeader(http.StatusForbidden)
+		}
+		return preflight
+	}
+	if preflight {
+		method := r.Header.Get("Access-Control-Request-Method")
+		headers := r.Header.Get("Access-Control-Request-Headers")
+		if !p.allowMethod(method) || !p.allowHeaders(headers) {
+			w.WriteHeader(http.StatusForbidden)
+			return true
+		}
+	}
+
+	if p.AllowCredentials || !p.allowsAnyOrigin() {
+		header.Set("Access-Control-Allow-Origin", origin)
+	} else {
+		header.Set("Access-Control-Allow-Origin", "*")
+	}
+	if p.AllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if !preflight {
+		if len(p.ExposedHeaders) > 0 {
+			header.Set("Access-Control-Expose-Headers", strings.Join(p.ExposedHeaders, ", "))
+		}
+		return false
+	}
+
+	if len(p.AllowedMethods) > 0 {
+		header.Set("Access-Control-Allow-Methods", strings.Join(p.AllowedMethods, ", "))
+	} else {
+		header.Set("Access-Control-Allow-Methods", "GET, POST")
+	}
+	if headers := r.Header.Get("Access-Control-Request-Headers"); headers != "" {
+		header.Set("Access-Control-Allow-Headers", headers)
+	}
+	if p.MaxAge > 0 {
+		header.Set("Access-Control-Max-Age", strconv.Itoa(p.MaxAge))
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
+
+func (p *Policy) allowsAnyOrigin() bool {
+	for _, allowed := range p.AllowedOrigins {
+		if allowed == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAllowOrigin(t *testing.T) {
+	p := &Policy{AllowedOrigins: []string{"https://example.com", "https://*.gopherd.com"}}
+	for _, tc := range []struct {
+		origin string
+		want   bool
+	}{
+		{"https://example.com", true},
+		{"https://EXAMPLE.com", true},
+		{"http://example.com", false},
+		{"https://a.gopherd.com", true},
+		{"https://a.b.gopherd.com", true},
+		{"https://.gopherd.com", false},
+		{"https://gopherd.com", false},
+		{"https://evilgopherd.com", false},
+	} {
+		if got := p.AllowOrigin(tc.origin); got != tc.want {
+			t.Fatalf("origin %q: want %v, got %v", tc.origin, tc.want, got)
+		}
+	}
+}
+
+func TestHandle(t *testing.T) {
+	p := &Policy{
+		AllowedOrigins: []string{"*"},
+		AllowedHeaders: []string{"Content-Type", "X-Lang"},
+		ExposedHeaders: []string{"Retry-After"},
+		MaxAge:         600,
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/auth/authorize", nil)
+	r.Header.Set("Origin", "https://example.com")
+	w := httptest.NewRecorder()
+	if p.Handle(w, r) {
+		t.Fatal("simple request handled as preflight")
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Fatalf("unexpected allowed origin %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Expose-Headers"); got != "Retry-After" {
+		t.Fatalf("unexpected exposed headers %q", got)
+	}
+
+	r = httptest.NewRequest(http.MethodOptions, "/auth/authorize", nil)
+	r.Header.Set("Origin", "https://example.com")
+	r.Header.Set("Access-Control-Request-Method", "POST")
+	r.Header.Set("Access-Control-Request-Headers", "content-type, x-lang")
+	w = httptest.NewRecorder()
+	if !p.Handle(w, r) {
+		t.Fatal("preflight request not handled")
+	}
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("unexpected status %d", w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Headers"); got != "content-type, x-lang" {
+		t.Fatalf("unexpected allowed headers %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Max-Age"); got != "600" {
+		t.Fatalf("unexpected max age %q", got)
+	}
+
+	r.Header.Set("Access-Control-Request-Method", "DELETE")
+	w = httptest.NewRecorder()
+	if !p.Handle(w, r) || w.Code != http.StatusForbidden {
+		t.Fatalf("disallowed method: unexpected status %d", w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Fatalf("disallowed method: unexpected allowed origin %q", got)
+	}
+
+	p.AllowCredentials = true
+	r = httptest.NewRequest(http.MethodGet, "/auth/profile", nil)
+	r.Header.Set("Origin", "https://example.com")
+	w = httptest.NewRecorder()
+	p.Handle(w, r)
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://example.com" {
+		t.Fatalf("credentials: unexpected allowed origin %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+		t.Fatalf("credentials: unexpected allow credentials %q", got)
+	}
+}
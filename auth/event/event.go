@@ -0,0 +1,63 @@
+package event
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gopherd/doge/mq"
+	"github.com/gopherd/doge/service/module"
+
+	"github.com/gopherd/gopherd/auth"
+	"github.com/gopherd/gopherd/auth/config"
+)
+
+type Service interface {
+	Config() *config.Config
+	MQ() mq.Conn
+}
+
+// New creates an auth.EventModule
+func New(service Service) interface {
+	module.Module
+	auth.EventModule
+} {
+	return newEventModule(service)
+}
+
+// eventModule implements auth.EventModule
+type eventModule struct {
+	*module.BasicModule
+	service Service
+}
+
+func newEventModule(service Service) *eventModule {
+	return &eventModule{
+		BasicModule: module.NewBasicModule("event"),
+		service:     service,
+	}
+}
+
+// message is the content published to mq
+type message struct {
+	Type string     `json:"type"`
+	Time int64      `json:"time"`
+	Data auth.Event `json:"data"`
+}
+
+// Publish implements auth.EventModule Publish method
+func (mod *eventModule) Publish(event auth.Event) error {
+	topic := mod.service.Config().Events.Topic
+	conn := mod.service.MQ()
+	if topic == "" || conn == nil {
+		return nil
+	}
+	content, err := json.Marshal(message{
+		Type: event.Type(),
+		Time: time.Now().Unix(),
+		Data: event,
+	})
+	if err != nil {
+		return err
+	}
+	return conn.Publish(topic, content)
+}
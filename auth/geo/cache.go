@@ -0,0 +1,62 @@
+package geo
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// cache is a LRU cache of city lookup results
+type cache struct {
+	mu       sync.Mutex
+	capacity int
+	list     *list.List
+	items    map[string]*list.Element
+}
+
+type cacheEntry struct {
+	ip   string
+	city *geoip2.City
+}
+
+func newCache(capacity int) *cache {
+	return &cache{
+		capacity: capacity,
+		list:     list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+func (c *cache) get(ip string) (*geoip2.City, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[ip]; ok {
+		c.list.MoveToFront(e)
+		return e.Value.(*cacheEntry).city, true
+	}
+	return nil, false
+}
+
+func (c *cache) add(ip string, city *geoip2.City) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[ip]; ok {
+		c.list.MoveToFront(e)
+		e.Value.(*cacheEntry).city = city
+		return
+	}
+	c.items[ip] = c.list.PushFront(&cacheEntry{ip: ip, city: city})
+	for c.list.Len() > c.capacity {
+		e := c.list.Back()
+		c.list.Remove(e)
+		delete(c.items, e.Value.(*cacheEntry).ip)
+	}
+}
+
+func (c *cache) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.list.Init()
+	c.items = make(map[string]*list.Element)
+}
@@ -0,0 +1,46 @@
+package geo
+
+import (
+	"os"
+	"time"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// database wraps a geoip2.Reader with its file modification time
+type database struct {
+	filepath string
+	modTime  time.Time
+	reader   *geoip2.Reader
+}
+
+func openDatabase(filepath string) (*database, error) {
+	info, err := os.Stat(filepath)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := geoip2.Open(filepath)
+	if err != nil {
+		return nil, err
+	}
+	return &database{
+		filepath: filepath,
+		modTime:  info.ModTime(),
+		reader:   reader,
+	}, nil
+}
+
+// modified reports whether the database file modified since opened
+func (db *database) modified() bool {
+	info, err := os.Stat(db.filepath)
+	if err != nil {
+		return false
+	}
+	return !info.ModTime().Equal(db.modTime)
+}
+
+func (db *database) close() {
+	if db != nil && db.reader != nil {
+		db.reader.Close()
+	}
+}
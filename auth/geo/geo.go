@@ -3,17 +3,23 @@ package geo
 import (
 	"errors"
 	"net"
-	"strings"
+	"sync"
+	"time"
 
 	"github.com/gopherd/doge/erron"
 	"github.com/gopherd/doge/service/module"
+	"github.com/gopherd/doge/time/timer"
 	"github.com/oschwald/geoip2-golang"
 
 	"github.com/gopherd/gopherd/auth"
 	"github.com/gopherd/gopherd/auth/config"
+	"github.com/gopherd/gopherd/auth/geo/policy"
 )
 
-var errInvalidIP = errors.New("invalid ip")
+var (
+	errInvalidIP       = errors.New("invalid ip")
+	errASNNotAvailable = errors.New("asn database not available")
+)
 
 type Service interface {
 	Config() *config.Config
@@ -30,7 +36,16 @@ func New(service Service) interface {
 type geoModule struct {
 	*module.BasicModule
 	service Service
-	db      *geoip2.Reader
+	policy  *policy.Policy
+	hosting map[uint]bool
+	ticker  *timer.Ticker
+	cache   *cache       // nil if cache disabled
+	private *geoip2.City // location of private ips
+
+	mu        sync.RWMutex
+	db        *database
+	asn       *database  // optional ASN or ISP database
+	overrides *overrides // optional custom locations
 }
 
 func newGeoModule(service Service) *geoModule {
@@ -44,40 +59,207 @@ func (mod *geoModule) Init() error {
 	if err := mod.BasicModule.Init(); err != nil {
 		return err
 	}
-	filepath := mod.service.Config().GeoIP.Filepath
-	db, err := geoip2.Open(filepath)
+	cfg := mod.service.Config()
+	filepath := cfg.GeoIP.Filepath
+	db, err := openDatabase(filepath)
 	if err != nil {
 		return erron.Throwf("load geoip from %q error: %w", filepath, err)
 	}
 	mod.db = db
+	if filepath = cfg.GeoIP.ASNFilepath; filepath != "" {
+		asn, err := openDatabase(filepath)
+		if err != nil {
+			return erron.Throwf("load asn from %q error: %w", filepath, err)
+		}
+		mod.asn = asn
+	}
+	if filepath = cfg.GeoIP.OverridesFilepath; filepath != "" {
+		overrides, err := loadOverrides(filepath)
+		if err != nil {
+			return erron.Throwf("load overrides from %q error: %w", filepath, err)
+		}
+		mod.overrides = overrides
+	}
+	if cfg.GeoIP.PrivateLocation != nil {
+		mod.private = newCity(cfg.GeoIP.PrivateLocation)
+	}
+	mod.policy = policy.New(cfg.GeoIP.Policy)
+	mod.hosting = make(map[uint]bool, len(cfg.GeoIP.HostingASNs))
+	for _, asn := range cfg.GeoIP.HostingASNs {
+		mod.hosting[asn] = true
+	}
+	if cfg.GeoIP.CacheSize > 0 {
+		mod.cache = newCache(cfg.GeoIP.CacheSize)
+	}
+	if cfg.GeoIP.ReloadInterval > 0 {
+		mod.ticker = timer.NewTicker(time.Duration(cfg.GeoIP.ReloadInterval) * time.Second)
+	}
 	return nil
 }
 
 func (mod *geoModule) Shutdown() {
 	defer mod.BasicModule.Shutdown()
-	if mod.db != nil {
-		mod.db.Close()
+	mod.mu.Lock()
+	defer mod.mu.Unlock()
+	mod.db.close()
+	mod.asn.close()
+}
+
+// Update overrides BasicModule Update method to reload modified databases
+func (mod *geoModule) Update(now time.Time, dt time.Duration) {
+	mod.BasicModule.Update(now, dt)
+	if mod.ticker != nil && mod.ticker.Next(now) {
+		mod.reload(&mod.db)
+		mod.reload(&mod.asn)
+		mod.reloadOverrides()
 	}
 }
 
+// reloadOverrides reloads overrides if it's file modified
+func (mod *geoModule) reloadOverrides() {
+	mod.mu.RLock()
+	old := mod.overrides
+	mod.mu.RUnlock()
+	if old == nil || !old.modified() {
+		return
+	}
+	overrides, err := loadOverrides(old.filepath)
+	if err != nil {
+		mod.Logger().Warn().
+			String("filepath", old.filepath).
+			Error("error", err).
+			Print("reload overrides error")
+		return
+	}
+	mod.mu.Lock()
+	mod.overrides = overrides
+	mod.mu.Unlock()
+	mod.Logger().Info().
+		String("filepath", overrides.filepath).
+		Int("size", len(overrides.overrides)).
+		Print("overrides reloaded")
+}
+
+// reload reopens the database if it's file modified and swaps it atomically
+func (mod *geoModule) reload(ptr **database) {
+	mod.mu.RLock()
+	old := *ptr
+	mod.mu.RUnlock()
+	if old == nil || !old.modified() {
+		return
+	}
+	db, err := openDatabase(old.filepath)
+	if err != nil {
+		mod.Logger().Warn().
+			String("filepath", old.filepath).
+			Error("error", err).
+			Print("reload database error")
+		return
+	}
+	mod.mu.Lock()
+	*ptr = db
+	mod.mu.Unlock()
+	old.close()
+	if mod.cache != nil && ptr == &mod.db {
+		mod.cache.purge()
+	}
+	mod.Logger().Info().
+		String("filepath", db.filepath).
+		String("type", db.reader.Metadata().DatabaseType).
+		Print("database reloaded")
+}
+
 func (mod *geoModule) QueryLocation(ip, lang string) (country, province, city string, err error) {
-	if lang == "" || strings.HasPrefix(lang, "en-") {
-		lang = "en"
+	var r *geoip2.City
+	r, err = mod.lookup(ip)
+	if err != nil {
+		return
+	}
+	langs := fallbackLanguages(lang)
+	city = localizedName(r.City.Names, langs)
+	if len(r.Subdivisions) > 0 {
+		province = localizedName(r.Subdivisions[0].Names, langs)
+	}
+	country = localizedName(r.Country.Names, langs)
+	return
+}
+
+func (mod *geoModule) QueryPosition(ip string) (pos auth.Position, err error) {
+	var r *geoip2.City
+	r, err = mod.lookup(ip)
+	if err != nil {
+		return
+	}
+	pos.Country = r.Country.IsoCode
+	if len(r.Subdivisions) > 0 {
+		pos.Region = r.Subdivisions[0].IsoCode
+	}
+	pos.Latitude = r.Location.Latitude
+	pos.Longitude = r.Location.Longitude
+	return
+}
+
+func (mod *geoModule) Allowed(ip string) bool {
+	pos, err := mod.QueryPosition(ip)
+	if err != nil {
+		return mod.policy.Allowed("", "")
 	}
+	return mod.policy.Allowed(pos.Country, pos.Region)
+}
+
+func (mod *geoModule) QueryNetwork(ip string) (network auth.Network, err error) {
 	x := net.ParseIP(ip)
 	if len(x) == 0 || x.IsUnspecified() {
 		err = errInvalidIP
 		return
 	}
-	var r *geoip2.City
-	r, err = mod.db.City(x)
-	if err != nil {
+	mod.mu.RLock()
+	defer mod.mu.RUnlock()
+	if mod.asn == nil {
+		err = errASNNotAvailable
 		return
 	}
-	city = r.City.Names[lang]
-	if len(r.Subdivisions) > 0 {
-		province = r.Subdivisions[0].Names[lang]
+	if mod.asn.reader.Metadata().DatabaseType == "GeoLite2-ASN" {
+		var r *geoip2.ASN
+		if r, err = mod.asn.reader.ASN(x); err != nil {
+			return
+		}
+		network.ASN = r.AutonomousSystemNumber
+		network.Organization = r.AutonomousSystemOrganization
+	} else {
+		var r *geoip2.ISP
+		if r, err = mod.asn.reader.ISP(x); err != nil {
+			return
+		}
+		network.ASN = r.AutonomousSystemNumber
+		network.Organization = r.AutonomousSystemOrganization
+		network.ISP = r.ISP
 	}
-	country = r.Country.Names[lang]
+	network.Hosting = mod.hosting[network.ASN]
 	return
 }
+
+func (mod *geoModule) lookup(ip string) (*geoip2.City, error) {
+	x := net.ParseIP(ip)
+	if len(x) == 0 || x.IsUnspecified() {
+		return nil, errInvalidIP
+	}
+	mod.mu.RLock()
+	defer mod.mu.RUnlock()
+	if r := mod.overrides.lookup(x); r != nil {
+		return r, nil
+	}
+	if mod.private != nil && isPrivateIP(x) {
+		return mod.private, nil
+	}
+	if mod.cache != nil {
+		if r, ok := mod.cache.get(ip); ok {
+			return r, nil
+		}
+	}
+	r, err := mod.db.reader.City(x)
+	if err == nil && mod.cache != nil {
+		mod.cache.add(ip, r)
+	}
+	return r, err
+}
@@ -1,8 +1,13 @@
 package geo_test
 
+//go:generate go run testdata/gen.go
+
 import (
 	"testing"
 
+	"github.com/gopherd/doge/service/module"
+
+	"github.com/gopherd/gopherd/auth"
 	"github.com/gopherd/gopherd/auth/config"
 	"github.com/gopherd/gopherd/auth/geo"
 )
@@ -13,24 +18,100 @@ type testingService struct {
 
 func (s *testingService) Config() *config.Config { return s.config }
 
-func TestQueryLocation(t *testing.T) {
+func newTestingModule(t *testing.T) interface {
+	module.Module
+	auth.GeoModule
+} {
 	service := new(testingService)
 	service.config = (*config.Config)(nil).Default().(*config.Config)
+	service.config.GeoIP.Filepath = "testdata/GeoLite2-City-Test.mmdb"
+	service.config.GeoIP.ASNFilepath = "testdata/GeoLite2-ASN-Test.mmdb"
+	service.config.GeoIP.HostingASNs = []uint{16509}
+	service.config.GeoIP.OverridesFilepath = "testdata/overrides.json"
+	service.config.GeoIP.PrivateLocation = &config.GeoLocation{
+		Country: config.GeoPlace{IsoCode: "JP", Names: map[string]string{"en": "Japan"}},
+	}
 	mod := geo.New(service)
 	if err := mod.Init(); err != nil {
 		t.Fatalf(err.Error())
 	}
 	t.Cleanup(mod.Shutdown)
-	for _, tc := range [][2]string{
-		{"en", "54.199.163.96"},
-		{"zh-CN", "111.192.98.171"},
-		{"en", "218.88.223.255"},
-		{"zh-CN", "218.88.223.255"},
+	return mod
+}
+
+func TestQueryLocation(t *testing.T) {
+	mod := newTestingModule(t)
+	for _, tc := range []struct {
+		lang, ip                string
+		country, province, city string
+	}{
+		{"en", "54.199.163.96", "Japan", "Tokyo", "Tokyo"},
+		{"zh-CN", "111.192.98.171", "中国", "北京市", "北京"},
+		{"en", "218.88.223.255", "China", "Sichuan", "Chengdu"},
+		{"zh-CN", "218.88.223.255", "中国", "四川省", "成都"},
+		{"", "218.88.223.255", "China", "Sichuan", "Chengdu"},
+		{"en-US", "54.199.163.96", "Japan", "Tokyo", "Tokyo"},
+		{"zh-TW", "218.88.223.255", "中国", "四川省", "成都"},
+		{"zh_Hant_HK", "111.192.98.171", "中国", "北京市", "北京"},
+		{"zh", "54.199.163.96", "日本", "东京都", "东京"},
+		{"ja-JP", "54.199.163.96", "日本", "東京都", "東京"},
+		{"pt-BR", "200.160.1.1", "Brasil", "São Paulo", "São Paulo"},
+		{"pt", "54.199.163.96", "Japão", "Tokyo", "Tokyo"},
+		{"pt-PT", "218.88.223.255", "China", "Sichuan", "Chengdu"},
+		{"fr-FR", "111.192.98.171", "China", "Beijing", "Beijing"},
+		{"zh-CN,zh;q=0.9", "111.192.98.171", "中国", "北京市", "北京"},
+		// overrides
+		{"zh-CN", "10.8.1.1", "中国", "四川省", "成都"},
+		{"en", "203.0.113.7", "Singapore", "", "Singapore"},
+		// private location
+		{"en", "127.0.0.1", "Japan", "", ""},
+		{"en", "192.168.1.1", "Japan", "", ""},
 	} {
-		country, province, city, err := mod.QueryLocation(tc[1], tc[0])
+		country, province, city, err := mod.QueryLocation(tc.ip, tc.lang)
 		if err != nil {
 			t.Fatalf(err.Error())
 		}
-		t.Logf("country=%s, province=%s, city=%s", country, province, city)
+		if country != tc.country || province != tc.province || city != tc.city {
+			t.Errorf("QueryLocation(%q, %q): want %s/%s/%s, got %s/%s/%s",
+				tc.ip, tc.lang, tc.country, tc.province, tc.city, country, province, city)
+		}
+	}
+	// cached result
+	if country, _, _, err := mod.QueryLocation("54.199.163.96", "en"); err != nil || country != "Japan" {
+		t.Errorf("QueryLocation from cache: want Japan, got %s, error %v", country, err)
+	}
+	if _, _, _, err := mod.QueryLocation("invalid", "en"); err == nil {
+		t.Errorf("QueryLocation for invalid ip: error expected")
+	}
+}
+
+func TestQueryPosition(t *testing.T) {
+	mod := newTestingModule(t)
+	pos, err := mod.QueryPosition("218.88.223.255")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if pos.Country != "CN" || pos.Region != "SC" || pos.Latitude == 0 || pos.Longitude == 0 {
+		t.Errorf("QueryPosition: unexpected position %+v", pos)
+	}
+}
+
+func TestQueryNetwork(t *testing.T) {
+	mod := newTestingModule(t)
+	for _, tc := range []struct {
+		ip      string
+		asn     uint
+		hosting bool
+	}{
+		{"54.199.163.96", 16509, true},
+		{"111.192.98.171", 4808, false},
+	} {
+		network, err := mod.QueryNetwork(tc.ip)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if network.ASN != tc.asn || network.Hosting != tc.hosting || network.Organization == "" {
+			t.Errorf("QueryNetwork(%q): unexpected network %+v", tc.ip, network)
+		}
 	}
 }
@@ -0,0 +1,72 @@
+package geo
+
+import (
+	"strings"
+)
+
+const defaultLanguage = "en"
+
+// aliases maps a base language to the regional variant available in geoip databases
+var aliases = map[string]string{
+	"zh": "zh-CN",
+	"pt": "pt-BR",
+}
+
+// normalizeLanguage normalizes BCP-47 language tag, e.g. zh_hant_tw => zh-Hant-TW.
+// Only the first tag of an Accept-Language like list is used.
+func normalizeLanguage(lang string) string {
+	if i := strings.IndexAny(lang, ",;"); i >= 0 {
+		lang = lang[:i]
+	}
+	subtags := strings.Split(strings.ReplaceAll(strings.TrimSpace(lang), "_", "-"), "-")
+	for i, subtag := range subtags {
+		switch {
+		case i == 0:
+			subtags[i] = strings.ToLower(subtag)
+		case len(subtag) == 4:
+			subtags[i] = strings.ToUpper(subtag[:1]) + strings.ToLower(subtag[1:])
+		default:
+			subtags[i] = strings.ToUpper(subtag)
+		}
+	}
+	return strings.Join(subtags, "-")
+}
+
+// fallbackLanguages returns the fallback chain of lang, e.g.
+//
+//	zh-TW => zh-TW, zh, zh-CN, en
+//	pt-BR => pt-BR, pt, en
+func fallbackLanguages(lang string) []string {
+	lang = normalizeLanguage(lang)
+	var langs = make([]string, 0, 4)
+	add := func(x string) {
+		if x == "" {
+			return
+		}
+		for _, l := range langs {
+			if l == x {
+				return
+			}
+		}
+		langs = append(langs, x)
+	}
+	add(lang)
+	base := lang
+	if i := strings.IndexByte(lang, '-'); i >= 0 {
+		base = lang[:i]
+	}
+	add(base)
+	add(aliases[base])
+	add(defaultLanguage)
+	return langs
+}
+
+// localizedName returns the first found name in languages order
+func localizedName(names map[string]string, langs []string) string {
+	for _, lang := range langs {
+		if name, ok := names[lang]; ok && name != "" {
+			return name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,114 @@
+package geo
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/oschwald/geoip2-golang"
+
+	"github.com/gopherd/gopherd/auth/config"
+)
+
+// override holds custom location of an ip range
+type override struct {
+	ipnet *net.IPNet
+	ones  int
+	city  *geoip2.City
+}
+
+// overrides holds overrides loaded from file sorted by prefix length descending
+type overrides struct {
+	filepath  string
+	modTime   time.Time
+	overrides []override
+}
+
+func loadOverrides(filepath string) (*overrides, error) {
+	info, err := os.Stat(filepath)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(filepath)
+	if err != nil {
+		return nil, err
+	}
+	var entries []struct {
+		CIDR string `json:"cidr"`
+		config.GeoLocation
+	}
+	if err := json.Unmarshal(content, &entries); err != nil {
+		return nil, err
+	}
+	o := &overrides{
+		filepath:  filepath,
+		modTime:   info.ModTime(),
+		overrides: make([]override, 0, len(entries)),
+	}
+	for _, entry := range entries {
+		_, ipnet, err := net.ParseCIDR(entry.CIDR)
+		if err != nil {
+			return nil, err
+		}
+		ones, _ := ipnet.Mask.Size()
+		o.overrides = append(o.overrides, override{
+			ipnet: ipnet,
+			ones:  ones,
+			city:  newCity(&entry.GeoLocation),
+		})
+	}
+	sort.SliceStable(o.overrides, func(i, j int) bool {
+		return o.overrides[i].ones > o.overrides[j].ones
+	})
+	return o, nil
+}
+
+// modified reports whether the overrides file modified since loaded
+func (o *overrides) modified() bool {
+	info, err := os.Stat(o.filepath)
+	if err != nil {
+		return false
+	}
+	return !info.ModTime().Equal(o.modTime)
+}
+
+// lookup finds the most specific override which contains the ip
+func (o *overrides) lookup(ip net.IP) *geoip2.City {
+	if o == nil {
+		return nil
+	}
+	for i := range o.overrides {
+		if o.overrides[i].ipnet.Contains(ip) {
+			return o.overrides[i].city
+		}
+	}
+	return nil
+}
+
+// newCity converts custom location to geoip2.City
+func newCity(location *config.GeoLocation) *geoip2.City {
+	r := new(geoip2.City)
+	r.Country.IsoCode = location.Country.IsoCode
+	r.Country.Names = location.Country.Names
+	r.City.Names = location.City.Names
+	if location.Subdivision.IsoCode != "" || len(location.Subdivision.Names) > 0 {
+		r.Subdivisions = append(r.Subdivisions, struct {
+			GeoNameID uint              `maxminddb:"geoname_id"`
+			IsoCode   string            `maxminddb:"iso_code"`
+			Names     map[string]string `maxminddb:"names"`
+		}{
+			IsoCode: location.Subdivision.IsoCode,
+			Names:   location.Subdivision.Names,
+		})
+	}
+	r.Location.Latitude = location.Latitude
+	r.Location.Longitude = location.Longitude
+	return r
+}
+
+// isPrivateIP reports whether the ip is a loopback, private or link-local address
+func isPrivateIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast()
+}
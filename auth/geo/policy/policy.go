@@ -0,0 +1,71 @@
+// Package policy implements country/region allow and deny policy of geoip
+package policy
+
+import (
+	"strings"
+)
+
+// decisions
+const (
+	Allow = "allow"
+	Deny  = "deny"
+)
+
+// Config represents config of geo policy
+type Config struct {
+	// Default decision for countries not matched by Allow and Deny, default: allow
+	Default string `json:"default"`
+	// Unknown decision for ips which country can not be resolved, default: allow
+	Unknown string `json:"unknown"`
+	// Allow and Deny contain ISO 3166-1 country codes (e.g. "US") or
+	// ISO 3166-2 region codes (e.g. "US-CA"). Region rules take precedence
+	// over country rules and Deny takes precedence over Allow.
+	Allow []string `json:"allow"`
+	Deny  []string `json:"deny"`
+}
+
+// Enabled reports whether the config contains any deny rule
+func (cfg Config) Enabled() bool {
+	return len(cfg.Deny) > 0 || strings.EqualFold(cfg.Default, Deny) || strings.EqualFold(cfg.Unknown, Deny)
+}
+
+// Policy decides whether a country or region is allowed
+type Policy struct {
+	allowByDefault bool
+	allowUnknown   bool
+	rules          map[string]bool
+}
+
+// New creates a Policy by config
+func New(cfg Config) *Policy {
+	p := &Policy{
+		allowByDefault: !strings.EqualFold(cfg.Default, Deny),
+		allowUnknown:   !strings.EqualFold(cfg.Unknown, Deny),
+		rules:          make(map[string]bool, len(cfg.Allow)+len(cfg.Deny)),
+	}
+	for _, code := range cfg.Allow {
+		p.rules[strings.ToUpper(code)] = true
+	}
+	for _, code := range cfg.Deny {
+		p.rules[strings.ToUpper(code)] = false
+	}
+	return p
+}
+
+// Allowed reports whether the country (and optional region) is allowed.
+// An empty country means the location is unknown.
+func (p *Policy) Allowed(country, region string) bool {
+	if country == "" {
+		return p.allowUnknown
+	}
+	country = strings.ToUpper(country)
+	if region != "" {
+		if allowed, ok := p.rules[country+"-"+strings.ToUpper(region)]; ok {
+			return allowed
+		}
+	}
+	if allowed, ok := p.rules[country]; ok {
+		return allowed
+	}
+	return p.allowByDefault
+}
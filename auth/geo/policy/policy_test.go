@@ -0,0 +1,33 @@
+package policy_test
+
+import (
+	"testing"
+
+	"github.com/gopherd/gopherd/auth/geo/policy"
+)
+
+func TestAllowed(t *testing.T) {
+	for i, tc := range []struct {
+		cfg     policy.Config
+		country string
+		region  string
+		allowed bool
+	}{
+		{policy.Config{}, "US", "CA", true},
+		{policy.Config{}, "", "", true},
+		{policy.Config{Unknown: "deny"}, "", "", false},
+		{policy.Config{Deny: []string{"kp"}}, "KP", "", false},
+		{policy.Config{Deny: []string{"KP"}}, "US", "", true},
+		{policy.Config{Deny: []string{"US-CA"}}, "US", "CA", false},
+		{policy.Config{Deny: []string{"US-CA"}}, "US", "NY", true},
+		{policy.Config{Default: "deny", Allow: []string{"JP"}}, "JP", "13", true},
+		{policy.Config{Default: "deny", Allow: []string{"JP"}}, "CN", "", false},
+		{policy.Config{Deny: []string{"UA"}, Allow: []string{"UA-30"}}, "UA", "30", true},
+		{policy.Config{Deny: []string{"UA"}, Allow: []string{"UA-30"}}, "UA", "43", false},
+		{policy.Config{Allow: []string{"CN"}, Deny: []string{"CN"}}, "CN", "", false},
+	} {
+		if got := policy.New(tc.cfg).Allowed(tc.country, tc.region); got != tc.allowed {
+			t.Errorf("%dth: Allowed(%q, %q): want %v, got %v", i, tc.country, tc.region, tc.allowed, got)
+		}
+	}
+}
@@ -0,0 +1,307 @@
+//go:build ignore
+
+// This program generates small GeoLite2 compatible databases used by tests.
+//
+//	go run testdata/gen.go
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// data types of MaxMind DB format
+const (
+	typeString  = 2
+	typeDouble  = 3
+	typeUint16  = 5
+	typeUint32  = 6
+	typeMap     = 7
+	typeUint64  = 9
+	typeArray   = 11
+	typeBoolean = 14
+)
+
+const (
+	recordSize         = 24
+	dataSectionPadding = 16
+	metadataMarker     = "\xAB\xCD\xEFMaxMind.com"
+)
+
+type (
+	uint16Value uint16
+	uint32Value uint32
+	uint64Value uint64
+
+	// entry is a key/value pair of an ordered map
+	entry struct {
+		key   string
+		value any
+	}
+	orderedMap []entry
+)
+
+type encoder struct {
+	buf bytes.Buffer
+}
+
+func (e *encoder) control(typ byte, size int) {
+	var first byte
+	if typ <= 7 {
+		first = typ << 5
+	}
+	var ext []byte
+	switch {
+	case size < 29:
+		first |= byte(size)
+	case size < 29+256:
+		first |= 29
+		ext = []byte{byte(size - 29)}
+	case size < 285+65536:
+		first |= 30
+		ext = []byte{byte((size - 285) >> 8), byte(size - 285)}
+	default:
+		first |= 31
+		ext = []byte{byte((size - 65821) >> 16), byte((size - 65821) >> 8), byte(size - 65821)}
+	}
+	e.buf.WriteByte(first)
+	if typ > 7 {
+		e.buf.WriteByte(typ - 7)
+	}
+	e.buf.Write(ext)
+}
+
+func (e *encoder) uint(typ byte, x uint64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], x)
+	n := 0
+	for n < 8 && b[n] == 0 {
+		n++
+	}
+	e.control(typ, 8-n)
+	e.buf.Write(b[n:])
+}
+
+func (e *encoder) encode(v any) {
+	switch x := v.(type) {
+	case string:
+		e.control(typeString, len(x))
+		e.buf.WriteString(x)
+	case float64:
+		e.control(typeDouble, 8)
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], math.Float64bits(x))
+		e.buf.Write(b[:])
+	case uint16Value:
+		e.uint(typeUint16, uint64(x))
+	case uint32Value:
+		e.uint(typeUint32, uint64(x))
+	case uint64Value:
+		e.uint(typeUint64, uint64(x))
+	case bool:
+		if x {
+			e.control(typeBoolean, 1)
+		} else {
+			e.control(typeBoolean, 0)
+		}
+	case orderedMap:
+		e.control(typeMap, len(x))
+		for _, kv := range x {
+			e.encode(kv.key)
+			e.encode(kv.value)
+		}
+	case map[string]string:
+		// names are written in fixed language order
+		var m orderedMap
+		for _, lang := range languages {
+			if name, ok := x[lang]; ok {
+				m = append(m, entry{lang, name})
+			}
+		}
+		e.encode(m)
+	case []any:
+		e.control(typeArray, len(x))
+		for _, elem := range x {
+			e.encode(elem)
+		}
+	case []string:
+		e.control(typeArray, len(x))
+		for _, elem := range x {
+			e.encode(elem)
+		}
+	default:
+		panic(fmt.Sprintf("unsupported type %T", v))
+	}
+}
+
+// node of the binary search tree, a record is either a child node,
+// an offset of data or empty
+type node struct {
+	index    int
+	children [2]*node
+	data     [2]int
+}
+
+type network struct {
+	cidr  string
+	value any
+}
+
+func build(databaseType string, networks []network) []byte {
+	var (
+		root = &node{data: [2]int{-1, -1}}
+		data encoder
+	)
+	for _, n := range networks {
+		_, ipnet, err := net.ParseCIDR(n.cidr)
+		if err != nil {
+			panic(err)
+		}
+		ip := ipnet.IP.To4()
+		ones, _ := ipnet.Mask.Size()
+		offset := data.buf.Len()
+		data.encode(n.value)
+		curr := root
+		for i := 0; i < ones; i++ {
+			bit := (ip[i/8] >> (7 - i%8)) & 1
+			if i == ones-1 {
+				curr.data[bit] = offset
+				break
+			}
+			if curr.children[bit] == nil {
+				curr.children[bit] = &node{data: [2]int{-1, -1}}
+			}
+			curr = curr.children[bit]
+		}
+	}
+
+	// numbers nodes in breadth-first order
+	var nodes = []*node{root}
+	for i := 0; i < len(nodes); i++ {
+		nodes[i].index = i
+		for _, child := range nodes[i].children {
+			if child != nil {
+				nodes = append(nodes, child)
+			}
+		}
+	}
+	var (
+		out       bytes.Buffer
+		nodeCount = len(nodes)
+	)
+	for _, n := range nodes {
+		for bit := 0; bit < 2; bit++ {
+			var record int
+			switch {
+			case n.children[bit] != nil:
+				record = n.children[bit].index
+			case n.data[bit] >= 0:
+				record = nodeCount + dataSectionPadding + n.data[bit]
+			default:
+				record = nodeCount
+			}
+			out.Write([]byte{byte(record >> 16), byte(record >> 8), byte(record)})
+		}
+	}
+	out.Write(make([]byte, dataSectionPadding))
+	out.Write(data.buf.Bytes())
+	out.WriteString(metadataMarker)
+
+	var metadata encoder
+	metadata.encode(orderedMap{
+		{"binary_format_major_version", uint16Value(2)},
+		{"binary_format_minor_version", uint16Value(0)},
+		{"build_epoch", uint64Value(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC).Unix())},
+		{"database_type", databaseType},
+		{"description", map[string]string{"en": "gopherd test database"}},
+		{"ip_version", uint16Value(4)},
+		{"languages", languages},
+		{"node_count", uint32Value(nodeCount)},
+		{"record_size", uint16Value(recordSize)},
+	})
+	out.Write(metadata.buf.Bytes())
+	return out.Bytes()
+}
+
+var languages = []string{"en", "ja", "pt-BR", "zh-CN"}
+
+func place(geonameId uint32, isoCode string, names map[string]string) orderedMap {
+	m := orderedMap{{"geoname_id", uint32Value(geonameId)}}
+	if isoCode != "" {
+		m = append(m, entry{"iso_code", isoCode})
+	}
+	return append(m, entry{"names", names})
+}
+
+func city(country, subdivision, city orderedMap, latitude, longitude float64) orderedMap {
+	return orderedMap{
+		{"city", city},
+		{"country", country},
+		{"location", orderedMap{
+			{"accuracy_radius", uint16Value(50)},
+			{"latitude", latitude},
+			{"longitude", longitude},
+		}},
+		{"subdivisions", []any{subdivision}},
+	}
+}
+
+func asn(number uint32, organization string) orderedMap {
+	return orderedMap{
+		{"autonomous_system_number", uint32Value(number)},
+		{"autonomous_system_organization", organization},
+	}
+}
+
+func main() {
+	var (
+		japan  = place(1861060, "JP", map[string]string{"en": "Japan", "ja": "日本", "pt-BR": "Japão", "zh-CN": "日本"})
+		china  = place(1814991, "CN", map[string]string{"en": "China", "ja": "中国", "pt-BR": "China", "zh-CN": "中国"})
+		brazil = place(3469034, "BR", map[string]string{"en": "Brazil", "ja": "ブラジル連邦共和国", "pt-BR": "Brasil", "zh-CN": "巴西"})
+	)
+	files := map[string][]byte{
+		"GeoLite2-City-Test.mmdb": build("GeoLite2-City", []network{
+			{"54.199.0.0/16", city(
+				japan,
+				place(1850144, "13", map[string]string{"en": "Tokyo", "ja": "東京都", "zh-CN": "东京都"}),
+				place(1850147, "", map[string]string{"en": "Tokyo", "ja": "東京", "zh-CN": "东京"}),
+				35.6893, 139.6899,
+			)},
+			{"111.192.0.0/16", city(
+				china,
+				place(2038349, "BJ", map[string]string{"en": "Beijing", "zh-CN": "北京市"}),
+				place(1816670, "", map[string]string{"en": "Beijing", "zh-CN": "北京"}),
+				39.9288, 116.3889,
+			)},
+			{"218.88.0.0/16", city(
+				china,
+				place(1794299, "SC", map[string]string{"en": "Sichuan", "zh-CN": "四川省"}),
+				place(1815286, "", map[string]string{"en": "Chengdu", "zh-CN": "成都"}),
+				30.6667, 104.0667,
+			)},
+			{"200.160.0.0/16", city(
+				brazil,
+				place(3448433, "SP", map[string]string{"en": "São Paulo", "pt-BR": "São Paulo"}),
+				place(3448439, "", map[string]string{"en": "São Paulo", "pt-BR": "São Paulo"}),
+				-23.5475, -46.6361,
+			)},
+		}),
+		"GeoLite2-ASN-Test.mmdb": build("GeoLite2-ASN", []network{
+			{"54.199.0.0/16", asn(16509, "AMAZON-02")},
+			{"111.192.0.0/16", asn(4808, "China Unicom Beijing Province Network")},
+			{"200.160.0.0/16", asn(22548, "Núcleo de Inf. e Coord. do Ponto BR - NIC.BR")},
+		}),
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join("testdata", name), content, 0644); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+}
@@ -2,39 +2,54 @@ package handler
 
 import (
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gopherd/doge/crypto/cryptoutil"
 	"github.com/gopherd/doge/erron"
-	"github.com/gopherd/doge/net/httputil"
 	"github.com/gopherd/doge/net/netutil"
 	"github.com/gopherd/jwt"
 
 	"github.com/gopherd/gopherd/auth"
 	"github.com/gopherd/gopherd/auth/api"
+	"github.com/gopherd/gopherd/auth/metrics"
 	"github.com/gopherd/gopherd/auth/provider"
+	"github.com/gopherd/gopherd/auth/scope"
 )
 
 func Authorize(service auth.Service, w http.ResponseWriter, r *http.Request) {
 	const tag = "authorize"
-	w.Header().Set("Access-Control-Allow-Origin", "*")
 	lang := r.Header.Get("X-Lang")
 	req := new(api.AuthorizeRequest)
+	// provider label is set after the provider found, so unknown types
+	// requested by clients don't blow up cardinality of metrics
+	providerLabel := metrics.Unknown
+	defer func() {
+		metrics.Logins.WithLabelValues(providerLabel, metrics.Channel(req.Channel), metrics.Result(api.ResponseErrno(w))).Inc()
+	}()
 	err := req.Parse(r)
 	if err != nil {
 		service.Logger().Info().
 			String("api", tag).
 			Error("error", err).
 			Print("parse arguments error")
-		httputil.JSONResponse(w, erron.Errno(api.BadArgument, err))
+		api.Response(w, erron.Errno(api.BadArgument, err))
 		return
 	}
 	if req.Channel <= 0 {
-		httputil.JSONResponse(w, erron.Errnof(api.BadArgument, "invalid channel: %d", req.Channel))
+		api.Response(w, erron.Errnof(api.BadArgument, "invalid channel: %d", req.Channel))
 		return
 	}
 
 	ip := netutil.IP(r)
+	if !service.GeoModule().Allowed(ip) {
+		service.Logger().Info().
+			String("api", tag).
+			String("ip", ip).
+			Print("region blocked")
+		api.Response(w, erron.Errnof(api.RegionBlocked, "region blocked"))
+		return
+	}
 
 	service.Logger().Debug().
 		String("api", tag).
@@ -46,11 +61,25 @@ func Authorize(service auth.Service, w http.ResponseWriter, r *http.Request) {
 	resp := new(api.AuthorizeResponse)
 	resp.Channel = req.Channel
 	if req.Type == "" {
-		httputil.JSONResponse(w, resp)
+		api.Response(w, resp)
+		return
+	}
+	// resume the risky authorize which has been authorized by the provider
+	if req.State != "" {
+		account, isNew, ok := resumeAuthorize(service, tag, w, r, ip, req)
+		if !ok {
+			return
+		}
+		providerLabel = req.Type
+		if !checkSecondFactor(service, tag, w, ip, req, account, isNew) {
+			return
+		}
+		completeAuthorize(service, tag, w, ip, lang, req, account, isNew, nil)
 		return
 	}
 	var user *provider.UserInfo
 	if req.Type == provider.Device {
+		providerLabel = req.Type
 		req.Device = req.Account
 	} else {
 		// lookup provider
@@ -60,17 +89,18 @@ func Authorize(service auth.Service, w http.ResponseWriter, r *http.Request) {
 				String("api", tag).
 				String("provider", req.Type).
 				Print("provider not found")
-			httputil.JSONResponse(w, erron.AsErrno(err))
+			api.Response(w, erron.AsErrno(err))
 			return
 		}
+		providerLabel = req.Type
 		// authorize for provider
-		user, err := p.Authorize(req.Account, req.Secret)
+		user, err = authorizeProvider(p, req.Type, req.Account, req.Secret)
 		if err != nil {
 			service.Logger().Warn().
 				String("api", tag).
 				String("provider", req.Type).
 				Print("provider.authorize error")
-			httputil.JSONResponse(w, erron.AsErrno(err))
+			api.Response(w, erron.AsErrno(err))
 			return
 		}
 		if req.Device == "" {
@@ -79,7 +109,7 @@ func Authorize(service auth.Service, w http.ResponseWriter, r *http.Request) {
 					String("api", tag).
 					String("provider", req.Type).
 					Print("openId required")
-				httputil.JSONResponse(w, erron.Errnof(api.BadAuthorization, "openId not found"))
+				api.Response(w, erron.Errnof(api.BadAuthorization, "openId not found"))
 				return
 			}
 			req.Device = joinDeviceByOpenId(req.Type, user.OpenId)
@@ -92,8 +122,20 @@ func Authorize(service auth.Service, w http.ResponseWriter, r *http.Request) {
 	if user != nil && user.Key != "" {
 		key = user.Key
 	}
-	// load or create account
-	account, isNew, err := service.AccountModule().LoadOrCreate(req.Type, key, req.Device)
+	var (
+		account auth.Account
+		isNew   bool
+	)
+	if user != nil && user.Uid > 0 {
+		// provider stores links of accounts itself
+		account, err = service.AccountModule().Load(auth.ByID(user.Uid))
+		if err == nil && account == nil {
+			err = erron.Errnof(api.AccountNotFoundOrPasswordMismatched, "account not found")
+		}
+	} else {
+		// load or create account
+		account, isNew, err = service.AccountModule().LoadOrCreate(req.Type, key, req.Device)
+	}
 	if err != nil {
 		service.Logger().Error().
 			String("api", tag).
@@ -102,73 +144,190 @@ func Authorize(service auth.Service, w http.ResponseWriter, r *http.Request) {
 			String("device", req.Device).
 			Error("error", err).
 			Print("load or create account error")
-		httputil.JSONResponse(w, erron.AsErrno(err))
+		api.Response(w, erron.AsErrno(err))
 		return
 	}
-	if user != nil {
-		if user.Name != "" {
-			account.SetName(user.Name)
+	// evaluate login risk
+	var verificationRequired bool
+	if risk, err := service.RiskModule().Evaluate(account, ip); err != nil {
+		service.Logger().Warn().
+			String("api", tag).
+			Int64("uid", account.GetID()).
+			String("ip", ip).
+			Error("error", err).
+			Print("evaluate login risk error")
+	} else if risk.Decision != auth.RiskAllow {
+		service.Logger().Warn().
+			String("api", tag).
+			Int64("uid", account.GetID()).
+			String("ip", ip).
+			String("rules", strings.Join(risk.Rules, ",")).
+			Int("decision", int(risk.Decision)).
+			Print("suspicious login")
+		if risk.Decision == auth.RiskDeny {
+			api.Response(w, erron.Errnof(api.SuspiciousLogin, "suspicious login"))
+			return
 		}
-		if user.Avatar != "" {
-			account.SetAvatar(user.Avatar)
+		verificationRequired = true
+	}
+	// second factor, which also satisfies verification of suspicious login
+	if !checkSecondFactor(service, tag, w, ip, req, account, isNew) {
+		return
+	}
+	// challenge, if required, satisfies verification of suspicious login. The
+	// state is responded with the challenge, so the authorize can be resumed
+	// without secret which may have been consumed by the provider.
+	if verificationRequired {
+		if !service.ChallengeModule().Required(tag) {
+			api.Response(w, erron.Errnof(api.VerificationRequired, "verification required"))
+			return
 		}
-		if user.Location != "" {
-			account.SetLocation(user.Location)
+		var state string
+		if req.Solution == "" {
+			if state, err = signAuthorizeState(service, ip, req, account, isNew); err != nil {
+				service.Logger().Error().
+					String("api", tag).
+					Error("error", err).
+					Print("sign authorize state error")
+				api.Response(w, erron.AsErrno(err))
+				return
+			}
 		}
-	} else {
+		if !verifyChallenge(service, tag, w, r, ip, req.Challenge, req.Solution, state) {
+			return
+		}
+	}
+	completeAuthorize(service, tag, w, ip, lang, req, account, isNew, user)
+}
+
+// checkSecondFactor responds a challenge token for verify2fa if two-factor
+// authentication enabled for the existing account. It returns false if the
+// request has been responded and shouldn't proceed.
+func checkSecondFactor(service auth.Service, tag string, w http.ResponseWriter, ip string, req *api.AuthorizeRequest, account auth.Account, isNew bool) bool {
+	if isNew {
+		return true
+	}
+	enabled, err := service.TwoFactorModule().Enabled(account.GetID())
+	if err != nil {
+		service.Logger().Error().
+			String("api", tag).
+			Int64("uid", account.GetID()).
+			Error("error", err).
+			Print("check two-factor authentication error")
+		api.Response(w, erron.AsErrno(err))
+		return false
+	}
+	if enabled {
+		challengeSecondFactor(service, tag, w, ip, req, account)
+		return false
+	}
+	return true
+}
+
+// completeAuthorize applies profiles to the authorized account and logins
+func completeAuthorize(service auth.Service, tag string, w http.ResponseWriter, ip, lang string, req *api.AuthorizeRequest, account auth.Account, isNew bool, user *provider.UserInfo) {
+	if user != nil {
+		applyUserInfo(service, account, user)
+	}
+	applyRequestProfile(service, account, req.Name, req.Avatar, req.Gender)
+	if account.GetLocation() == "" {
 		if country, province, city, err := service.GeoModule().QueryLocation(ip, lang); err == nil {
 			if location := provider.Location(country, province, city); location != "" {
 				account.SetLocation(location)
 			}
 		}
 	}
+	login(service, tag, w, ip, req, account, isNew)
+}
+
+// login completes authorization of the account and responds access_token and
+// refresh_token
+func login(service auth.Service, tag string, w http.ResponseWriter, ip string, req *api.AuthorizeRequest, account auth.Account, isNew bool) {
+	grants, err := service.RoleModule().Grants(account.GetID())
+	if err != nil {
+		service.Logger().Error().
+			String("api", tag).
+			Int64("uid", account.GetID()).
+			Error("error", err).
+			Print("load roles error")
+		api.Response(w, erron.Errnof(api.InternalServerError, "internal server error"))
+		return
+	}
+	granted, err := grantScope(service, account, grants, req.Scope)
+	if err != nil {
+		service.Logger().Info().
+			String("api", tag).
+			Int64("uid", account.GetID()).
+			String("scope", req.Scope).
+			Error("error", err).
+			Print("grant scope error")
+		api.Response(w, erron.AsErrno(err))
+		return
+	}
+
 	// authorized success
-	claims, err := authorized(service, ip, req, account, isNew)
+	claims, err := authorized(service, ip, req, account, isNew, granted, grants)
 	if err != nil {
-		httputil.JSONResponse(w, erron.Errnof(api.InternalServerError, "internal server error"))
+		if erron.GetErrno(err) == erron.EUnknown {
+			err = erron.Errnof(api.InternalServerError, "internal server error")
+		}
+		api.Response(w, erron.AsErrno(err))
 		return
 	}
 
-	// sign access_token and refresh_token
-	options := service.Config()
-	claims.Issuer = options.JWT.Issuer
-	claims.IssuedAt = time.Now().Unix()
-	claims.ExpiresAt = claims.IssuedAt + options.AccessTokenTTL
-	resp.AccessToken, err = service.Signer().Sign(claims)
+	session, err := service.SessionModule().Open(account.GetID(), req.Device, req.Os, req.Model, ip)
 	if err != nil {
 		service.Logger().Error().
 			String("api", tag).
+			Int64("uid", account.GetID()).
 			Error("error", err).
-			Print("signed access token error")
-		httputil.JSONResponse(w, erron.AsErrno(err))
+			Print("open session error")
+		api.Response(w, erron.Errnof(api.InternalServerError, "internal server error"))
 		return
 	}
-	resp.AccessTokenExpiredAt = claims.ExpiresAt
-	claims.ExpiresAt = claims.IssuedAt + options.RefreshTokenTTL
-	claims.Payload = jwt.Payload{
-		Salt:  cryptoutil.GenerateSalt(16),
-		Scope: "*",
-		ID:    claims.Payload.ID,
-		IP:    ip,
-	}
-	resp.RefreshToken, err = service.Signer().Sign(claims)
+
+	// sign access_token and refresh_token
+	t, err := signTokens(service, claims, session)
 	if err != nil {
 		service.Logger().Error().
 			String("api", tag).
 			Error("error", err).
-			Print("signed refresh token error")
-		httputil.JSONResponse(w, erron.AsErrno(err))
+			Print("sign tokens error")
+		api.Response(w, erron.AsErrno(err))
 		return
 	}
-	resp.RefreshTokenExpiredAt = claims.ExpiresAt
-	httputil.JSONResponse(w, resp)
+	resp := new(api.AuthorizeResponse)
+	resp.Channel = req.Channel
+	resp.AccessToken = t.accessToken
+	resp.AccessTokenExpiredAt = t.accessTokenExpiredAt
+	resp.RefreshToken = t.refreshToken
+	resp.RefreshTokenExpiredAt = t.refreshTokenExpiredAt
+	resp.Scope = claims.Payload.Scope
+	api.Response(w, resp)
 }
 
 func joinDeviceByOpenId(provider, openId string) string {
 	return provider + ":" + openId + "@" + cryptoutil.MD5(openId)
 }
 
-func authorized(service auth.Service, ip string, req *api.AuthorizeRequest, account auth.Account, isNew bool) (*jwt.Claims, error) {
+// accessClaims creates claims of access token of the account
+func accessClaims(account auth.Account, ip string, granted scope.Scope, grants *auth.Grants) *jwt.Claims {
+	var claims = new(jwt.Claims)
+	claims.Payload.Salt = cryptoutil.GenerateSalt(16)
+	claims.Payload.Scope = granted.String()
+	claims.Payload.ID = account.GetID()
+	claims.Payload.IP = ip
+	claims.Payload.Values = map[string]any{
+		api.ClaimProviders: account.GetProviders(),
+	}
+	if len(grants.Roles) > 0 {
+		claims.Payload.Values[api.ClaimRoles] = strings.Join(grants.Roles, " ")
+		claims.Payload.Values[api.ClaimPermissions] = strings.Join(grants.Permissions, " ")
+	}
+	return claims
+}
+
+func authorized(service auth.Service, ip string, req *api.AuthorizeRequest, account auth.Account, isNew bool, granted scope.Scope, grants *auth.Grants) (*jwt.Claims, error) {
 	if banned, reason := account.GetBanned(); banned {
 		service.Logger().Info().
 			Int64("uid", account.GetID()).
@@ -177,14 +336,7 @@ func authorized(service auth.Service, ip string, req *api.AuthorizeRequest, acco
 		return nil, erron.Errnof(api.Banned, "banned")
 	}
 
-	var claims = new(jwt.Claims)
-	claims.Payload.Salt = cryptoutil.GenerateSalt(16)
-	claims.Payload.Scope = "*"
-	claims.Payload.ID = account.GetID()
-	claims.Payload.IP = ip
-	claims.Payload.Values = map[string]any{
-		"providers": account.GetProviders(),
-	}
+	claims := accessClaims(account, ip, granted, grants)
 
 	service.Logger().Info().
 		Int64("uid", account.GetID()).
@@ -194,7 +346,24 @@ func authorized(service auth.Service, ip string, req *api.AuthorizeRequest, acco
 	now := time.Now()
 	if isNew {
 		account.SetRegister(now, ip)
+		account.SetAttribution(auth.Attribution{
+			Channel: req.Channel,
+			Source:  req.Source,
+			OS:      req.Os,
+			Model:   req.Model,
+		})
 	}
 	account.SetLastLogin(now, ip)
-	return claims, service.AccountModule().Store(req.Type, account)
+	if err := service.AccountModule().Store(req.Type, account); err != nil {
+		return nil, err
+	}
+	service.AvatarModule().Mirror(account.GetID(), account.GetAvatar())
+	if err := service.RiskModule().Record(account, ip); err != nil {
+		service.Logger().Warn().
+			Int64("uid", account.GetID()).
+			String("ip", ip).
+			Error("error", err).
+			Print("record login history error")
+	}
+	return claims, nil
 }
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gopherd/gopherd/auth"
+	"github.com/gopherd/gopherd/auth/account"
+	"github.com/gopherd/gopherd/auth/api"
+)
+
+// testTwoFactor enables two-factor authentication for account 1
+type testTwoFactor struct {
+	auth.TwoFactorModule
+}
+
+func (testTwoFactor) Enabled(uid int64) (bool, error) {
+	return uid == 1, nil
+}
+
+func TestCheckSecondFactor(t *testing.T) {
+	service := newTestService()
+	service.twoFactor = testTwoFactor{}
+	for _, tc := range []struct {
+		uid     int64
+		isNew   bool
+		proceed bool
+	}{
+		{1, false, false},
+		{1, true, true},
+		{2, false, true},
+	} {
+		r := httptest.NewRequest(http.MethodPost, api.RouteAuthorize, nil)
+		w := api.NegotiateResponseWriter(httptest.NewRecorder(), r)
+		a := &account.Account{ID: tc.uid}
+		proceed := checkSecondFactor(service, "authorize", w, "127.0.0.1", new(api.AuthorizeRequest), a, tc.isNew)
+		if proceed != tc.proceed {
+			t.Fatalf("uid %d, new %v: want proceed %v, got %v", tc.uid, tc.isNew, tc.proceed, proceed)
+		}
+		if !proceed && api.ResponseErrno(w) != api.SecondFactorRequired {
+			t.Fatalf("uid %d: want errno %d, got %d", tc.uid, api.SecondFactorRequired, api.ResponseErrno(w))
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gopherd/doge/crypto/cryptoutil"
+	"github.com/gopherd/doge/erron"
+	"github.com/gopherd/jwt"
+
+	"github.com/gopherd/gopherd/auth"
+	"github.com/gopherd/gopherd/auth/api"
+)
+
+// verifyChallenge verifies the solution of the challenge, ChallengeRequiredResponse
+// with a new challenge and the state is responded if no solution. It returns
+// false if the request has been responded and shouldn't proceed.
+func verifyChallenge(service auth.Service, tag string, w http.ResponseWriter, r *http.Request, ip, challenge, solution, state string) bool {
+	if solution == "" {
+		c, err := service.ChallengeModule().Issue(ip)
+		if err != nil {
+			service.Logger().Error().
+				String("api", tag).
+				Error("error", err).
+				Print("issue challenge error")
+			api.Response(w, erron.AsErrno(err))
+			return false
+		}
+		api.Response(w, &api.ChallengeRequiredResponse{
+			Error:              api.ChallengeRequired,
+			Description:        "challenge required",
+			Type:               c.Type,
+			Challenge:          c.Challenge,
+			Difficulty:         c.Difficulty,
+			SiteKey:            c.SiteKey,
+			ChallengeExpiredAt: c.ExpiredAt,
+			Message:            api.Localize(w, api.ChallengeRequired),
+			State:              state,
+		})
+		return false
+	}
+	if err := service.ChallengeModule().Verify(r.Context(), ip, challenge, solution); err != nil {
+		service.Logger().Info().
+			String("api", tag).
+			String("ip", ip).
+			Error("error", err).
+			Print("verify challenge error")
+		api.Response(w, erron.AsErrno(err))
+		return false
+	}
+	return true
+}
+
+// stateIssuerSuffix is appended to issuer of authorize state tokens, so state
+// tokens can't be used as access tokens or 2fa challenge tokens
+const stateIssuerSuffix = "#state"
+
+func stateIssuer(service auth.Service) string {
+	return service.Config().JWT.Issuer + stateIssuerSuffix
+}
+
+// signAuthorizeState signs the state of the risky authorize which has been
+// authorized by the provider, the state carries the authorize request like
+// challenge tokens of second factor
+func signAuthorizeState(service auth.Service, ip string, req *api.AuthorizeRequest, account auth.Account, isNew bool) (string, error) {
+	claims := new(jwt.Claims)
+	claims.Issuer = stateIssuer(service)
+	claims.IssuedAt = time.Now().Unix()
+	claims.ExpiresAt = claims.IssuedAt + service.Config().Challenge.TTL
+	claims.Payload = jwt.Payload{
+		Salt: cryptoutil.GenerateSalt(16),
+		ID:   account.GetID(),
+		IP:   ip,
+		Values: map[string]any{
+			"type":    req.Type,
+			"device":  req.Device,
+			"channel": strconv.Itoa(req.Channel),
+			"os":      req.Os,
+			"model":   req.Model,
+			"source":  req.Source,
+			"scope":   req.Scope,
+			"new":     strconv.FormatBool(isNew),
+		},
+	}
+	return service.Signer().Sign(claims)
+}
+
+// resumeAuthorize verifies the state and the solution of the challenge, and
+// loads the account of the state. Fields of req are replaced by the state.
+func resumeAuthorize(service auth.Service, tag string, w http.ResponseWriter, r *http.Request, ip string, req *api.AuthorizeRequest) (account auth.Account, isNew bool, ok bool) {
+	claims, err := service.Signer().Verify(stateIssuer(service), req.State)
+	if err != nil || claims.Payload.IP != ip {
+		service.Logger().Warn().
+			String("api", tag).
+			String("ip", ip).
+			Error("error", err).
+			Print("invalid authorize state")
+		api.Response(w, erron.Errnof(api.Unauthorized, "invalid state"))
+		return
+	}
+	if !verifyChallenge(service, tag, w, r, ip, req.Challenge, req.Solution, req.State) {
+		return
+	}
+	account, err = service.AccountModule().Load(auth.ByID(claims.Payload.ID))
+	if err != nil {
+		service.Logger().Warn().
+			String("api", tag).
+			Int64("uid", claims.Payload.ID).
+			Error("error", err).
+			Print("get account error")
+		api.Response(w, erron.AsErrno(err))
+		return
+	}
+	if account == nil {
+		api.Response(w, erron.Errnof(api.Unauthorized, "account not found"))
+		return
+	}
+	values := claims.Payload.Values
+	req.Type = stringValue(values, "type")
+	req.Device = stringValue(values, "device")
+	req.Channel, _ = strconv.Atoi(stringValue(values, "channel"))
+	req.Os = stringValue(values, "os")
+	req.Model = stringValue(values, "model")
+	req.Source = stringValue(values, "source")
+	req.Scope = stringValue(values, "scope")
+	isNew, _ = strconv.ParseBool(stringValue(values, "new"))
+	return account, isNew, true
+}
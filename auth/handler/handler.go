@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gopherd/doge/erron"
+	"github.com/gopherd/jwt"
+
+	"github.com/gopherd/gopherd/auth"
+	"github.com/gopherd/gopherd/auth/api"
+	"github.com/gopherd/gopherd/auth/scope"
+)
+
+// isAdmin reports whether the request carries the configured admin key
+func isAdmin(service auth.Service, r *http.Request) bool {
+	key := service.Config().Admin.Key
+	if key == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Admin-Key")), []byte(key)) == 1
+}
+
+// bearerToken returns token if it's not empty, otherwise returns the bearer token
+// of Authorization header
+func bearerToken(r *http.Request, token string) (string, bool) {
+	if token != "" {
+		return token, true
+	}
+	const prefix = "Bearer "
+	credentials := r.Header.Get("Authorization")
+	if !strings.HasPrefix(credentials, prefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(credentials, prefix), true
+}
+
+// Scopes required by apis of accounts, the access token must have any of them
+const (
+	// readScopes are required by apis which read the account
+	readScopes = scope.Game | scope.Chat | scope.ReadOnly
+	// writeScopes are required by apis which modify the account, so read-only
+	// tokens are rejected
+	writeScopes = scope.Game | scope.Chat
+)
+
+// loadAccountByToken loads account by the access token, an error response would
+// be written and nil returned if failed
+func loadAccountByToken(service auth.Service, tag string, w http.ResponseWriter, r *http.Request, token string, required scope.Scope) auth.Account {
+	_, account := authenticate(service, tag, w, r, token, required)
+	return account
+}
+
+// authenticate verifies the access token which must have any of required
+// scopes and loads the account, an error response would be written and nil
+// returned if failed
+func authenticate(service auth.Service, tag string, w http.ResponseWriter, r *http.Request, token string, required scope.Scope) (*jwt.Claims, auth.Account) {
+	accessToken, ok := bearerToken(r, token)
+	if !ok {
+		service.Logger().Warn().
+			String("api", tag).
+			String("credentials", r.Header.Get("Authorization")).
+			Print("unsupported Authorization header")
+		api.Response(w, erron.Errnof(api.Unauthorized, "unauthorized"))
+		return nil, nil
+	}
+	claims, err := service.Signer().Verify(service.Config().JWT.Issuer, accessToken)
+	if err != nil {
+		service.Logger().Warn().
+			String("api", tag).
+			Error("error", err).
+			Print("invalid access token")
+		api.Response(w, erron.Errno(api.Unauthorized, err))
+		return nil, nil
+	}
+	// tokens of service clients have no account
+	if _, ok := claims.Payload.Values[api.ClaimClient]; ok || claims.Payload.ID <= 0 {
+		service.Logger().Warn().
+			String("api", tag).
+			Int64("uid", claims.Payload.ID).
+			Print("token without account rejected")
+		api.Response(w, erron.Errnof(api.Unauthorized, "unauthorized"))
+		return nil, nil
+	}
+	// impersonation tokens are accepted by game services only
+	if isImpersonation(claims) {
+		impersonator := impersonatorOf(claims)
+		service.Logger().Warn().
+			String("api", tag).
+			Int64("uid", claims.Payload.ID).
+			String("impersonator", impersonator).
+			Print("impersonation token rejected")
+		api.Response(w, erron.Errnof(api.Unauthorized, "impersonation token not allowed"))
+		return nil, nil
+	}
+	if granted, _ := scope.Parse(claims.Payload.Scope); !granted.Any(required) {
+		service.Logger().Info().
+			String("api", tag).
+			Int64("uid", claims.Payload.ID).
+			String("scope", claims.Payload.Scope).
+			Print("insufficient scope")
+		api.Response(w, erron.Errnof(api.ScopeDenied, "insufficient scope"))
+		return nil, nil
+	}
+	// access tokens of revoked sessions are rejected
+	if session := sessionOf(claims); session != "" {
+		if active, err := service.SessionModule().Active(claims.Payload.ID, session); err != nil {
+			service.Logger().Warn().
+				String("api", tag).
+				Int64("uid", claims.Payload.ID).
+				Error("error", err).
+				Print("check session error")
+			api.Response(w, erron.AsErrno(err))
+			return nil, nil
+		} else if !active {
+			service.Logger().Info().
+				String("api", tag).
+				Int64("uid", claims.Payload.ID).
+				String("session", session).
+				Print("session revoked")
+			api.Response(w, erron.Errnof(api.Unauthorized, "session revoked"))
+			return nil, nil
+		}
+	}
+	account, err := service.AccountModule().Load(auth.ByID(claims.Payload.ID))
+	if err != nil {
+		service.Logger().Warn().
+			String("api", tag).
+			Int64("uid", claims.Payload.ID).
+			Error("error", err).
+			Print("get account error")
+		api.Response(w, erron.AsErrno(err))
+		return nil, nil
+	}
+	if account == nil {
+		service.Logger().Info().
+			String("api", tag).
+			Int64("uid", claims.Payload.ID).
+			Print("account not found by access token")
+		api.Response(w, erron.Errnof(api.Unauthorized, "account not found"))
+		return nil, nil
+	}
+	return claims, account
+}
+
+// sessionOf returns id of login session of the access token
+func sessionOf(claims *jwt.Claims) string {
+	session, _ := claims.Payload.Values[api.ClaimSession].(string)
+	return session
+}
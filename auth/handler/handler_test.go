@@ -0,0 +1,208 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gopherd/jwt"
+	"github.com/gopherd/log"
+
+	"github.com/gopherd/gopherd/auth"
+	"github.com/gopherd/gopherd/auth/account"
+	"github.com/gopherd/gopherd/auth/api"
+	"github.com/gopherd/gopherd/auth/config"
+	"github.com/gopherd/gopherd/auth/scope"
+)
+
+const testAdminKey = "admin-key"
+
+// testService implements auth.Service for tests of handlers, methods which
+// aren't overridden panic so requests must be rejected before using them
+type testService struct {
+	auth.Service
+	config    *config.Config
+	twoFactor auth.TwoFactorModule
+	audit     auth.AuditModule
+	signed    *jwt.Claims // claims signed last
+}
+
+func newTestService() *testService {
+	cfg := new(config.Config).Default().(*config.Config)
+	cfg.Admin.Key = testAdminKey
+	return &testService{config: cfg}
+}
+
+func (s *testService) Config() *config.Config            { return s.config }
+func (s *testService) Logger() *log.Logger               { return log.DefaultLogger }
+func (s *testService) Signer() auth.Signer               { return testSigner{signed: &s.signed} }
+func (s *testService) AuditModule() auth.AuditModule     { return s.audit }
+func (s *testService) AccountModule() auth.AccountModule { return testAccounts{} }
+func (s *testService) TwoFactorModule() auth.TwoFactorModule {
+	return s.twoFactor
+}
+
+// testSigner verifies tokens as scopes of access tokens of account 1, tokens
+// prefixed by "client:" are verified as tokens of service clients
+type testSigner struct {
+	auth.Signer
+	signed **jwt.Claims
+}
+
+func (testSigner) Verify(issuer, token string) (*jwt.Claims, error) {
+	claims := new(jwt.Claims)
+	claims.Issuer = issuer
+	claims.Payload.ID = 1
+	claims.Payload.Scope = token
+	if strings.HasPrefix(token, "client:") {
+		claims.Payload.ID = 0
+		claims.Payload.Scope = strings.TrimPrefix(token, "client:")
+		claims.Payload.Values = map[string]any{api.ClaimClient: "matchd"}
+	}
+	return claims, nil
+}
+
+func (s testSigner) Sign(claims *jwt.Claims) (string, error) {
+	*s.signed = claims
+	return claims.Issuer, nil
+}
+
+// testAccounts loads accounts by id
+type testAccounts struct {
+	auth.AccountModule
+}
+
+func (testAccounts) Load(by ...auth.Field) (auth.Account, error) {
+	a := new(account.Account)
+	id, err := strconv.ParseInt(by[0].Value, 10, 64)
+	a.ID = id
+	return a, err
+}
+
+// serve calls the handler with a form posted by admin and returns errno of
+// the response
+func serve(service auth.Service, h func(auth.Service, http.ResponseWriter, *http.Request), form url.Values) int {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("X-Admin-Key", testAdminKey)
+	w := api.NegotiateResponseWriter(httptest.NewRecorder(), r)
+	h(service, w, r)
+	return api.ResponseErrno(w)
+}
+
+func TestImpersonateArguments(t *testing.T) {
+	service := newTestService()
+	for _, tc := range []struct {
+		operator, reason string
+	}{
+		{"", "ticket 1"},
+		{" \t", "ticket 1"},
+		{"alice", ""},
+		{"alice", "  "},
+	} {
+		form := url.Values{
+			"uid":      {"1"},
+			"operator": {tc.operator},
+			"reason":   {tc.reason},
+		}
+		if got := serve(service, Impersonate, form); got != api.BadArgument {
+			t.Fatalf("operator %q reason %q: want errno %d, got %d", tc.operator, tc.reason, api.BadArgument, got)
+		}
+	}
+}
+
+// testAudit records audit entries in memory
+type testAudit struct {
+	entries []*auth.AuditEntry
+}
+
+func (a *testAudit) Record(entry *auth.AuditEntry) error {
+	a.entries = append(a.entries, entry)
+	return nil
+}
+
+func TestImpersonateClaims(t *testing.T) {
+	service := newTestService()
+	audit := new(testAudit)
+	service.audit = audit
+	form := url.Values{
+		"uid":      {"1"},
+		"operator": {"alice"},
+		"reason":   {"ticket 1"},
+	}
+	if got := serve(service, Impersonate, form); got != 0 {
+		t.Fatalf("want errno 0, got %d", got)
+	}
+	claims := service.signed
+	if claims == nil || len(audit.entries) != 1 {
+		t.Fatal("impersonation token not signed or audited")
+	}
+	// ip of the operator mustn't override ip of the account in gated
+	if claims.Payload.IP != "" || claims.Payload.Values[api.ClaimOperatorIP] != audit.entries[0].IP {
+		t.Fatalf("unexpected ip %q, operator ip %v", claims.Payload.IP, claims.Payload.Values[api.ClaimOperatorIP])
+	}
+	if impersonatorOf(claims) != "alice" {
+		t.Fatalf("unexpected impersonator %q", impersonatorOf(claims))
+	}
+}
+
+func TestMetricsToken(t *testing.T) {
+	service := newTestService()
+	service.config.Metrics.Token = "metrics-token"
+	for _, tc := range []struct {
+		key   string
+		errno int
+	}{
+		{"", api.Unauthorized},
+		{"mismatched", api.Unauthorized},
+		{testAdminKey, api.Unauthorized},
+		{"metrics-token", 0},
+	} {
+		r := httptest.NewRequest(http.MethodGet, api.RouteMetrics, nil)
+		r.Header.Set("Authorization", "Bearer "+tc.key)
+		r.Header.Set("X-Admin-Key", testAdminKey)
+		rec := httptest.NewRecorder()
+		w := api.NegotiateResponseWriter(rec, r)
+		Metrics(service, w, r)
+		if got := api.ResponseErrno(w); got != tc.errno {
+			t.Fatalf("key %q: want errno %d, got %d", tc.key, tc.errno, got)
+		}
+		if tc.errno == 0 && !strings.Contains(rec.Body.String(), "go_goroutines") {
+			t.Fatalf("key %q: metrics not exposed", tc.key)
+		}
+	}
+}
+
+func TestAuthenticateScopes(t *testing.T) {
+	service := newTestService()
+	for _, tc := range []struct {
+		scope    string
+		required scope.Scope
+		errno    int
+	}{
+		{"game chat", writeScopes, 0},
+		{"chat", writeScopes, 0},
+		{"*", writeScopes, 0},
+		{"readonly", writeScopes, api.ScopeDenied},
+		{"admin", writeScopes, api.ScopeDenied},
+		{"openid", writeScopes, api.ScopeDenied},
+		{"readonly", readScopes, 0},
+		{"game", readScopes, 0},
+		{"service", readScopes, api.ScopeDenied},
+		{"client:game", readScopes, api.Unauthorized},
+		{"client:service", readScopes, api.Unauthorized},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		w := api.NegotiateResponseWriter(httptest.NewRecorder(), r)
+		_, a := authenticate(service, "test", w, r, tc.scope, tc.required)
+		if got := api.ResponseErrno(w); got != tc.errno {
+			t.Fatalf("scope %q required %q: want errno %d, got %d", tc.scope, tc.required, tc.errno, got)
+		}
+		if (a != nil) != (tc.errno == 0) {
+			t.Fatalf("scope %q required %q: unexpected account %v", tc.scope, tc.required, a)
+		}
+	}
+}
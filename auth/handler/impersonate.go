@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gopherd/doge/crypto/cryptoutil"
+	"github.com/gopherd/doge/erron"
+	"github.com/gopherd/doge/net/netutil"
+	"github.com/gopherd/jwt"
+
+	"github.com/gopherd/gopherd/auth"
+	"github.com/gopherd/gopherd/auth/api"
+	"github.com/gopherd/gopherd/auth/scope"
+)
+
+// impersonatorOf returns operator who impersonates the account of the token,
+// empty string returned if it's not an impersonation token
+func impersonatorOf(claims *jwt.Claims) string {
+	impersonator, _ := claims.Payload.Values[api.ClaimImpersonator].(string)
+	return impersonator
+}
+
+// isImpersonation reports whether the token is an impersonation token, the
+// claim is checked by presence so tokens with empty impersonator are rejected too
+func isImpersonation(claims *jwt.Claims) bool {
+	_, ok := claims.Payload.Values[api.ClaimImpersonator]
+	return ok
+}
+
+// Impersonate mints a short-lived impersonation token of an account for
+// customer service. Impersonation tokens have no sessions and can't be
+// refreshed, and they are rejected by account apis of authd. The operator is
+// declared by the caller of the shared admin key, so it's logged and audited
+// as an unverified operator.
+func Impersonate(service auth.Service, w http.ResponseWriter, r *http.Request) {
+	const tag = "impersonate"
+	if !adminOnly(service, tag, w, r) {
+		return
+	}
+	if r.Method != http.MethodPost {
+		api.Response(w, erron.Errnof(api.BadArgument, "method %s not allowed", r.Method))
+		return
+	}
+	req := new(api.ImpersonateRequest)
+	err := req.Parse(r)
+	if err != nil {
+		service.Logger().Info().
+			String("api", tag).
+			Error("error", err).
+			Print("parse arguments error")
+		api.Response(w, erron.Errno(api.BadArgument, err))
+		return
+	}
+	// required arguments may be blank, the operator must be audited
+	req.Operator = strings.TrimSpace(req.Operator)
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Operator == "" || req.Reason == "" {
+		api.Response(w, erron.Errnof(api.BadArgument, "operator and reason required"))
+		return
+	}
+	cfg := service.Config().Impersonation
+	ttl := cfg.TTL
+	if req.Ttl > 0 && req.Ttl < ttl {
+		ttl = req.Ttl
+	}
+	// service scope is granted to service clients only
+	granted, _ := scope.Parse(cfg.Scope)
+	granted &^= scope.Service
+	if req.Scope != "" {
+		want, unknown := scope.Parse(req.Scope)
+		if len(unknown) > 0 {
+			api.Response(w, erron.Errnof(api.BadArgument, "unknown scopes: %s", strings.Join(unknown, " ")))
+			return
+		}
+		granted &= want
+	}
+	if granted == scope.None {
+		api.Response(w, erron.Errnof(api.ScopeDenied, "scope not granted"))
+		return
+	}
+
+	account, err := service.AccountModule().Load(auth.ByID(req.Uid))
+	if err != nil {
+		service.Logger().Warn().
+			String("api", tag).
+			Int64("uid", req.Uid).
+			Error("error", err).
+			Print("get account error")
+		api.Response(w, erron.AsErrno(err))
+		return
+	}
+	if account == nil {
+		api.Response(w, erron.Errnof(api.BadArgument, "account %d not found", req.Uid))
+		return
+	}
+
+	ip := netutil.IP(r)
+	claims := new(jwt.Claims)
+	claims.Issuer = service.Config().JWT.Issuer
+	claims.Id = cryptoutil.GenerateSalt(16)
+	claims.IssuedAt = time.Now().Unix()
+	claims.ExpiresAt = claims.IssuedAt + ttl
+	claims.Payload = jwt.Payload{
+		Salt:  cryptoutil.GenerateSalt(16),
+		Scope: granted.String(),
+		ID:    account.GetID(),
+		// ip of the token is the ip of the account's login, so the operator's
+		// ip is carried by another claim
+		Values: map[string]any{
+			api.ClaimProviders:    account.GetProviders(),
+			api.ClaimImpersonator: req.Operator,
+			api.ClaimOperatorIP:   ip,
+		},
+	}
+
+	// the token is not issued if the issuance can't be audited
+	if err := service.AuditModule().Record(&auth.AuditEntry{
+		Action:   auth.AuditImpersonate,
+		Operator: req.Operator,
+		Uid:      account.GetID(),
+		IP:       ip,
+		Detail:   "jti=" + claims.Id + " scope=" + claims.Payload.Scope + " ttl=" + strconv.FormatInt(ttl, 10) + " reason=" + req.Reason + " operator_verified=false",
+	}); err != nil {
+		service.Logger().Error().
+			String("api", tag).
+			Int64("uid", account.GetID()).
+			String("operator", req.Operator).
+			Error("error", err).
+			Print("record audit error")
+		api.Response(w, erron.Errnof(api.InternalServerError, "internal server error"))
+		return
+	}
+	token, err := service.Signer().Sign(claims)
+	if err != nil {
+		service.Logger().Error().
+			String("api", tag).
+			Int64("uid", account.GetID()).
+			Error("error", err).
+			Print("sign impersonation token error")
+		api.Response(w, erron.AsErrno(err))
+		return
+	}
+	service.Logger().Warn().
+		String("api", tag).
+		Int64("uid", account.GetID()).
+		String("unverified_operator", req.Operator).
+		String("operator_ip", ip).
+		String("scope", claims.Payload.Scope).
+		Int64("ttl", ttl).
+		Print("impersonation token issued")
+	api.Response(w, &api.ImpersonateResponse{
+		AccessToken:          token,
+		AccessTokenExpiredAt: claims.ExpiresAt,
+		Scope:                claims.Payload.Scope,
+	})
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gopherd/doge/erron"
+	"github.com/gopherd/doge/net/netutil"
+
+	"github.com/gopherd/gopherd/auth"
+	"github.com/gopherd/gopherd/auth/api"
+)
+
+// Introspect reports state of an access token for service clients
+func Introspect(service auth.Service, w http.ResponseWriter, r *http.Request) {
+	const tag = "introspect"
+	if r.Method != http.MethodPost {
+		api.Response(w, erron.Errnof(api.BadArgument, "method %s not allowed", r.Method))
+		return
+	}
+	req := new(api.IntrospectRequest)
+	err := req.Parse(r)
+	if err != nil {
+		service.Logger().Info().
+			String("api", tag).
+			Error("error", err).
+			Print("parse arguments error")
+		api.Response(w, erron.Errno(api.BadArgument, err))
+		return
+	}
+	client, err := authenticateClient(service, r, req.ClientId, req.ClientSecret, req.ClientAssertion)
+	if err != nil {
+		service.Logger().Warn().
+			String("api", tag).
+			String("client", req.ClientId).
+			String("ip", netutil.IP(r)).
+			Error("error", err).
+			Print("authenticate client error")
+		api.Response(w, erron.AsErrno(err))
+		return
+	}
+
+	resp := new(api.IntrospectResponse)
+	claims, err := service.Signer().Verify(service.Config().JWT.Issuer, req.Token)
+	if err != nil {
+		// invalid or expired tokens are inactive
+		service.Logger().Debug().
+			String("api", tag).
+			String("client", client.ID).
+			Error("error", err).
+			Print("introspect invalid token")
+		api.Response(w, resp)
+		return
+	}
+	resp.Uid = claims.Payload.ID
+	resp.Client, _ = claims.Payload.Values[api.ClaimClient].(string)
+	resp.Scope = claims.Payload.Scope
+	resp.Roles, _ = claims.Payload.Values[api.ClaimRoles].(string)
+	resp.Permissions, _ = claims.Payload.Values[api.ClaimPermissions].(string)
+	resp.Impersonator = impersonatorOf(claims)
+	resp.Session = sessionOf(claims)
+	resp.IssuedAt = claims.IssuedAt
+	resp.ExpiredAt = claims.ExpiresAt
+	resp.Active = true
+	if resp.Session != "" {
+		active, err := service.SessionModule().Active(resp.Uid, resp.Session)
+		if err != nil {
+			service.Logger().Warn().
+				String("api", tag).
+				Int64("uid", resp.Uid).
+				Error("error", err).
+				Print("check session error")
+			api.Response(w, erron.AsErrno(err))
+			return
+		}
+		resp.Revoked = !active
+		resp.Active = active
+	}
+	if resp.Active && resp.Uid > 0 {
+		account, err := service.AccountModule().Load(auth.ByID(resp.Uid))
+		if err != nil {
+			service.Logger().Warn().
+				String("api", tag).
+				Int64("uid", resp.Uid).
+				Error("error", err).
+				Print("get account error")
+			api.Response(w, erron.AsErrno(err))
+			return
+		}
+		if account == nil {
+			resp.Active = false
+		} else if banned, _ := account.GetBanned(); banned {
+			resp.Active = false
+		}
+	}
+	service.Logger().Debug().
+		String("api", tag).
+		String("client", client.ID).
+		Int64("uid", resp.Uid).
+		Bool("active", resp.Active).
+		Print("token introspected")
+	api.Response(w, resp)
+}
+
+// Userinfo responds profile of the account of the access token
+func Userinfo(service auth.Service, w http.ResponseWriter, r *http.Request) {
+	const tag = "userinfo"
+	req := new(api.UserinfoRequest)
+	err := req.Parse(r)
+	if err != nil {
+		service.Logger().Info().
+			String("api", tag).
+			Error("error", err).
+			Print("parse arguments error")
+		api.Response(w, erron.Errno(api.BadArgument, err))
+		return
+	}
+	claims, account := authenticate(service, tag, w, r, req.Token, readScopes)
+	if account == nil {
+		return
+	}
+	api.Response(w, &api.UserinfoResponse{
+		Id:       account.GetID(),
+		Name:     account.GetName(),
+		Tag:      account.GetTag(),
+		Avatar:   account.GetAvatar(),
+		Gender:   account.GetGender(),
+		Location: account.GetLocation(),
+		Scope:    claims.Payload.Scope,
+	})
+}
@@ -0,0 +1,22 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gopherd/doge/net/httputil"
+
+	"github.com/gopherd/gopherd/auth"
+)
+
+const defaultJWKSMaxAge = 300 // seconds
+
+// JWKS publishes public keys to verify tokens as a JSON Web Key Set
+func JWKS(service auth.Service, w http.ResponseWriter, r *http.Request) {
+	maxAge := service.Config().JWT.JWKSMaxAge
+	if maxAge <= 0 {
+		maxAge = defaultJWKSMaxAge
+	}
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.FormatInt(maxAge, 10))
+	httputil.JSONResponse(w, service.Signer().JWKS())
+}
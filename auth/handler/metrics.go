@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"time"
+
+	"github.com/gopherd/doge/erron"
+	"github.com/gopherd/doge/net/netutil"
+
+	"github.com/gopherd/gopherd/auth"
+	"github.com/gopherd/gopherd/auth/api"
+	"github.com/gopherd/gopherd/auth/metrics"
+	"github.com/gopherd/gopherd/auth/provider"
+)
+
+// Metrics exposes prometheus metrics to scrapers which send the metrics token
+// as bearer token
+func Metrics(service auth.Service, w http.ResponseWriter, r *http.Request) {
+	const tag = "metrics"
+	expected := service.Config().Metrics.Token
+	token, ok := bearerToken(r, "")
+	if expected == "" || !ok || subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+		service.Logger().Warn().
+			String("api", tag).
+			String("ip", netutil.IP(r)).
+			Print("metrics token mismatched")
+		api.Response(w, erron.Errnof(api.Unauthorized, "unauthorized"))
+		return
+	}
+	metrics.Handler().ServeHTTP(w, r)
+}
+
+// authorizeProvider authorizes the account by the provider and observes
+// latency of the authorization
+func authorizeProvider(p provider.Provider, name, account, secret string) (*provider.UserInfo, error) {
+	start := time.Now()
+	user, err := p.Authorize(account, secret)
+	metrics.ProviderDuration.WithLabelValues(name, metrics.ErrorResult(err)).Observe(metrics.Since(start))
+	return user, err
+}
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gopherd/doge/build"
+	"github.com/gopherd/doge/net/httputil"
+
+	"github.com/gopherd/gopherd/auth"
+	"github.com/gopherd/gopherd/auth/api"
+)
+
+// OpenAPI responds the OpenAPI document of apis with configured routes
+func OpenAPI(service auth.Service, w http.ResponseWriter, r *http.Request) {
+	routers := service.Config().Routers
+	routes := make(map[string]string)
+	for route, configured := range map[string]string{
+		api.RouteAuthorize:          routers.Authorize,
+		api.RouteLink:               routers.Link,
+		api.RouteSmsCode:            routers.SMSCode,
+		api.RouteProfile:            routers.Profile,
+		api.RouteVerify2fa:          routers.Verify2FA,
+		api.RouteTwoFactorEnroll:    routers.TwoFactorEnroll,
+		api.RouteTwoFactorActivate:  routers.TwoFactorActivate,
+		api.RouteTwoFactorDisable:   routers.TwoFactorDisable,
+		api.RouteWebAuthnRegister:   routers.WebAuthnRegister,
+		api.RouteWebAuthnLogin:      routers.WebAuthnLogin,
+		api.RouteRefresh:            routers.Refresh,
+		api.RouteSessions:           routers.Sessions,
+		api.RouteRevokeSession:      routers.RevokeSession,
+		api.RouteToken:              routers.Token,
+		api.RouteIntrospect:         routers.Introspect,
+		api.RouteUserinfo:           routers.Userinfo,
+		api.RouteRegistrationReport: routers.RegistrationReport,
+		api.RouteRoles:              routers.Roles,
+		api.RouteGrantRole:          routers.GrantRole,
+		api.RouteRevokeRole:         routers.RevokeRole,
+		api.RouteImpersonate:        routers.Impersonate,
+	} {
+		if configured != "" {
+			routes[route] = configured
+		}
+	}
+	httputil.JSONResponse(w, api.OpenAPI(build.Name(), build.Version(), routes))
+}
@@ -0,0 +1,241 @@
+package handler
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gopherd/doge/erron"
+
+	"github.com/gopherd/gopherd/auth"
+	"github.com/gopherd/gopherd/auth/api"
+	"github.com/gopherd/gopherd/auth/avatar"
+	"github.com/gopherd/gopherd/auth/provider"
+)
+
+const (
+	maxAvatarLength   = 512
+	maxLocationLength = 128 // in runes
+)
+
+// profile fields
+const (
+	fieldName     = "name"
+	fieldAvatar   = "avatar"
+	fieldGender   = "gender"
+	fieldLocation = "location"
+)
+
+func Profile(service auth.Service, w http.ResponseWriter, r *http.Request) {
+	const tag = "profile"
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		api.Response(w, erron.Errnof(api.BadArgument, "method %s not allowed", r.Method))
+		return
+	}
+	req := new(api.ProfileRequest)
+	err := req.Parse(r)
+	if err != nil {
+		service.Logger().Info().
+			String("api", tag).
+			Error("error", err).
+			Print("parse arguments error")
+		api.Response(w, erron.Errno(api.BadArgument, err))
+		return
+	}
+	required := readScopes
+	if r.Method == http.MethodPost {
+		required = writeScopes
+	}
+	account := loadAccountByToken(service, tag, w, r, req.Token, required)
+	if account == nil {
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		fields, err := updateProfile(service, account, req, r.Form)
+		if err != nil {
+			service.Logger().Info().
+				String("api", tag).
+				Int64("uid", account.GetID()).
+				Error("error", err).
+				Print("update profile error")
+			api.Response(w, erron.AsErrno(err))
+			return
+		}
+		if len(fields) > 0 {
+			if err := service.AccountModule().Store("", account, fields...); err != nil {
+				service.Logger().Error().
+					String("api", tag).
+					Int64("uid", account.GetID()).
+					Error("error", err).
+					Print("store account error")
+				api.Response(w, erron.AsErrno(err))
+				return
+			}
+			if err := service.EventModule().Publish(&auth.ProfileChangedEvent{
+				Uid:      account.GetID(),
+				Fields:   fields,
+				Name:     account.GetName(),
+				Tag:      account.GetTag(),
+				Avatar:   account.GetAvatar(),
+				Gender:   account.GetGender(),
+				Location: account.GetLocation(),
+			}); err != nil {
+				service.Logger().Warn().
+					String("api", tag).
+					Int64("uid", account.GetID()).
+					Error("error", err).
+					Print("publish profile changed event error")
+			}
+			service.AvatarModule().Mirror(account.GetID(), account.GetAvatar())
+		}
+	}
+
+	api.Response(w, &api.ProfileResponse{
+		Id:       account.GetID(),
+		Name:     account.GetName(),
+		Tag:      account.GetTag(),
+		Avatar:   account.GetAvatar(),
+		Gender:   account.GetGender(),
+		Location: account.GetLocation(),
+	})
+}
+
+// updateProfile updates fields present in form and returns names of changed fields
+func updateProfile(service auth.Service, account auth.Account, req *api.ProfileRequest, form url.Values) ([]string, error) {
+	var fields []string
+	if _, ok := form[fieldName]; ok {
+		name, err := service.AccountModule().ValidateName(req.Name)
+		if err != nil {
+			return nil, erron.Errno(api.BadArgument, err)
+		}
+		if name != account.GetName() {
+			if err := service.AccountModule().AssignName(account, name); err != nil {
+				return nil, err
+			}
+			fields = append(fields, fieldName)
+		}
+	}
+	if _, ok := form[fieldAvatar]; ok {
+		if req.Avatar != "" {
+			if err := validateAvatar(req.Avatar); err != nil {
+				return nil, erron.Errno(api.BadArgument, err)
+			}
+		}
+		if req.Avatar != account.GetAvatar() {
+			account.SetAvatar(req.Avatar)
+			fields = append(fields, fieldAvatar)
+		}
+	}
+	if _, ok := form[fieldGender]; ok {
+		if err := validateGender(req.Gender); err != nil {
+			return nil, erron.Errno(api.BadArgument, err)
+		}
+		if req.Gender != account.GetGender() {
+			account.SetGender(req.Gender)
+			fields = append(fields, fieldGender)
+		}
+	}
+	if _, ok := form[fieldLocation]; ok {
+		location := strings.TrimSpace(req.Location)
+		if err := validateText("location", location, maxLocationLength); err != nil {
+			return nil, erron.Errno(api.BadArgument, err)
+		}
+		if location != account.GetLocation() {
+			account.SetLocation(location)
+			fields = append(fields, fieldLocation)
+		}
+	}
+	return fields, nil
+}
+
+// applyUserInfo applies profile from provider to account, name and avatar
+// are applied only if not set so that edits of users aren't overwritten
+func applyUserInfo(service auth.Service, account auth.Account, user *provider.UserInfo) {
+	if account.GetName() == "" && user.Name != "" {
+		if name := service.AccountModule().SanitizeName(user.Name); name != "" {
+			assignName(service, account, name)
+		}
+	}
+	if account.GetAvatar() == "" && user.Avatar != "" {
+		account.SetAvatar(user.Avatar)
+	}
+	if user.Gender != provider.Unknown {
+		account.SetGender(int(user.Gender))
+	}
+	if user.Location != "" {
+		account.SetLocation(user.Location)
+	}
+}
+
+// applyRequestProfile applies profile sent by client to fields of account which
+// are not set, invalid values are ignored
+func applyRequestProfile(service auth.Service, account auth.Account, name, avatar string, gender int) {
+	if account.GetName() == "" && name != "" {
+		if name, err := service.AccountModule().ValidateName(name); err == nil {
+			assignName(service, account, name)
+		}
+	}
+	if account.GetAvatar() == "" && validateAvatar(avatar) == nil {
+		account.SetAvatar(avatar)
+	}
+	if account.GetGender() == api.Unknown && gender != api.Unknown && validateGender(gender) == nil {
+		account.SetGender(gender)
+	}
+}
+
+func assignName(service auth.Service, account auth.Account, name string) {
+	if err := service.AccountModule().AssignName(account, name); err != nil {
+		service.Logger().Warn().
+			Int64("uid", account.GetID()).
+			String("name", name).
+			Error("error", err).
+			Print("assign name error")
+	}
+}
+
+func validateText(field, text string, maxLength int) error {
+	if !utf8.ValidString(text) {
+		return errors.New(field + ": invalid utf8 string")
+	}
+	if utf8.RuneCountInString(text) > maxLength {
+		return errors.New(field + ": too long")
+	}
+	for _, c := range text {
+		if !unicode.IsGraphic(c) {
+			return errors.New(field + ": invalid character")
+		}
+	}
+	return nil
+}
+
+func validateAvatar(source string) error {
+	if len(source) > maxAvatarLength {
+		return errors.New("avatar: too long")
+	}
+	u, err := url.Parse(source)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("avatar: invalid url")
+	}
+	// names are checked after resolved while mirroring
+	if ip := net.ParseIP(u.Hostname()); ip != nil && avatar.CheckIP(ip) != nil {
+		return errors.New("avatar: internal address")
+	}
+	if strings.EqualFold(u.Hostname(), "localhost") {
+		return errors.New("avatar: internal address")
+	}
+	return nil
+}
+
+func validateGender(gender int) error {
+	switch gender {
+	case api.Unknown, api.Male, api.Female:
+		return nil
+	default:
+		return errors.New("gender: invalid value")
+	}
+}
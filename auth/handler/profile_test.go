@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/gopherd/gopherd/auth/account"
+	"github.com/gopherd/gopherd/auth/provider"
+)
+
+func TestApplyUserInfoKeepsEdits(t *testing.T) {
+	service := newTestService()
+	a := &account.Account{
+		Name:   "edited",
+		Avatar: "https://example.com/edited.png",
+	}
+	applyUserInfo(service, a, &provider.UserInfo{
+		Name:     "provider",
+		Avatar:   "https://example.com/provider.png",
+		Location: "Tokyo",
+	})
+	if a.Name != "edited" {
+		t.Fatalf("name overwritten: %q", a.Name)
+	}
+	if a.Avatar != "https://example.com/edited.png" {
+		t.Fatalf("avatar overwritten: %q", a.Avatar)
+	}
+	if a.Location != "Tokyo" {
+		t.Fatalf("location not applied: %q", a.Location)
+	}
+
+	a = new(account.Account)
+	applyUserInfo(service, a, &provider.UserInfo{Avatar: "https://example.com/provider.png"})
+	if a.Avatar != "https://example.com/provider.png" {
+		t.Fatalf("avatar not applied: %q", a.Avatar)
+	}
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gopherd/doge/erron"
+
+	"github.com/gopherd/gopherd/auth"
+	"github.com/gopherd/gopherd/auth/api"
+)
+
+const (
+	reportDateLayout = "2006-01-02"
+	maxReportDays    = 366
+)
+
+func RegistrationReport(service auth.Service, w http.ResponseWriter, r *http.Request) {
+	const tag = "registration_report"
+	if !adminOnly(service, tag, w, r) {
+		return
+	}
+	req := new(api.RegistrationReportRequest)
+	err := req.Parse(r)
+	if err != nil {
+		service.Logger().Info().
+			String("api", tag).
+			Error("error", err).
+			Print("parse arguments error")
+		api.Response(w, erron.Errno(api.BadArgument, err))
+		return
+	}
+	from, err := time.ParseInLocation(reportDateLayout, req.From, time.Local)
+	if err != nil {
+		api.Response(w, erron.Errnof(api.BadArgument, "invalid from: %q", req.From))
+		return
+	}
+	to := from
+	if req.To != "" {
+		if to, err = time.ParseInLocation(reportDateLayout, req.To, time.Local); err != nil {
+			api.Response(w, erron.Errnof(api.BadArgument, "invalid to: %q", req.To))
+			return
+		}
+	}
+	// to is inclusive
+	to = to.AddDate(0, 0, 1)
+	if !to.After(from) || to.Sub(from) > maxReportDays*24*time.Hour {
+		api.Response(w, erron.Errnof(api.BadArgument, "invalid date range: %s ~ %s", req.From, req.To))
+		return
+	}
+
+	stats, err := service.AccountModule().RegistrationReport(from, to)
+	if err != nil {
+		service.Logger().Error().
+			String("api", tag).
+			String("from", req.From).
+			String("to", req.To).
+			Error("error", err).
+			Print("query registration report error")
+		api.Response(w, erron.AsErrno(err))
+		return
+	}
+	resp := &api.RegistrationReportResponse{
+		Stats: make([]api.RegistrationStat, 0, len(stats)),
+	}
+	for _, stat := range stats {
+		resp.Stats = append(resp.Stats, api.RegistrationStat{
+			Day:     stat.Day,
+			Channel: stat.Channel,
+			Source:  stat.Source,
+			Os:      stat.OS,
+			Count:   stat.Count,
+		})
+	}
+	api.Response(w, resp)
+}
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gopherd/doge/erron"
+
+	"github.com/gopherd/gopherd/auth"
+	"github.com/gopherd/gopherd/auth/api"
+)
+
+// adminOnly reports whether the request is authorized as an admin request,
+// it responds Unauthorized if not
+func adminOnly(service auth.Service, tag string, w http.ResponseWriter, r *http.Request) bool {
+	if isAdmin(service, r) {
+		return true
+	}
+	service.Logger().Warn().
+		String("api", tag).
+		Print("admin key mismatched")
+	api.Response(w, erron.Errnof(api.Unauthorized, "unauthorized"))
+	return false
+}
+
+// Roles lists roles of an account and permissions granted by the roles
+func Roles(service auth.Service, w http.ResponseWriter, r *http.Request) {
+	const tag = "roles"
+	if !adminOnly(service, tag, w, r) {
+		return
+	}
+	req := new(api.RolesRequest)
+	err := req.Parse(r)
+	if err != nil {
+		service.Logger().Info().
+			String("api", tag).
+			Error("error", err).
+			Print("parse arguments error")
+		api.Response(w, erron.Errno(api.BadArgument, err))
+		return
+	}
+	grants, err := service.RoleModule().Grants(req.Uid)
+	if err != nil {
+		service.Logger().Warn().
+			String("api", tag).
+			Int64("uid", req.Uid).
+			Error("error", err).
+			Print("load roles error")
+		api.Response(w, erron.AsErrno(err))
+		return
+	}
+	resp := &api.RolesResponse{
+		Roles:       grants.Roles,
+		Permissions: grants.Permissions,
+	}
+	if resp.Roles == nil {
+		resp.Roles = []string{}
+	}
+	if resp.Permissions == nil {
+		resp.Permissions = []string{}
+	}
+	api.Response(w, resp)
+}
+
+// GrantRole grants a role to an account, it takes effect on next login or
+// refresh of the account
+func GrantRole(service auth.Service, w http.ResponseWriter, r *http.Request) {
+	const tag = "grant_role"
+	if !adminOnly(service, tag, w, r) {
+		return
+	}
+	req := new(api.GrantRoleRequest)
+	err := req.Parse(r)
+	if err != nil {
+		service.Logger().Info().
+			String("api", tag).
+			Error("error", err).
+			Print("parse arguments error")
+		api.Response(w, erron.Errno(api.BadArgument, err))
+		return
+	}
+	if found, err := service.AccountModule().Contains(auth.ByID(req.Uid)); err != nil {
+		api.Response(w, erron.AsErrno(err))
+		return
+	} else if !found {
+		api.Response(w, erron.Errnof(api.BadArgument, "account %d not found", req.Uid))
+		return
+	}
+	granted, err := service.RoleModule().Grant(req.Uid, req.Role)
+	if err != nil {
+		service.Logger().Warn().
+			String("api", tag).
+			Int64("uid", req.Uid).
+			String("role", req.Role).
+			Error("error", err).
+			Print("grant role error")
+		api.Response(w, erron.AsErrno(err))
+		return
+	}
+	api.Response(w, &api.GrantRoleResponse{
+		Granted: granted,
+	})
+}
+
+// RevokeRole revokes a role from an account and revokes login sessions of
+// the account, so the role can't be used by issued tokens
+func RevokeRole(service auth.Service, w http.ResponseWriter, r *http.Request) {
+	const tag = "revoke_role"
+	if !adminOnly(service, tag, w, r) {
+		return
+	}
+	req := new(api.RevokeRoleRequest)
+	err := req.Parse(r)
+	if err != nil {
+		service.Logger().Info().
+			String("api", tag).
+			Error("error", err).
+			Print("parse arguments error")
+		api.Response(w, erron.Errno(api.BadArgument, err))
+		return
+	}
+	revoked, err := service.RoleModule().Revoke(req.Uid, req.Role)
+	if err != nil {
+		service.Logger().Warn().
+			String("api", tag).
+			Int64("uid", req.Uid).
+			String("role", req.Role).
+			Error("error", err).
+			Print("revoke role error")
+		api.Response(w, erron.AsErrno(err))
+		return
+	}
+	if revoked {
+		if _, err := service.SessionModule().Revoke(req.Uid, ""); err != nil {
+			service.Logger().Warn().
+				String("api", tag).
+				Int64("uid", req.Uid).
+				Error("error", err).
+				Print("revoke sessions error")
+			api.Response(w, erron.AsErrno(err))
+			return
+		}
+	}
+	api.Response(w, &api.RevokeRoleResponse{
+		Revoked: revoked,
+	})
+}
@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"strings"
+
+	"github.com/gopherd/doge/erron"
+
+	"github.com/gopherd/gopherd/auth"
+	"github.com/gopherd/gopherd/auth/api"
+	"github.com/gopherd/gopherd/auth/scope"
+)
+
+// grantScope returns scopes granted to the account for requested scopes,
+// requested scopes which can't be granted to the account are dropped
+func grantScope(service auth.Service, account auth.Account, grants *auth.Grants, requested string) (scope.Scope, error) {
+	cfg := service.Config().Scope
+	if requested == "" {
+		requested = cfg.Default
+	}
+	want, unknown := scope.Parse(requested)
+	if len(unknown) > 0 {
+		return scope.None, erron.Errnof(api.BadArgument, "unknown scopes: %s", strings.Join(unknown, " "))
+	}
+	grantable, _ := scope.Parse(cfg.Grantable)
+	// service scope is granted to service clients only
+	grantable |= grants.Scope &^ scope.Service
+	for _, uid := range cfg.Admins {
+		if uid == account.GetID() {
+			grantable = scope.All &^ scope.Service
+			break
+		}
+	}
+	granted := want & grantable
+	if granted == scope.None {
+		return granted, erron.Errnof(api.ScopeDenied, "scope not granted")
+	}
+	return granted, nil
+}
@@ -0,0 +1,254 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gopherd/doge/erron"
+	"github.com/gopherd/doge/net/netutil"
+	"github.com/gopherd/jwt"
+
+	"github.com/gopherd/gopherd/auth"
+	"github.com/gopherd/gopherd/auth/api"
+)
+
+// refreshIssuerSuffix is appended to issuer of refresh tokens, so refresh
+// tokens can't be used as access tokens
+const refreshIssuerSuffix = "#refresh"
+
+func refreshIssuer(service auth.Service) string {
+	return service.Config().JWT.Issuer + refreshIssuerSuffix
+}
+
+// tokens represents signed access token and refresh token
+type tokens struct {
+	accessToken           string
+	accessTokenExpiredAt  int64
+	refreshToken          string
+	refreshTokenExpiredAt int64
+}
+
+// signTokens signs access token by claims and refresh token of the session
+func signTokens(service auth.Service, claims *jwt.Claims, session *auth.Session) (tokens, error) {
+	var t tokens
+	options := service.Config()
+	claims.Issuer = options.JWT.Issuer
+	claims.IssuedAt = time.Now().Unix()
+	claims.ExpiresAt = claims.IssuedAt + options.AccessTokenTTL
+	if claims.Payload.Values == nil {
+		claims.Payload.Values = make(map[string]any)
+	}
+	claims.Payload.Values[api.ClaimSession] = session.ID
+	var err error
+	if t.accessToken, err = service.Signer().Sign(claims); err != nil {
+		return t, err
+	}
+	t.accessTokenExpiredAt = claims.ExpiresAt
+
+	refresh := new(jwt.Claims)
+	refresh.Issuer = refreshIssuer(service)
+	refresh.Id = session.ID
+	refresh.IssuedAt = claims.IssuedAt
+	refresh.ExpiresAt = session.ExpiresAt.Unix()
+	refresh.Payload = jwt.Payload{
+		Salt:  session.Salt,
+		Scope: claims.Payload.Scope,
+		ID:    claims.Payload.ID,
+		IP:    claims.Payload.IP,
+	}
+	if t.refreshToken, err = service.Signer().Sign(refresh); err != nil {
+		return t, err
+	}
+	t.refreshTokenExpiredAt = refresh.ExpiresAt
+	return t, nil
+}
+
+// Refresh exchanges a refresh token for new access token and refresh token
+func Refresh(service auth.Service, w http.ResponseWriter, r *http.Request) {
+	const tag = "refresh"
+	req := new(api.RefreshRequest)
+	err := req.Parse(r)
+	if err != nil {
+		service.Logger().Info().
+			String("api", tag).
+			Error("error", err).
+			Print("parse arguments error")
+		api.Response(w, erron.Errno(api.BadArgument, err))
+		return
+	}
+	claims, err := service.Signer().Verify(refreshIssuer(service), req.RefreshToken)
+	if err != nil || claims.Id == "" {
+		service.Logger().Warn().
+			String("api", tag).
+			Error("error", err).
+			Print("invalid refresh token")
+		api.Response(w, erron.Errnof(api.Unauthorized, "invalid refresh token"))
+		return
+	}
+	uid := claims.Payload.ID
+	ip := netutil.IP(r)
+	if !service.GeoModule().Allowed(ip) {
+		service.Logger().Info().
+			String("api", tag).
+			Int64("uid", uid).
+			String("ip", ip).
+			Print("region blocked")
+		api.Response(w, erron.Errnof(api.RegionBlocked, "region blocked"))
+		return
+	}
+	account, err := service.AccountModule().Load(auth.ByID(uid))
+	if err != nil {
+		service.Logger().Warn().
+			String("api", tag).
+			Int64("uid", uid).
+			Error("error", err).
+			Print("get account error")
+		api.Response(w, erron.AsErrno(err))
+		return
+	}
+	if account == nil {
+		api.Response(w, erron.Errnof(api.Unauthorized, "account not found"))
+		return
+	}
+	if banned, reason := account.GetBanned(); banned {
+		service.Logger().Info().
+			String("api", tag).
+			Int64("uid", uid).
+			String("banned_reason", reason).
+			Print("account banned")
+		api.Response(w, erron.Errnof(api.Banned, "banned"))
+		return
+	}
+	// the session is rotated after the account checked, so rejected refresh
+	// tokens are still usable once the account allowed again
+	session, err := service.SessionModule().Refresh(uid, claims.Id, claims.Payload.Salt, ip)
+	if err != nil {
+		service.Logger().Info().
+			String("api", tag).
+			Int64("uid", uid).
+			String("session", claims.Id).
+			Error("error", err).
+			Print("refresh session error")
+		api.Response(w, erron.AsErrno(err))
+		return
+	}
+	// roles and scopes are granted again since grants of the account may be changed
+	grants, err := service.RoleModule().Grants(uid)
+	if err != nil {
+		service.Logger().Error().
+			String("api", tag).
+			Int64("uid", uid).
+			Error("error", err).
+			Print("load roles error")
+		api.Response(w, erron.AsErrno(err))
+		return
+	}
+	granted, err := grantScope(service, account, grants, claims.Payload.Scope)
+	if err != nil {
+		service.Logger().Info().
+			String("api", tag).
+			Int64("uid", uid).
+			String("scope", claims.Payload.Scope).
+			Error("error", err).
+			Print("grant scope error")
+		api.Response(w, erron.AsErrno(err))
+		return
+	}
+	access := accessClaims(account, ip, granted, grants)
+	t, err := signTokens(service, access, session)
+	if err != nil {
+		service.Logger().Error().
+			String("api", tag).
+			Error("error", err).
+			Print("sign tokens error")
+		api.Response(w, erron.AsErrno(err))
+		return
+	}
+	api.Response(w, &api.RefreshResponse{
+		AccessToken:           t.accessToken,
+		AccessTokenExpiredAt:  t.accessTokenExpiredAt,
+		RefreshToken:          t.refreshToken,
+		RefreshTokenExpiredAt: t.refreshTokenExpiredAt,
+		Scope:                 access.Payload.Scope,
+	})
+}
+
+// Sessions lists active login sessions of the account
+func Sessions(service auth.Service, w http.ResponseWriter, r *http.Request) {
+	const tag = "sessions"
+	req := new(api.SessionsRequest)
+	err := req.Parse(r)
+	if err != nil {
+		service.Logger().Info().
+			String("api", tag).
+			Error("error", err).
+			Print("parse arguments error")
+		api.Response(w, erron.Errno(api.BadArgument, err))
+		return
+	}
+	claims, account := authenticate(service, tag, w, r, req.Token, readScopes)
+	if account == nil {
+		return
+	}
+	sessions, err := service.SessionModule().List(account.GetID())
+	if err != nil {
+		service.Logger().Warn().
+			String("api", tag).
+			Int64("uid", account.GetID()).
+			Error("error", err).
+			Print("list sessions error")
+		api.Response(w, erron.AsErrno(err))
+		return
+	}
+	current := sessionOf(claims)
+	resp := &api.SessionsResponse{
+		Sessions: make([]api.SessionInfo, 0, len(sessions)),
+	}
+	for _, s := range sessions {
+		resp.Sessions = append(resp.Sessions, api.SessionInfo{
+			Id:         s.ID,
+			Device:     s.Device,
+			Os:         s.OS,
+			Model:      s.Model,
+			Ip:         s.IP,
+			CreatedAt:  s.CreatedAt.Unix(),
+			LastUsedAt: s.LastUsedAt.Unix(),
+			ExpiresAt:  s.ExpiresAt.Unix(),
+			Current:    s.ID == current,
+		})
+	}
+	api.Response(w, resp)
+}
+
+// RevokeSession revokes a login session, or all sessions if id is empty
+func RevokeSession(service auth.Service, w http.ResponseWriter, r *http.Request) {
+	const tag = "revoke_session"
+	req := new(api.RevokeSessionRequest)
+	err := req.Parse(r)
+	if err != nil {
+		service.Logger().Info().
+			String("api", tag).
+			Error("error", err).
+			Print("parse arguments error")
+		api.Response(w, erron.Errno(api.BadArgument, err))
+		return
+	}
+	account := loadAccountByToken(service, tag, w, r, req.Token, writeScopes)
+	if account == nil {
+		return
+	}
+	n, err := service.SessionModule().Revoke(account.GetID(), req.Id)
+	if err != nil {
+		service.Logger().Warn().
+			String("api", tag).
+			Int64("uid", account.GetID()).
+			String("session", req.Id).
+			Error("error", err).
+			Print("revoke session error")
+		api.Response(w, erron.AsErrno(err))
+		return
+	}
+	api.Response(w, &api.RevokeSessionResponse{
+		Revoked: n,
+	})
+}
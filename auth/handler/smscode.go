@@ -5,34 +5,41 @@ import (
 	"time"
 
 	"github.com/gopherd/doge/erron"
-	"github.com/gopherd/doge/net/httputil"
 	"github.com/gopherd/doge/net/netutil"
 	"github.com/gopherd/gopherd/auth"
 	"github.com/gopherd/gopherd/auth/api"
+	"github.com/gopherd/gopherd/auth/metrics"
 )
 
 func SMSCode(service auth.Service, w http.ResponseWriter, r *http.Request) {
 	const tag = "smscode"
 	req := new(api.SmsCodeRequest)
+	defer func() {
+		metrics.SMSCodes.WithLabelValues(metrics.Channel(req.Channel), metrics.Result(api.ResponseErrno(w))).Inc()
+	}()
 	err := req.Parse(r)
 	if err != nil {
 		service.Logger().Info().
 			String("api", tag).
 			Error("error", err).
 			Print("parse arguments error")
-		httputil.JSONResponse(w, erron.Errno(api.BadArgument, err))
+		api.Response(w, erron.Errno(api.BadArgument, err))
 		return
 	}
 	if req.Channel <= 0 {
-		httputil.JSONResponse(w, erron.Errnof(api.BadArgument, "invalid channel: %d", req.Channel))
+		api.Response(w, erron.Errnof(api.BadArgument, "invalid channel: %d", req.Channel))
 		return
 	}
 
-	ttl, err := service.SMSModule().GenerateCode(req.Channel, netutil.IP(r), req.Mobile)
+	ip := netutil.IP(r)
+	if service.ChallengeModule().Required(tag) && !verifyChallenge(service, tag, w, r, ip, req.Challenge, req.Solution, "") {
+		return
+	}
+	ttl, err := service.SMSModule().GenerateCode(req.Channel, ip, req.Mobile)
 	if err != nil {
-		httputil.JSONResponse(w, erron.AsErrno(err))
+		api.Response(w, erron.AsErrno(err))
 	} else {
-		httputil.JSONResponse(w, api.SmsCodeResponse{
+		api.Response(w, api.SmsCodeResponse{
 			Seconds: int(ttl / time.Second),
 		})
 	}
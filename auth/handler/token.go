@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gopherd/doge/crypto/cryptoutil"
+	"github.com/gopherd/doge/erron"
+	"github.com/gopherd/doge/net/netutil"
+	"github.com/gopherd/jwt"
+
+	"github.com/gopherd/gopherd/auth"
+	"github.com/gopherd/gopherd/auth/api"
+	"github.com/gopherd/gopherd/auth/scope"
+)
+
+const grantTypeClientCredentials = "client_credentials"
+
+// authenticateClient authenticates the service client by the client assertion,
+// HTTP Basic authentication or id and secret
+func authenticateClient(service auth.Service, r *http.Request, id, secret, assertion string) (*auth.Client, error) {
+	if assertion != "" {
+		return service.ClientModule().AuthenticateAssertion(id, assertion)
+	}
+	if basicId, basicSecret, ok := r.BasicAuth(); ok {
+		id, secret = basicId, basicSecret
+	}
+	return service.ClientModule().Authenticate(id, secret)
+}
+
+// Token exchanges client credentials of a service client for an access token
+func Token(service auth.Service, w http.ResponseWriter, r *http.Request) {
+	const tag = "token"
+	if r.Method != http.MethodPost {
+		api.Response(w, erron.Errnof(api.BadArgument, "method %s not allowed", r.Method))
+		return
+	}
+	req := new(api.TokenRequest)
+	err := req.Parse(r)
+	if err != nil {
+		service.Logger().Info().
+			String("api", tag).
+			Error("error", err).
+			Print("parse arguments error")
+		api.Response(w, erron.Errno(api.BadArgument, err))
+		return
+	}
+	if req.GrantType != grantTypeClientCredentials {
+		api.Response(w, erron.Errnof(api.BadArgument, "unsupported grant_type: %s", req.GrantType))
+		return
+	}
+
+	client, err := authenticateClient(service, r, req.ClientId, req.ClientSecret, req.ClientAssertion)
+	if err != nil {
+		service.Logger().Warn().
+			String("api", tag).
+			String("client", req.ClientId).
+			String("ip", netutil.IP(r)).
+			Error("error", err).
+			Print("authenticate client error")
+		api.Response(w, erron.AsErrno(err))
+		return
+	}
+
+	// grant scopes
+	granted := client.Scope
+	if req.Scope != "" {
+		want, unknown := scope.Parse(req.Scope)
+		if len(unknown) > 0 {
+			api.Response(w, erron.Errnof(api.BadArgument, "unknown scopes: %s", strings.Join(unknown, " ")))
+			return
+		}
+		granted &= want
+	}
+	if granted == scope.None {
+		api.Response(w, erron.Errnof(api.ScopeDenied, "scope not granted"))
+		return
+	}
+
+	claims := new(jwt.Claims)
+	claims.Issuer = service.Config().JWT.Issuer
+	claims.Subject = client.ID
+	claims.Id = cryptoutil.GenerateSalt(16)
+	claims.IssuedAt = time.Now().Unix()
+	claims.ExpiresAt = claims.IssuedAt + int64(client.TTL/time.Second)
+	claims.Payload = jwt.Payload{
+		Salt:  cryptoutil.GenerateSalt(16),
+		Scope: granted.String(),
+		Values: map[string]any{
+			api.ClaimClient: client.ID,
+		},
+	}
+	token, err := service.Signer().Sign(claims)
+	if err != nil {
+		service.Logger().Error().
+			String("api", tag).
+			String("client", client.ID).
+			Error("error", err).
+			Print("sign client token error")
+		api.Response(w, erron.AsErrno(err))
+		return
+	}
+	service.Logger().Info().
+		String("api", tag).
+		String("client", client.ID).
+		String("scope", claims.Payload.Scope).
+		Print("client token issued")
+	api.Response(w, &api.TokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   claims.ExpiresAt - claims.IssuedAt,
+		Scope:       claims.Payload.Scope,
+	})
+}
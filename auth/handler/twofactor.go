@@ -0,0 +1,238 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gopherd/doge/crypto/cryptoutil"
+	"github.com/gopherd/doge/erron"
+	"github.com/gopherd/doge/net/netutil"
+	"github.com/gopherd/jwt"
+
+	"github.com/gopherd/gopherd/auth"
+	"github.com/gopherd/gopherd/auth/api"
+)
+
+// challengeIssuerSuffix is appended to issuer of challenge tokens, so
+// challenge tokens can't be used as access tokens
+const challengeIssuerSuffix = "#2fa"
+
+func challengeIssuer(service auth.Service) string {
+	return service.Config().JWT.Issuer + challengeIssuerSuffix
+}
+
+// challengeSecondFactor responds a challenge token which carries the authorize
+// request for verify2fa
+func challengeSecondFactor(service auth.Service, tag string, w http.ResponseWriter, ip string, req *api.AuthorizeRequest, account auth.Account) {
+	claims := new(jwt.Claims)
+	claims.Issuer = challengeIssuer(service)
+	claims.IssuedAt = time.Now().Unix()
+	claims.ExpiresAt = claims.IssuedAt + service.Config().TwoFactor.ChallengeTTL
+	claims.Payload = jwt.Payload{
+		Salt: cryptoutil.GenerateSalt(16),
+		ID:   account.GetID(),
+		IP:   ip,
+		Values: map[string]any{
+			"type":    req.Type,
+			"device":  req.Device,
+			"channel": strconv.Itoa(req.Channel),
+			"os":      req.Os,
+			"model":   req.Model,
+			"source":  req.Source,
+			"scope":   req.Scope,
+		},
+	}
+	challenge, err := service.Signer().Sign(claims)
+	if err != nil {
+		service.Logger().Error().
+			String("api", tag).
+			Error("error", err).
+			Print("signed challenge token error")
+		api.Response(w, erron.AsErrno(err))
+		return
+	}
+	service.Logger().Info().
+		String("api", tag).
+		Int64("uid", account.GetID()).
+		Print("second factor required")
+	api.Response(w, &api.SecondFactorRequiredResponse{
+		Error:              api.SecondFactorRequired,
+		Description:        "second factor required",
+		Challenge:          challenge,
+		ChallengeExpiredAt: claims.ExpiresAt,
+		Message:            api.Localize(w, api.SecondFactorRequired),
+	})
+}
+
+func Verify2FA(service auth.Service, w http.ResponseWriter, r *http.Request) {
+	const tag = "verify2fa"
+	req := new(api.Verify2faRequest)
+	err := req.Parse(r)
+	if err != nil {
+		service.Logger().Info().
+			String("api", tag).
+			Error("error", err).
+			Print("parse arguments error")
+		api.Response(w, erron.Errno(api.BadArgument, err))
+		return
+	}
+	ip := netutil.IP(r)
+	claims, err := service.Signer().Verify(challengeIssuer(service), req.Challenge)
+	if err != nil || claims.Payload.IP != ip {
+		service.Logger().Warn().
+			String("api", tag).
+			String("ip", ip).
+			Error("error", err).
+			Print("invalid challenge token")
+		api.Response(w, erron.Errnof(api.Unauthorized, "invalid challenge"))
+		return
+	}
+	account, err := service.AccountModule().Load(auth.ByID(claims.Payload.ID))
+	if err != nil {
+		service.Logger().Warn().
+			String("api", tag).
+			Int64("uid", claims.Payload.ID).
+			Error("error", err).
+			Print("get account error")
+		api.Response(w, erron.AsErrno(err))
+		return
+	}
+	if account == nil {
+		api.Response(w, erron.Errnof(api.Unauthorized, "account not found"))
+		return
+	}
+	if err := service.TwoFactorModule().Verify(account.GetID(), req.Code); err != nil {
+		service.Logger().Info().
+			String("api", tag).
+			Int64("uid", account.GetID()).
+			Error("error", err).
+			Print("verify second factor error")
+		api.Response(w, erron.AsErrno(err))
+		return
+	}
+	// the challenge token is redeemed after verified, so it can't be used
+	// to login again with another code
+	if !service.TwoFactorModule().Redeem(claims.Payload.Salt, claims.ExpiresAt) {
+		service.Logger().Warn().
+			String("api", tag).
+			Int64("uid", account.GetID()).
+			String("ip", ip).
+			Print("challenge token redeemed")
+		api.Response(w, erron.Errnof(api.Unauthorized, "invalid challenge"))
+		return
+	}
+
+	values := claims.Payload.Values
+	channel, _ := strconv.Atoi(stringValue(values, "channel"))
+	login(service, tag, w, ip, &api.AuthorizeRequest{
+		Type:    stringValue(values, "type"),
+		Device:  stringValue(values, "device"),
+		Channel: channel,
+		Os:      stringValue(values, "os"),
+		Model:   stringValue(values, "model"),
+		Source:  stringValue(values, "source"),
+		Scope:   stringValue(values, "scope"),
+	}, account, false)
+}
+
+func stringValue(values map[string]any, key string) string {
+	s, _ := values[key].(string)
+	return s
+}
+
+func TwoFactorEnroll(service auth.Service, w http.ResponseWriter, r *http.Request) {
+	const tag = "2fa_enroll"
+	req := new(api.TwoFactorEnrollRequest)
+	err := req.Parse(r)
+	if err != nil {
+		service.Logger().Info().
+			String("api", tag).
+			Error("error", err).
+			Print("parse arguments error")
+		api.Response(w, erron.Errno(api.BadArgument, err))
+		return
+	}
+	account := loadAccountByToken(service, tag, w, r, req.Token, writeScopes)
+	if account == nil {
+		return
+	}
+	label := account.GetName()
+	if label == "" {
+		label = strconv.FormatInt(account.GetID(), 10)
+	}
+	secret, uri, recoveryCodes, err := service.TwoFactorModule().Enroll(account.GetID(), label)
+	if err != nil {
+		service.Logger().Warn().
+			String("api", tag).
+			Int64("uid", account.GetID()).
+			Error("error", err).
+			Print("enroll two-factor authentication error")
+		api.Response(w, erron.AsErrno(err))
+		return
+	}
+	api.Response(w, &api.TwoFactorEnrollResponse{
+		Secret:        secret,
+		Uri:           uri,
+		RecoveryCodes: recoveryCodes,
+	})
+}
+
+func TwoFactorActivate(service auth.Service, w http.ResponseWriter, r *http.Request) {
+	twoFactor(service, w, r, "2fa_activate", true)
+}
+
+func TwoFactorDisable(service auth.Service, w http.ResponseWriter, r *http.Request) {
+	twoFactor(service, w, r, "2fa_disable", false)
+}
+
+// twoFactor activates or disables two-factor authentication
+func twoFactor(service auth.Service, w http.ResponseWriter, r *http.Request, tag string, enable bool) {
+	var token, code string
+	var err error
+	if enable {
+		req := new(api.TwoFactorActivateRequest)
+		err = req.Parse(r)
+		token, code = req.Token, req.Code
+	} else {
+		req := new(api.TwoFactorDisableRequest)
+		err = req.Parse(r)
+		token, code = req.Token, req.Code
+	}
+	if err != nil {
+		service.Logger().Info().
+			String("api", tag).
+			Error("error", err).
+			Print("parse arguments error")
+		api.Response(w, erron.Errno(api.BadArgument, err))
+		return
+	}
+	account := loadAccountByToken(service, tag, w, r, token, writeScopes)
+	if account == nil {
+		return
+	}
+	if enable {
+		err = service.TwoFactorModule().Activate(account.GetID(), code)
+	} else {
+		err = service.TwoFactorModule().Disable(account.GetID(), code)
+	}
+	if err != nil {
+		service.Logger().Info().
+			String("api", tag).
+			Int64("uid", account.GetID()).
+			Error("error", err).
+			Print("update two-factor authentication error")
+		api.Response(w, erron.AsErrno(err))
+		return
+	}
+	service.Logger().Info().
+		String("api", tag).
+		Int64("uid", account.GetID()).
+		Bool("enabled", enable).
+		Print("two-factor authentication updated")
+	if enable {
+		api.Response(w, &api.TwoFactorActivateResponse{Enabled: true})
+	} else {
+		api.Response(w, &api.TwoFactorDisableResponse{Enabled: false})
+	}
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gopherd/doge/erron"
+
+	"github.com/gopherd/gopherd/auth"
+	"github.com/gopherd/gopherd/auth/api"
+)
+
+// WebAuthnRegister responds options of a registration ceremony for the
+// account of the access token
+func WebAuthnRegister(service auth.Service, w http.ResponseWriter, r *http.Request) {
+	const tag = "webauthn_register"
+	req := new(api.WebAuthnRegisterRequest)
+	err := req.Parse(r)
+	if err != nil {
+		service.Logger().Info().
+			String("api", tag).
+			Error("error", err).
+			Print("parse arguments error")
+		api.Response(w, erron.Errno(api.BadArgument, err))
+		return
+	}
+	account := loadAccountByToken(service, tag, w, r, req.Token, writeScopes)
+	if account == nil {
+		return
+	}
+	options, err := service.WebAuthnModule().BeginRegistration(account)
+	if err != nil {
+		service.Logger().Warn().
+			String("api", tag).
+			Int64("uid", account.GetID()).
+			Error("error", err).
+			Print("begin webauthn registration error")
+		api.Response(w, erron.AsErrno(err))
+		return
+	}
+	api.Response(w, &api.WebAuthnRegisterResponse{
+		Challenge:          options.Challenge,
+		RpId:               options.RPID,
+		RpName:             options.RPName,
+		UserId:             options.UserID,
+		UserName:           options.UserName,
+		Algorithms:         options.Algorithms,
+		ExcludeCredentials: options.Credentials,
+		Timeout:            options.Timeout.Milliseconds(),
+		UserVerification:   options.UserVerification,
+	})
+}
+
+// WebAuthnLogin responds options of an authentication ceremony
+func WebAuthnLogin(service auth.Service, w http.ResponseWriter, r *http.Request) {
+	const tag = "webauthn_login"
+	req := new(api.WebAuthnLoginRequest)
+	err := req.Parse(r)
+	if err != nil {
+		service.Logger().Info().
+			String("api", tag).
+			Error("error", err).
+			Print("parse arguments error")
+		api.Response(w, erron.Errno(api.BadArgument, err))
+		return
+	}
+	options, err := service.WebAuthnModule().BeginLogin()
+	if err != nil {
+		service.Logger().Warn().
+			String("api", tag).
+			Error("error", err).
+			Print("begin webauthn login error")
+		api.Response(w, erron.AsErrno(err))
+		return
+	}
+	api.Response(w, &api.WebAuthnLoginResponse{
+		Challenge:        options.Challenge,
+		RpId:             options.RPID,
+		Timeout:          options.Timeout.Milliseconds(),
+		UserVerification: options.UserVerification,
+	})
+}
@@ -0,0 +1,96 @@
+package keyring
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// JWK represents a public JSON Web Key
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"` // EC, OKP
+	X   string `json:"x,omitempty"`   // EC, OKP
+	Y   string `json:"y,omitempty"`   // EC
+	N   string `json:"n,omitempty"`   // RSA
+	E   string `json:"e,omitempty"`   // RSA
+}
+
+// JWKS represents a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// ReadPublicJWK reads a PEM encoded PKCS#8 private key or PKIX public key
+// file and returns the public key as a JWK
+func ReadPublicJWK(filename, kid string) (JWK, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return JWK{}, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return JWK{}, errors.New("keyring: no PEM data found in " + filename)
+	}
+	var key any
+	if block.Type == "PUBLIC KEY" {
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	} else {
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return JWK{}, err
+	}
+	if signer, ok := key.(crypto.Signer); ok {
+		key = signer.Public()
+	}
+	return PublicJWK(key, kid)
+}
+
+// PublicJWK returns the public key as a JWK
+func PublicJWK(key crypto.PublicKey, kid string) (JWK, error) {
+	b64 := base64.RawURLEncoding
+	jwk := JWK{Kid: kid, Use: "sig"}
+	switch key := key.(type) {
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = key.Curve.Params().Name
+		switch jwk.Crv {
+		case "P-256":
+			jwk.Alg = "ES256"
+		case "P-384":
+			jwk.Alg = "ES384"
+		case "P-521":
+			jwk.Alg = "ES512"
+		default:
+			return JWK{}, fmt.Errorf("keyring: unsupported curve %s", jwk.Crv)
+		}
+		jwk.X = b64.EncodeToString(key.X.FillBytes(make([]byte, size)))
+		jwk.Y = b64.EncodeToString(key.Y.FillBytes(make([]byte, size)))
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.Alg = "RS256"
+		jwk.N = b64.EncodeToString(key.N.Bytes())
+		jwk.E = b64.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Alg = "EdDSA"
+		jwk.Crv = "Ed25519"
+		jwk.X = b64.EncodeToString(key)
+	default:
+		return JWK{}, fmt.Errorf("keyring: unsupported key type %T", key)
+	}
+	return jwk, nil
+}
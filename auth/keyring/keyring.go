@@ -0,0 +1,247 @@
+// Package keyring implements signing and verification of tokens by multiple
+// keys which are identified by kid in headers of tokens, so signing keys can
+// be rotated without a coordinated restart of verifiers.
+//
+// A rotation is staged: the new key is published by JWKS before it becomes
+// active, and the old key is retired by an expiry, so it signs no tokens but
+// verifies tokens signed before until expired, and leaves JWKS after expired.
+package keyring
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gopherd/jwt"
+)
+
+var (
+	ErrMalformedToken = errors.New("keyring: malformed token")
+	ErrUnknownKey     = errors.New("keyring: unknown key")
+	ErrExpiredKey     = errors.New("keyring: key expired")
+)
+
+// KeyID returns kid in header of the token
+func KeyID(token string) (string, error) {
+	i := strings.IndexByte(token, '.')
+	if i < 0 {
+		return "", ErrMalformedToken
+	}
+	data, err := base64.RawURLEncoding.DecodeString(token[:i])
+	if err != nil {
+		return "", ErrMalformedToken
+	}
+	var header struct {
+		Kid string `json:"kid"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return "", ErrMalformedToken
+	}
+	return header.Kid, nil
+}
+
+// Key represents a key file and its id
+type Key struct {
+	Filename string `json:"filename"`
+	KeyId    string `json:"key_id"`
+	// ExpiredAt retires the key if not 0: the key verifies tokens until the
+	// unix time and can't be the active key
+	ExpiredAt int64 `json:"expired_at,omitempty"`
+}
+
+// expiries returns expiries of retired keys keyed by key id
+func expiries(keys []Key) map[string]int64 {
+	expiredAt := make(map[string]int64)
+	for _, key := range keys {
+		if key.ExpiredAt > 0 {
+			expiredAt[key.KeyId] = key.ExpiredAt
+		}
+	}
+	return expiredAt
+}
+
+// expired reports whether the key identified by kid is retired and expired
+func expired(expiredAt map[string]int64, kid string, now time.Time) bool {
+	t, ok := expiredAt[kid]
+	return ok && now.Unix() >= t
+}
+
+// Verifier verifies tokens by the key identified by kid of tokens
+type Verifier struct {
+	mu        sync.RWMutex
+	verifiers map[string]*jwt.Verifier
+	expiredAt map[string]int64
+}
+
+// NewVerifier creates a verifier without keys
+func NewVerifier() *Verifier {
+	return &Verifier{
+		verifiers: make(map[string]*jwt.Verifier),
+	}
+}
+
+// Load loads public keys, keys loaded before are replaced
+func (v *Verifier) Load(keys []Key) error {
+	verifiers := make(map[string]*jwt.Verifier, len(keys))
+	for _, key := range keys {
+		verifier, err := jwt.NewVerifier(key.Filename, key.KeyId)
+		if err != nil {
+			return err
+		}
+		verifiers[key.KeyId] = verifier
+	}
+	v.mu.Lock()
+	v.verifiers = verifiers
+	v.expiredAt = expiries(keys)
+	v.mu.Unlock()
+	return nil
+}
+
+// KeyIds returns ids of loaded keys
+func (v *Verifier) KeyIds() []string {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	ids := make([]string, 0, len(v.verifiers))
+	for id := range v.verifiers {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Verify verifies the token by the key identified by kid of the token, tokens
+// without kid are verified by the only key if only one key loaded
+func (v *Verifier) Verify(issuer, token string) (*jwt.Claims, error) {
+	kid, err := KeyID(token)
+	if err != nil {
+		return nil, err
+	}
+	v.mu.RLock()
+	verifier := lookup(v.verifiers, kid)
+	isExpired := expired(v.expiredAt, kid, time.Now())
+	v.mu.RUnlock()
+	if isExpired {
+		return nil, ErrExpiredKey
+	}
+	if verifier == nil {
+		return nil, ErrUnknownKey
+	}
+	return verifier.Verify(issuer, token)
+}
+
+// lookup returns the key identified by kid, or the only key if kid is empty
+func lookup[T any](keys map[string]*T, kid string) *T {
+	if key, ok := keys[kid]; ok {
+		return key
+	}
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key
+		}
+	}
+	return nil
+}
+
+// ReadDir returns key files of the directory, id of a key is the file name
+// without extensions, e.g. key id of "2022-06.pub.p8" is "2022-06". Hidden
+// files are ignored.
+func ReadDir(dir string) ([]Key, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var keys []Key
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") {
+			continue
+		}
+		id := name
+		if i := strings.IndexByte(name, '.'); i > 0 {
+			id = name[:i]
+		}
+		keys = append(keys, Key{
+			Filename: filepath.Join(dir, name),
+			KeyId:    id,
+		})
+	}
+	return keys, nil
+}
+
+// Signer signs tokens by the active key, and verifies tokens signed by any key
+// which is not expired
+type Signer struct {
+	active    *jwt.Signer
+	signers   map[string]*jwt.Signer
+	expiredAt map[string]int64
+	jwks      JWKS
+}
+
+// NewSigner creates a signer by private keys, the active key signs tokens and
+// must not be retired
+func NewSigner(keys []Key, active string) (*Signer, error) {
+	s := &Signer{
+		signers:   make(map[string]*jwt.Signer, len(keys)),
+		expiredAt: expiries(keys),
+	}
+	for _, key := range keys {
+		if key.KeyId == active && key.ExpiredAt > 0 {
+			return nil, errors.New("keyring: active key " + active + " retired")
+		}
+		signer, err := jwt.NewSigner(key.Filename, key.KeyId)
+		if err != nil {
+			return nil, err
+		}
+		jwk, err := ReadPublicJWK(key.Filename, key.KeyId)
+		if err != nil {
+			return nil, err
+		}
+		s.signers[key.KeyId] = signer
+		s.jwks.Keys = append(s.jwks.Keys, jwk)
+		if key.KeyId == active {
+			s.active = signer
+		}
+	}
+	if s.active == nil {
+		return nil, errors.New("keyring: active key " + active + " not found")
+	}
+	return s, nil
+}
+
+// Sign signs claims by the active key
+func (s *Signer) Sign(claims *jwt.Claims) (string, error) {
+	return s.active.Sign(claims)
+}
+
+// Verify verifies the token by the key identified by kid of the token, tokens
+// without kid are verified by the only key if only one key loaded
+func (s *Signer) Verify(issuer, token string) (*jwt.Claims, error) {
+	kid, err := KeyID(token)
+	if err != nil {
+		return nil, err
+	}
+	if expired(s.expiredAt, kid, time.Now()) {
+		return nil, ErrExpiredKey
+	}
+	signer := lookup(s.signers, kid)
+	if signer == nil {
+		return nil, ErrUnknownKey
+	}
+	return signer.Verify(issuer, token)
+}
+
+// JWKS returns public keys of keys which are not expired
+func (s *Signer) JWKS() JWKS {
+	now := time.Now()
+	jwks := JWKS{Keys: make([]JWK, 0, len(s.jwks.Keys))}
+	for _, jwk := range s.jwks.Keys {
+		if !expired(s.expiredAt, jwk.Kid, now) {
+			jwks.Keys = append(jwks.Keys, jwk)
+		}
+	}
+	return jwks
+}
@@ -0,0 +1,149 @@
+package keyring
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gopherd/jwt"
+)
+
+func TestKeyID(t *testing.T) {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"ES256","kid":"2022-06","typ":"JWT"}`))
+	kid, err := KeyID(header + ".e30.sig")
+	if err != nil || kid != "2022-06" {
+		t.Fatalf("want kid 2022-06, got %q, error %v", kid, err)
+	}
+	for _, token := range []string{"", "abc", "!!!.e30.sig", base64.RawURLEncoding.EncodeToString([]byte("[]")) + ".e30.sig"} {
+		if _, err := KeyID(token); err == nil {
+			t.Fatalf("token %q: error expected", token)
+		}
+	}
+}
+
+func TestReadPublicJWK(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(t.TempDir(), "2022-06.p8")
+	if err := os.WriteFile(filename, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	jwk, err := ReadPublicJWK(filename, "2022-06")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jwk.Kty != "EC" || jwk.Crv != "P-256" || jwk.Alg != "ES256" || jwk.Kid != "2022-06" {
+		t.Fatalf("unexpected jwk: %+v", jwk)
+	}
+	x, _ := base64.RawURLEncoding.DecodeString(jwk.X)
+	y, _ := base64.RawURLEncoding.DecodeString(jwk.Y)
+	if len(x) != 32 || new(big.Int).SetBytes(x).Cmp(key.X) != 0 || new(big.Int).SetBytes(y).Cmp(key.Y) != 0 {
+		t.Fatalf("public key mismatched: %+v", jwk)
+	}
+}
+
+func TestReadDir(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"2022-06.pub.p8", "2022-12.pem", ".hidden"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, key := range keys {
+		ids = append(ids, key.KeyId)
+	}
+	sort.Strings(ids)
+	if len(ids) != 2 || ids[0] != "2022-06" || ids[1] != "2022-12" {
+		t.Fatalf("unexpected key ids: %v", ids)
+	}
+}
+
+// writeKey writes a P-256 private key file of the key id to dir
+func writeKey(t *testing.T, dir, kid string) Key {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(dir, kid+".p8")
+	if err := os.WriteFile(filename, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return Key{Filename: filename, KeyId: kid}
+}
+
+func TestRetiredKeys(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now().Unix()
+	expired := writeKey(t, dir, "2022-06")
+	expired.ExpiredAt = now - 60
+	retired := writeKey(t, dir, "2022-12")
+	retired.ExpiredAt = now + 3600
+	active := writeKey(t, dir, "2023-06")
+	keys := []Key{expired, retired, active}
+
+	if _, err := NewSigner(keys, retired.KeyId); err == nil {
+		t.Fatal("retired key can't be active")
+	}
+	// keys of the signer are read as public keys, so that tokens are
+	// verified without signatures
+	s := &Signer{signers: make(map[string]*jwt.Signer), expiredAt: expiries(keys)}
+	for _, key := range keys {
+		jwk, err := ReadPublicJWK(key.Filename, key.KeyId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.jwks.Keys = append(s.jwks.Keys, jwk)
+	}
+	var kids []string
+	for _, jwk := range s.JWKS().Keys {
+		kids = append(kids, jwk.Kid)
+	}
+	if len(kids) != 2 || kids[0] != retired.KeyId || kids[1] != active.KeyId {
+		t.Fatalf("unexpected keys of jwks: %v", kids)
+	}
+
+	token := func(kid string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"ES256","kid":"`+kid+`","typ":"JWT"}`)) + ".e30.sig"
+	}
+	if _, err := s.Verify("issuer", token(expired.KeyId)); err != ErrExpiredKey {
+		t.Fatalf("want %v, got %v", ErrExpiredKey, err)
+	}
+	// the retired key isn't expired, so the token is looked up by signers
+	if _, err := s.Verify("issuer", token(retired.KeyId)); err != ErrUnknownKey {
+		t.Fatalf("want %v, got %v", ErrUnknownKey, err)
+	}
+	v := NewVerifier()
+	if err := v.Load(keys); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Verify("issuer", token(expired.KeyId)); err != ErrExpiredKey {
+		t.Fatalf("verifier: want %v, got %v", ErrExpiredKey, err)
+	}
+}
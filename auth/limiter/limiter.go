@@ -0,0 +1,89 @@
+package limiter
+
+import (
+	"context"
+	"time"
+
+	"github.com/gopherd/doge/erron"
+	"github.com/gopherd/doge/service/module"
+	"github.com/gopherd/log"
+
+	"github.com/gopherd/gopherd/auth"
+	"github.com/gopherd/gopherd/auth/config"
+	"github.com/gopherd/gopherd/auth/ratelimit"
+)
+
+type Service interface {
+	Config() *config.Config
+	Logger() *log.Logger
+}
+
+// New creates an auth.RateLimitModule
+func New(service Service) interface {
+	module.Module
+	auth.RateLimitModule
+} {
+	return newLimiterModule(service)
+}
+
+// limiterModule implements auth.RateLimitModule
+type limiterModule struct {
+	*module.BasicModule
+	service Service
+	store   ratelimit.Store
+}
+
+func newLimiterModule(service Service) *limiterModule {
+	return &limiterModule{
+		BasicModule: module.NewBasicModule("limiter"),
+		service:     service,
+	}
+}
+
+func (mod *limiterModule) Init() error {
+	if err := mod.BasicModule.Init(); err != nil {
+		return err
+	}
+	cfg := mod.service.Config().RateLimit
+	store, err := ratelimit.Open(cfg.Store, cfg.Source)
+	if err != nil {
+		return erron.Throwf("open ratelimit store %q error %w", cfg.Store, err)
+	}
+	mod.store = store
+	return nil
+}
+
+func (mod *limiterModule) Shutdown() {
+	if mod.store != nil {
+		mod.store.Close()
+	}
+	mod.BasicModule.Shutdown()
+}
+
+// Limit implements auth.RateLimitModule Limit method, requests are allowed if
+// the store fails
+func (mod *limiterModule) Limit(ctx context.Context, route string, arg func(by string) string) time.Duration {
+	var retryAfter time.Duration
+	for _, rule := range mod.service.Config().RateLimit.Routes[route] {
+		if rule.Requests <= 0 {
+			continue
+		}
+		value := arg(rule.By)
+		if value == "" {
+			continue
+		}
+		ok, d, err := mod.store.Take(ctx, route+"/"+rule.By+"/"+value, rule.Limit())
+		if err != nil {
+			mod.service.Logger().Warn().
+				String("route", route).
+				String("by", rule.By).
+				Error("error", err).
+				Print("take rate limit token error")
+			continue
+		}
+		if !ok && d > retryAfter {
+			retryAfter = d
+		}
+	}
+	return retryAfter
+}
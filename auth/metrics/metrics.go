@@ -0,0 +1,127 @@
+// Package metrics defines prometheus metrics of authd
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "authd"
+
+// MaxChannel is max channel used as label value, greater channels are
+// labelled other so that clients can't blow up cardinality of metrics
+const MaxChannel = 999
+
+// Label values of results
+const (
+	ResultOK    = "ok"
+	ResultError = "error"
+)
+
+// Label values of unknown providers and channels
+const (
+	Unknown = "unknown"
+	Other   = "other"
+)
+
+var registry = prometheus.NewRegistry()
+
+var (
+	// Requests counts requests of apis by default route and result
+	Requests = newCounterVec("http_requests_total",
+		"Requests of apis by route and result, result is ok or errno.",
+		"route", "result")
+	// RequestDuration observes latency of apis by default route
+	RequestDuration = newHistogramVec("http_request_duration_seconds",
+		"Latency of apis by route.",
+		"route")
+	// Logins counts authorize requests by provider, channel and result
+	Logins = newCounterVec("logins_total",
+		"Authorize requests by provider, channel and result, result is ok or errno.",
+		"provider", "channel", "result")
+	// Links counts link requests by provider and result
+	Links = newCounterVec("links_total",
+		"Link requests by provider and result, result is ok or errno.",
+		"provider", "result")
+	// SMSCodes counts smscode requests by channel and result
+	SMSCodes = newCounterVec("sms_codes_total",
+		"SMS code requests by channel and result, result is ok or errno.",
+		"channel", "result")
+	// ProviderDuration observes latency of authorizations of providers
+	ProviderDuration = newHistogramVec("provider_authorize_duration_seconds",
+		"Latency of authorizations of providers by provider and result, result is ok or error.",
+		"provider", "result")
+	// DBDuration observes latency of database operations
+	DBDuration = newHistogramVec("db_operation_duration_seconds",
+		"Latency of database operations by operation, table and result, result is ok or error.",
+		"operation", "table", "result")
+)
+
+func init() {
+	registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+	)
+}
+
+func newCounterVec(name, help string, labels ...string) *prometheus.CounterVec {
+	c := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      name,
+		Help:      help,
+	}, labels)
+	registry.MustRegister(c)
+	return c
+}
+
+func newHistogramVec(name, help string, labels ...string) *prometheus.HistogramVec {
+	h := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      name,
+		Help:      help,
+		Buckets:   prometheus.DefBuckets,
+	}, labels)
+	registry.MustRegister(h)
+	return h
+}
+
+// Handler returns the http handler which exposes metrics
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// Result returns the result label of errno: ok, or the errno
+func Result(errno int) string {
+	if errno == 0 {
+		return ResultOK
+	}
+	return strconv.Itoa(errno)
+}
+
+// ErrorResult returns the result label of err: ok or error
+func ErrorResult(err error) string {
+	if err == nil {
+		return ResultOK
+	}
+	return ResultError
+}
+
+// Channel returns the channel label of channel
+func Channel(channel int) string {
+	if channel <= 0 {
+		return Unknown
+	}
+	if channel > MaxChannel {
+		return Other
+	}
+	return strconv.Itoa(channel)
+}
+
+// Since returns seconds elapsed since start
+func Since(start time.Time) float64 {
+	return time.Since(start).Seconds()
+}
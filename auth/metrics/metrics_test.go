@@ -0,0 +1,37 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLabels(t *testing.T) {
+	for _, tc := range []struct {
+		got, want string
+	}{
+		{Result(0), ResultOK},
+		{Result(201), "201"},
+		{ErrorResult(nil), ResultOK},
+		{ErrorResult(errors.New("timeout")), ResultError},
+		{Channel(0), Unknown},
+		{Channel(3), "3"},
+		{Channel(MaxChannel + 1), Other},
+	} {
+		if tc.got != tc.want {
+			t.Fatalf("want %q, got %q", tc.want, tc.got)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	Logins.WithLabelValues("device", Channel(1), Result(0)).Inc()
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	want := `authd_logins_total{channel="1",provider="device",result="ok"} 1`
+	if !strings.Contains(w.Body.String(), want) {
+		t.Fatalf("%q not found in metrics", want)
+	}
+}
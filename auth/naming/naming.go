@@ -0,0 +1,227 @@
+// Package naming implements display name policy: unicode normalization,
+// length and charset limits and blocked words filtering
+package naming
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	ErrEmpty            = errors.New("naming: empty name")
+	ErrTooShort         = errors.New("naming: name too short")
+	ErrTooLong          = errors.New("naming: name too long")
+	ErrInvalidCharacter = errors.New("naming: invalid character")
+	ErrBlockedWord      = errors.New("naming: blocked word")
+)
+
+// mask replaces characters of blocked words while sanitizing
+const mask = '*'
+
+// Config represents config of name policy
+type Config struct {
+	// MinLength and MaxLength limit number of characters, default: 2 and 16
+	MinLength int `json:"min_length"`
+	MaxLength int `json:"max_length"`
+	// Scripts contains allowed unicode scripts of letters, e.g. "Latin", "Han",
+	// letters of all scripts are allowed if empty
+	Scripts []string `json:"scripts"`
+	// Symbols contains allowed punctuation and symbol characters besides
+	// letters, numbers and spaces, default: "-_.'"
+	Symbols *string `json:"symbols"`
+	// Words contains blocked words which are matched case-insensitively
+	// ignoring spaces and symbols
+	Words []string `json:"words"`
+}
+
+// Policy validates and sanitizes display names
+type Policy struct {
+	minLength int
+	maxLength int
+	scripts   []*unicode.RangeTable
+	symbols   string
+	words     [][]rune
+}
+
+// New creates a Policy by config, words are blocked besides cfg.Words
+func New(cfg Config, words ...string) *Policy {
+	p := &Policy{
+		minLength: cfg.MinLength,
+		maxLength: cfg.MaxLength,
+		symbols:   "-_.'",
+	}
+	if p.minLength <= 0 {
+		p.minLength = 2
+	}
+	if p.maxLength <= 0 {
+		p.maxLength = 16
+	}
+	if cfg.Symbols != nil {
+		p.symbols = *cfg.Symbols
+	}
+	for _, name := range cfg.Scripts {
+		if table, ok := unicode.Scripts[name]; ok {
+			p.scripts = append(p.scripts, table)
+		}
+	}
+	for _, list := range [][]string{cfg.Words, words} {
+		for _, word := range list {
+			if w, _ := skeleton(Normalize(word)); len(w) > 0 {
+				p.words = append(p.words, w)
+			}
+		}
+	}
+	return p
+}
+
+// ReadWords reads words from r line by line, empty lines and lines
+// start with # are ignored
+func ReadWords(r io.Reader) ([]string, error) {
+	var (
+		words   []string
+		scanner = bufio.NewScanner(r)
+	)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	return words, scanner.Err()
+}
+
+// Normalize normalizes name by NFKC, removes control and format characters,
+// and collapses spaces
+func Normalize(name string) string {
+	name = norm.NFKC.String(strings.ToValidUTF8(name, ""))
+	var (
+		sb    strings.Builder
+		space bool
+	)
+	for _, c := range name {
+		switch {
+		case unicode.IsSpace(c):
+			space = sb.Len() > 0
+		case unicode.IsControl(c) || unicode.In(c, unicode.Cf):
+		default:
+			if space {
+				sb.WriteByte(' ')
+				space = false
+			}
+			sb.WriteRune(c)
+		}
+	}
+	return sb.String()
+}
+
+// Validate normalizes name and validates it by the policy
+func (p *Policy) Validate(name string) (string, error) {
+	name = Normalize(name)
+	if name == "" {
+		return "", ErrEmpty
+	}
+	for _, c := range name {
+		if !p.allowed(c) {
+			return "", ErrInvalidCharacter
+		}
+	}
+	if n := utf8.RuneCountInString(name); n < p.minLength {
+		return "", ErrTooShort
+	} else if n > p.maxLength {
+		return "", ErrTooLong
+	}
+	if len(p.match(name)) > 0 {
+		return "", ErrBlockedWord
+	}
+	return name, nil
+}
+
+// Sanitize normalizes name, removes disallowed characters, masks blocked words
+// and truncates it to max length. It returns an empty string if the result
+// is too short.
+func (p *Policy) Sanitize(name string) string {
+	name = Normalize(name)
+	runes := make([]rune, 0, len(name))
+	for _, c := range name {
+		if p.allowed(c) {
+			runes = append(runes, c)
+		}
+	}
+	for _, i := range p.match(string(runes)) {
+		runes[i] = mask
+	}
+	if len(runes) > p.maxLength {
+		runes = runes[:p.maxLength]
+	}
+	name = strings.TrimSpace(string(runes))
+	if utf8.RuneCountInString(name) < p.minLength {
+		return ""
+	}
+	return name
+}
+
+func (p *Policy) allowed(c rune) bool {
+	switch {
+	case c == ' ':
+		return true
+	case unicode.IsLetter(c) || unicode.IsMark(c):
+		if len(p.scripts) == 0 || unicode.In(c, unicode.Inherited) {
+			return true
+		}
+		return unicode.In(c, p.scripts...)
+	case unicode.IsNumber(c):
+		return true
+	default:
+		return strings.ContainsRune(p.symbols, c)
+	}
+}
+
+// match returns indices of runes in name which are covered by blocked words
+func (p *Policy) match(name string) []int {
+	if len(p.words) == 0 {
+		return nil
+	}
+	s, indices := skeleton(name)
+	var covered []int
+	for _, w := range p.words {
+		for i := 0; i+len(w) <= len(s); i++ {
+			if equal(s[i:i+len(w)], w) {
+				covered = append(covered, indices[i:i+len(w)]...)
+			}
+		}
+	}
+	return covered
+}
+
+// skeleton returns lower case letters and numbers of name and their rune indices
+func skeleton(name string) ([]rune, []int) {
+	var (
+		runes   []rune
+		indices []int
+		i       int
+	)
+	for _, c := range name {
+		if unicode.IsLetter(c) || unicode.IsNumber(c) {
+			runes = append(runes, unicode.ToLower(c))
+			indices = append(indices, i)
+		}
+		i++
+	}
+	return runes, indices
+}
+
+func equal(x, y []rune) bool {
+	for i := range x {
+		if x[i] != y[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,79 @@
+package naming_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gopherd/gopherd/auth/naming"
+)
+
+func TestNormalize(t *testing.T) {
+	for _, tc := range []struct {
+		name, want string
+	}{
+		{"  Alice  ", "Alice"},
+		{"Bob \t  Smith", "Bob Smith"},
+		{"Ａｌｉｃｅ", "Alice"},
+		{"Al\u200bice", "Alice"},
+		{"Al\x00ice", "Alice"},
+		{"e\u0301", "\u00e9"},
+	} {
+		if got := naming.Normalize(tc.name); got != tc.want {
+			t.Errorf("Normalize(%q): want %q, got %q", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	p := naming.New(naming.Config{
+		MaxLength: 8,
+		Scripts:   []string{"Latin", "Han"},
+		Words:     []string{"bad word"},
+	}, "evil")
+	for _, tc := range []struct {
+		name, want string
+		err        error
+	}{
+		{" Alice ", "Alice", nil},
+		{"张三", "张三", nil},
+		{"O'Neil-1", "O'Neil-1", nil},
+		{"", "", naming.ErrEmpty},
+		{"A", "", naming.ErrTooShort},
+		{"Alice Smith", "", naming.ErrTooLong},
+		{"Алиса", "", naming.ErrInvalidCharacter},
+		{"a@b", "", naming.ErrInvalidCharacter},
+		{"xBadWord", "", naming.ErrBlockedWord},
+		{"E.v.i.l", "", naming.ErrBlockedWord},
+	} {
+		got, err := p.Validate(tc.name)
+		if got != tc.want || err != tc.err {
+			t.Errorf("Validate(%q): want %q, %v, got %q, %v", tc.name, tc.want, tc.err, got, err)
+		}
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	p := naming.New(naming.Config{MaxLength: 8}, "evil")
+	for _, tc := range []struct {
+		name, want string
+	}{
+		{"Alice@Home", "AliceHom"},
+		{"Evil Bob", "**** Bob"},
+		{"@@", ""},
+		{"😀Bob😀", "Bob"},
+	} {
+		if got := p.Sanitize(tc.name); got != tc.want {
+			t.Errorf("Sanitize(%q): want %q, got %q", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestReadWords(t *testing.T) {
+	words, err := naming.ReadWords(strings.NewReader("# comment\nfoo\n\n  bar \n"))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(words) != 2 || words[0] != "foo" || words[1] != "bar" {
+		t.Errorf("ReadWords: unexpected words %q", words)
+	}
+}
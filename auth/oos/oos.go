@@ -2,8 +2,11 @@ package oos
 
 import (
 	"errors"
+	"fmt"
 	"strings"
+	"time"
 
+	mysqldriver "github.com/go-sql-driver/mysql"
 	"github.com/gopherd/doge/erron"
 	"github.com/gopherd/doge/service/module"
 	"github.com/gopherd/gorm_logger_wrapper"
@@ -13,6 +16,7 @@ import (
 
 	"github.com/gopherd/gopherd/auth"
 	"github.com/gopherd/gopherd/auth/config"
+	"github.com/gopherd/gopherd/auth/metrics"
 )
 
 type Service interface {
@@ -78,25 +82,46 @@ func formatConds(by []auth.Field) []any {
 	return args
 }
 
+// observe observes latency and result of the operation on the table, raw
+// queries are labelled by table raw
+func observe(op, table string, start time.Time, err error) {
+	metrics.DBDuration.WithLabelValues(op, table, metrics.ErrorResult(err)).Observe(metrics.Since(start))
+}
+
+// duplicateKeyErrno is the mysql error number of duplicate entries of unique keys
+const duplicateKeyErrno = 1062
+
+// wrapError wraps err by auth.ErrDuplicateKey if a unique key duplicated
+func wrapError(err error) error {
+	var e *mysqldriver.MySQLError
+	if errors.As(err, &e) && e.Number == duplicateKeyErrno {
+		return fmt.Errorf("%w: %v", auth.ErrDuplicateKey, err)
+	}
+	return err
+}
+
 func (mod *oosModule) GetObject(obj auth.Object, by ...auth.Field) (bool, error) {
-	if err := mod.db.Take(obj, formatConds(by)...).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return false, nil
-		}
-		return false, err
+	start := time.Now()
+	err := mod.db.Take(obj, formatConds(by)...).Error
+	found := err == nil
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = nil
 	}
-	return true, nil
+	observe("get", obj.TableName(), start, err)
+	return found, err
 }
 
 func (mod *oosModule) HasObject(tableName string, by ...auth.Field) (bool, error) {
 	var count int64
 	var err error
 	var conds = formatConds(by)
+	var start = time.Now()
 	if len(conds) > 0 {
 		err = mod.db.Table(tableName).Where(conds[0], conds[1:]).Count(&count).Error
 	} else {
 		err = mod.db.Table(tableName).Count(&count).Error
 	}
+	observe("count", tableName, start, err)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return false, nil
@@ -107,15 +132,38 @@ func (mod *oosModule) HasObject(tableName string, by ...auth.Field) (bool, error
 }
 
 func (mod *oosModule) InsertObject(obj auth.Object) error {
-	return mod.db.Create(obj).Error
+	start := time.Now()
+	err := mod.db.Create(obj).Error
+	observe("insert", obj.TableName(), start, err)
+	return wrapError(err)
 }
 
 func (mod *oosModule) UpdateObject(obj auth.Object, fields ...any) (int64, error) {
 	var result *gorm.DB
+	var start = time.Now()
 	if len(fields) > 0 {
 		result = mod.db.Model(obj).Select(fields[0], fields[1:]...).Updates(obj)
 	} else {
 		result = mod.db.Model(obj).Updates(obj)
 	}
+	observe("update", obj.TableName(), start, result.Error)
+	return result.RowsAffected, wrapError(result.Error)
+}
+
+func (mod *oosModule) DeleteObjects(obj auth.Object, by ...auth.Field) (int64, error) {
+	conds := formatConds(by)
+	if len(conds) == 0 {
+		return 0, errors.New("oos: delete objects without conditions")
+	}
+	start := time.Now()
+	result := mod.db.Where(conds[0], conds[1:]...).Delete(obj)
+	observe("delete", obj.TableName(), start, result.Error)
 	return result.RowsAffected, result.Error
 }
+
+func (mod *oosModule) Query(dst any, sql string, args ...any) error {
+	start := time.Now()
+	err := mod.db.Raw(sql, args...).Scan(dst).Error
+	observe("query", "raw", start, err)
+	return err
+}
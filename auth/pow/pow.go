@@ -0,0 +1,51 @@
+// Package pow implements hashcash-like proof-of-work: a solution of a challenge
+// is a string which makes sha256(challenge + ":" + solution) begin with at
+// least difficulty zero bits
+package pow
+
+import (
+	"crypto/sha256"
+	"math/bits"
+	"strconv"
+)
+
+// MaxSolutionLength is max length of solutions
+const MaxSolutionLength = 64
+
+// Hash returns the hash of the solution of the challenge
+func Hash(challenge, solution string) [sha256.Size]byte {
+	return sha256.Sum256([]byte(challenge + ":" + solution))
+}
+
+// LeadingZeroBits returns number of leading zero bits of the hash
+func LeadingZeroBits(hash []byte) int {
+	n := 0
+	for _, b := range hash {
+		if b != 0 {
+			return n + bits.LeadingZeros8(b)
+		}
+		n += 8
+	}
+	return n
+}
+
+// Verify reports whether the solution solves the challenge with difficulty
+func Verify(challenge, solution string, difficulty int) bool {
+	if solution == "" || len(solution) > MaxSolutionLength {
+		return false
+	}
+	hash := Hash(challenge, solution)
+	return LeadingZeroBits(hash[:]) >= difficulty
+}
+
+// Solve finds a solution of the challenge with difficulty, solutions are
+// decimal nonces. It takes about 2^difficulty hashes.
+func Solve(challenge string, difficulty int) string {
+	for nonce := uint64(0); ; nonce++ {
+		solution := strconv.FormatUint(nonce, 10)
+		hash := Hash(challenge, solution)
+		if LeadingZeroBits(hash[:]) >= difficulty {
+			return solution
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package pow
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLeadingZeroBits(t *testing.T) {
+	for _, tc := range []struct {
+		hash []byte
+		want int
+	}{
+		{[]byte{0x80}, 0},
+		{[]byte{0x01, 0xff}, 7},
+		{[]byte{0x00, 0x10}, 11},
+		{[]byte{0x00, 0x00}, 16},
+	} {
+		if got := LeadingZeroBits(tc.hash); got != tc.want {
+			t.Fatalf("%x: want %d, got %d", tc.hash, tc.want, got)
+		}
+	}
+}
+
+func TestSolve(t *testing.T) {
+	const challenge, difficulty = "eyJhbGciOiJFUzI1NiJ9.test", 12
+	solution := Solve(challenge, difficulty)
+	if !Verify(challenge, solution, difficulty) {
+		t.Fatalf("solution %q not verified", solution)
+	}
+	if Verify(challenge, "", 0) {
+		t.Fatal("empty solution verified")
+	}
+	if Verify(challenge, strings.Repeat("0", MaxSolutionLength+1), 0) {
+		t.Fatal("too long solution verified")
+	}
+}
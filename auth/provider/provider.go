@@ -77,6 +77,10 @@ type UserInfo struct {
 	Location string
 
 	OpenId string
+
+	// Uid is id of the linked account, it's set by providers which
+	// store links of accounts themselves, e.g. webauthn
+	Uid int64
 }
 
 type Provider interface {
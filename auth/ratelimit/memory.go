@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is interval of removing full buckets from memory stores
+const sweepInterval = time.Minute
+
+func init() {
+	Register("memory", func(string) (Store, error) {
+		return NewMemoryStore(), nil
+	})
+}
+
+type memoryBucket struct {
+	Bucket
+	limit Limit
+}
+
+// memoryStore implements a Store in memory of the process, limits are not
+// shared by instances
+type memoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryStore creates a Store in memory of the process
+func NewMemoryStore() Store {
+	return &memoryStore{
+		buckets: make(map[string]*memoryBucket),
+		now:     time.Now,
+	}
+}
+
+// Take implements Store Take method
+func (s *memoryStore) Take(ctx context.Context, key string, limit Limit) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		s.lastSweep = now
+		for k, b := range s.buckets {
+			if b.Full(now, b.limit) {
+				delete(s.buckets, k)
+			}
+		}
+	}
+	b, ok := s.buckets[key]
+	if !ok {
+		b = new(memoryBucket)
+		s.buckets[key] = b
+	}
+	b.limit = limit
+	ok, retryAfter := b.Take(now, limit)
+	return ok, retryAfter, nil
+}
+
+// Close implements Store Close method
+func (s *memoryStore) Close() error {
+	return nil
+}
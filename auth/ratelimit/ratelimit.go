@@ -0,0 +1,111 @@
+// Package ratelimit defines pluggable token bucket stores used to limit rates
+// of requests
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// Rule represents a token bucket rule of a route
+type Rule struct {
+	// By is what requests are limited by: ip, or an argument of requests such
+	// as device, account (authorize) and mobile (smscode)
+	By       string `json:"by"`
+	Requests int    `json:"requests"` // requests allowed in period
+	Period   int64  `json:"period"`   // seconds, default: 60
+	Burst    int    `json:"burst"`    // capacity of the bucket, default: requests
+}
+
+// Limit returns the limit of the rule
+func (rule Rule) Limit() Limit {
+	period := rule.Period
+	if period <= 0 {
+		period = 60
+	}
+	burst := rule.Burst
+	if burst <= 0 {
+		burst = rule.Requests
+	}
+	return Limit{
+		Rate:  float64(rule.Requests) / float64(period),
+		Burst: burst,
+	}
+}
+
+// Limit represents limit of a token bucket
+type Limit struct {
+	Rate  float64 // tokens refilled per second
+	Burst int     // capacity of the bucket
+}
+
+// Store represents a token bucket store
+type Store interface {
+	// Take takes a token from the bucket of key, it returns the duration to
+	// wait for the next token if no tokens left
+	Take(ctx context.Context, key string, limit Limit) (ok bool, retryAfter time.Duration, err error)
+	// Close closes the store
+	Close() error
+}
+
+// Driver opens a Store by driver-specific source
+type Driver func(source string) (Store, error)
+
+var (
+	driversMu sync.RWMutex
+	drivers   = make(map[string]Driver)
+)
+
+// Register makes a ratelimit driver available by the provided name
+func Register(name string, driver Driver) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	if _, dup := drivers[name]; dup {
+		panic("ratelimit: Register " + name + " called twice")
+	}
+	drivers[name] = driver
+}
+
+// Open opens a store specified by its driver name and a driver-specific source
+func Open(name string, source string) (Store, error) {
+	driversMu.RLock()
+	driver, ok := drivers[name]
+	driversMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("ratelimit: driver %q not found, forgot import?", name)
+	}
+	return driver(source)
+}
+
+// Bucket represents state of a token bucket
+type Bucket struct {
+	Tokens float64
+	Time   time.Time // time of tokens
+}
+
+// Take refills the bucket to now and takes a token from it, it returns the
+// duration to wait for the next token if no tokens left
+func (b *Bucket) Take(now time.Time, limit Limit) (bool, time.Duration) {
+	if b.Time.IsZero() {
+		b.Tokens = float64(limit.Burst)
+	} else if elapsed := now.Sub(b.Time).Seconds(); elapsed > 0 {
+		b.Tokens = math.Min(float64(limit.Burst), b.Tokens+elapsed*limit.Rate)
+	}
+	b.Time = now
+	if b.Tokens >= 1 {
+		b.Tokens--
+		return true, 0
+	}
+	if limit.Rate <= 0 {
+		return false, time.Duration(math.MaxInt64)
+	}
+	return false, time.Duration((1 - b.Tokens) / limit.Rate * float64(time.Second))
+}
+
+// Full reports whether the bucket would be full at now
+func (b *Bucket) Full(now time.Time, limit Limit) bool {
+	return b.Tokens+now.Sub(b.Time).Seconds()*limit.Rate >= float64(limit.Burst)
+}
@@ -0,0 +1,55 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRuleLimit(t *testing.T) {
+	limit := Rule{By: "ip", Requests: 30}.Limit()
+	if limit.Rate != 0.5 || limit.Burst != 30 {
+		t.Fatalf("unexpected limit %+v", limit)
+	}
+	limit = Rule{By: "mobile", Requests: 5, Period: 3600, Burst: 1}.Limit()
+	if limit.Burst != 1 || limit.Rate*3600 != 5 {
+		t.Fatalf("unexpected limit %+v", limit)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	s := NewMemoryStore().(*memoryStore)
+	now := time.Unix(1655000000, 0)
+	s.now = func() time.Time { return now }
+	ctx := context.Background()
+	limit := Limit{Rate: 1, Burst: 2}
+
+	for i := 0; i < 2; i++ {
+		if ok, _, _ := s.Take(ctx, "a", limit); !ok {
+			t.Fatalf("take %d: token expected", i)
+		}
+	}
+	ok, retryAfter, _ := s.Take(ctx, "a", limit)
+	if ok || retryAfter != time.Second {
+		t.Fatalf("want retry after 1s, got %v %v", ok, retryAfter)
+	}
+	if ok, _, _ := s.Take(ctx, "b", limit); !ok {
+		t.Fatal("buckets of keys should be independent")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	ok, retryAfter, _ = s.Take(ctx, "a", limit)
+	if ok || retryAfter != 500*time.Millisecond {
+		t.Fatalf("want retry after 500ms, got %v %v", ok, retryAfter)
+	}
+	now = now.Add(500 * time.Millisecond)
+	if ok, _, _ := s.Take(ctx, "a", limit); !ok {
+		t.Fatal("token expected after refilled")
+	}
+
+	now = now.Add(sweepInterval)
+	s.Take(ctx, "c", limit)
+	if _, ok := s.buckets["a"]; ok {
+		t.Fatal("full bucket should be swept")
+	}
+}
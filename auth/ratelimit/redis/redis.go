@@ -0,0 +1,97 @@
+// Package redis implements a token bucket store on redis, limits are shared
+// by instances using the same redis.
+//
+// Source format:
+//
+//	[tcp://]host:port?db=<db>&password=<password>&prefix=<prefix>
+//
+// e.g. "127.0.0.1:6379?prefix=gopherd/", buckets are stored in hashes keyed
+// by <prefix>ratelimit/<key>.
+package redis
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gopherd/redis/api"
+
+	"github.com/gopherd/gopherd/auth/ratelimit"
+)
+
+const name = "redis"
+
+func init() {
+	ratelimit.Register(name, open)
+}
+
+func open(source string) (ratelimit.Store, error) {
+	client, options, err := api.NewClient(source)
+	if err != nil {
+		return nil, err
+	}
+	return &store{
+		client: client,
+		prefix: options.Prefix + "ratelimit/",
+	}, nil
+}
+
+// take refills the bucket by redis time and takes a token from it, it returns
+// 1 and 0 if a token taken, otherwise 0 and milliseconds to wait.
+//
+//	KEYS[1]: key of the bucket
+//	ARGV[1]: tokens refilled per second
+//	ARGV[2]: capacity of the bucket
+var take = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "time")
+local tokens = tonumber(bucket[1])
+local last = tonumber(bucket[2])
+if tokens == nil or last == nil then
+	tokens = burst
+elseif now > last then
+	tokens = math.min(burst, tokens + (now - last) / 1000 * rate)
+end
+local allowed, wait = 0, 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = math.ceil((1 - tokens) / rate * 1000)
+end
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "time", now)
+redis.call("PEXPIRE", KEYS[1], math.ceil(burst / rate * 1000))
+return {allowed, wait}
+`)
+
+type store struct {
+	client *redis.Client
+	prefix string
+}
+
+// Take implements ratelimit.Store Take method
+func (s *store) Take(ctx context.Context, key string, limit ratelimit.Limit) (bool, time.Duration, error) {
+	if limit.Rate <= 0 || limit.Burst <= 0 {
+		return false, 0, errors.New("redis: rate and burst of limit must be positive")
+	}
+	result, err := take.Run(ctx, s.client, []string{s.prefix + key}, limit.Rate, limit.Burst).Result()
+	if err != nil {
+		return false, 0, err
+	}
+	values, ok := result.([]any)
+	if !ok || len(values) != 2 {
+		return false, 0, errors.New("redis: unexpected result of take")
+	}
+	allowed, _ := values[0].(int64)
+	wait, _ := values[1].(int64)
+	return allowed == 1, time.Duration(wait) * time.Millisecond, nil
+}
+
+// Close implements ratelimit.Store Close method
+func (s *store) Close() error {
+	return s.client.Close()
+}
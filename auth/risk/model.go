@@ -0,0 +1,19 @@
+package risk
+
+import (
+	"time"
+)
+
+const tableName = "login_history"
+
+type loginHistory struct {
+	ID        int64     `gorm:"primaryKey;column:id"`
+	Uid       int64     `gorm:"index;column:uid;not null"`
+	IP        string    `gorm:"index;column:ip"`
+	Country   string    `gorm:"column:country"`
+	Latitude  float64   `gorm:"column:latitude"`
+	Longitude float64   `gorm:"column:longitude"`
+	LoginAt   time.Time `gorm:"index;column:login_at"`
+}
+
+func (*loginHistory) TableName() string { return tableName }
@@ -0,0 +1,208 @@
+package risk
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gopherd/doge/erron"
+	"github.com/gopherd/doge/service/module"
+
+	"github.com/gopherd/gopherd/auth"
+	"github.com/gopherd/gopherd/auth/config"
+)
+
+// rules
+const (
+	ImpossibleTravel = "impossible_travel"
+	NewCountry       = "new_country"
+	SharedIP         = "shared_ip"
+	HostingNetwork   = "hosting_network"
+)
+
+type Service interface {
+	Config() *config.Config
+	OOSModule() auth.OOSModule
+	GeoModule() auth.GeoModule
+}
+
+// New creates an auth.RiskModule
+func New(service Service) interface {
+	module.Module
+	auth.RiskModule
+} {
+	return newRiskModule(service)
+}
+
+// riskModule implements auth.RiskModule
+type riskModule struct {
+	*module.BasicModule
+	service Service
+}
+
+func newRiskModule(service Service) *riskModule {
+	return &riskModule{
+		BasicModule: module.NewBasicModule("risk"),
+		service:     service,
+	}
+}
+
+func (mod *riskModule) Init() error {
+	if err := mod.BasicModule.Init(); err != nil {
+		return err
+	}
+	if err := mod.checkPolicies(); err != nil {
+		return err
+	}
+	return mod.service.OOSModule().CreateSchema(new(loginHistory))
+}
+
+// checkPolicies rejects verify policies unless challenges are required by
+// authorize, otherwise suspicious logins of accounts without second factor
+// could never be verified
+func (mod *riskModule) checkPolicies() error {
+	cfg := mod.service.Config()
+	if cfg.Challenge.Type != "" && cfg.Challenge.Authorize {
+		return nil
+	}
+	for _, x := range []struct {
+		rule   string
+		policy string
+	}{
+		{ImpossibleTravel, cfg.Risk.ImpossibleTravel.Policy},
+		{NewCountry, cfg.Risk.NewCountry.Policy},
+		{SharedIP, cfg.Risk.SharedIP.Policy},
+		{HostingNetwork, cfg.Risk.HostingNetwork.Policy},
+	} {
+		if parsePolicy(x.policy) == auth.RiskVerify {
+			return erron.Throwf("policy verify of %s requires challenge.authorize", x.rule)
+		}
+	}
+	return nil
+}
+
+func parsePolicy(policy string) auth.RiskDecision {
+	switch policy {
+	case "verify":
+		return auth.RiskVerify
+	case "deny":
+		return auth.RiskDeny
+	default:
+		return auth.RiskAllow
+	}
+}
+
+func (mod *riskModule) Evaluate(account auth.Account, ip string) (auth.Risk, error) {
+	var (
+		risk auth.Risk
+		cfg  = mod.service.Config().Risk
+		now  = time.Now()
+	)
+	violate := func(rule string, policy string) {
+		if decision := parsePolicy(policy); decision != auth.RiskAllow {
+			risk.Rules = append(risk.Rules, rule)
+			if decision > risk.Decision {
+				risk.Decision = decision
+			}
+		}
+	}
+
+	pos, err := mod.service.GeoModule().QueryPosition(ip)
+	if err != nil {
+		mod.Logger().Debug().
+			String("ip", ip).
+			Error("error", err).
+			Print("query position error")
+	}
+	uid := account.GetID()
+
+	if err == nil && parsePolicy(cfg.ImpossibleTravel.Policy) != auth.RiskAllow {
+		last, err := mod.lastLogin(uid)
+		if err != nil {
+			return risk, err
+		}
+		if last != nil && last.Country != "" && cfg.ImpossibleTravel.MaxSpeed > 0 {
+			km := distance(last.Latitude, last.Longitude, pos.Latitude, pos.Longitude)
+			hours := now.Sub(last.LoginAt).Hours()
+			if km/math.Max(hours, 1.0/60) > cfg.ImpossibleTravel.MaxSpeed {
+				violate(ImpossibleTravel, cfg.ImpossibleTravel.Policy)
+			}
+		}
+	}
+
+	if err == nil && pos.Country != "" && parsePolicy(cfg.NewCountry.Policy) != auth.RiskAllow {
+		byUid := auth.Field{Name: "uid", Value: strconv.FormatInt(uid, 10)}
+		if found, err := mod.service.OOSModule().HasObject(tableName, byUid); err != nil {
+			return risk, err
+		} else if found {
+			if found, err = mod.service.OOSModule().HasObject(tableName, byUid, auth.Field{
+				Name:  "country",
+				Value: pos.Country,
+			}); err != nil {
+				return risk, err
+			} else if !found {
+				violate(NewCountry, cfg.NewCountry.Policy)
+			}
+		}
+	}
+
+	if parsePolicy(cfg.SharedIP.Policy) != auth.RiskAllow && cfg.SharedIP.MaxAccounts > 0 {
+		var count int64
+		since := now.Add(-time.Duration(cfg.SharedIP.Window) * time.Second)
+		if err := mod.service.OOSModule().Query(&count,
+			"SELECT COUNT(DISTINCT `uid`) FROM `"+tableName+"` WHERE `ip` = ? AND `login_at` >= ? AND `uid` <> ?",
+			ip, since, uid,
+		); err != nil {
+			return risk, err
+		}
+		if count >= int64(cfg.SharedIP.MaxAccounts) {
+			violate(SharedIP, cfg.SharedIP.Policy)
+		}
+	}
+
+	if parsePolicy(cfg.HostingNetwork.Policy) != auth.RiskAllow {
+		if network, err := mod.service.GeoModule().QueryNetwork(ip); err == nil && network.Hosting {
+			violate(HostingNetwork, cfg.HostingNetwork.Policy)
+		}
+	}
+	return risk, nil
+}
+
+func (mod *riskModule) lastLogin(uid int64) (*loginHistory, error) {
+	var records []loginHistory
+	if err := mod.service.OOSModule().Query(&records,
+		"SELECT * FROM `"+tableName+"` WHERE `uid` = ? ORDER BY `login_at` DESC LIMIT 1",
+		uid,
+	); err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	return &records[0], nil
+}
+
+func (mod *riskModule) Record(account auth.Account, ip string) error {
+	record := &loginHistory{
+		Uid:     account.GetID(),
+		IP:      ip,
+		LoginAt: time.Now(),
+	}
+	if pos, err := mod.service.GeoModule().QueryPosition(ip); err == nil {
+		record.Country = pos.Country
+		record.Latitude = pos.Latitude
+		record.Longitude = pos.Longitude
+	}
+	return mod.service.OOSModule().InsertObject(record)
+}
+
+// distance returns great-circle distance in kilometers between two points
+func distance(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadius = 6371.0
+	const rad = math.Pi / 180
+	dlat := (lat2 - lat1) * rad
+	dlon := (lon2 - lon1) * rad
+	a := math.Sin(dlat/2)*math.Sin(dlat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dlon/2)*math.Sin(dlon/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
@@ -0,0 +1,40 @@
+package risk
+
+import (
+	"testing"
+
+	"github.com/gopherd/gopherd/auth"
+	"github.com/gopherd/gopherd/auth/config"
+)
+
+type testService struct {
+	Service
+	config *config.Config
+}
+
+func (s *testService) Config() *config.Config { return s.config }
+
+func TestCheckPolicies(t *testing.T) {
+	for _, tc := range []struct {
+		policy    string
+		challenge string
+		authorize bool
+		ok        bool
+	}{
+		{"allow", "", false, true},
+		{"deny", "", false, true},
+		{"verify", "", false, false},
+		{"verify", auth.ChallengePoW, false, false},
+		{"verify", "", true, false},
+		{"verify", auth.ChallengePoW, true, true},
+	} {
+		cfg := new(config.Config).Default().(*config.Config)
+		cfg.Risk.NewCountry.Policy = tc.policy
+		cfg.Challenge.Type = tc.challenge
+		cfg.Challenge.Authorize = tc.authorize
+		mod := newRiskModule(&testService{config: cfg})
+		if err := mod.checkPolicies(); (err == nil) != tc.ok {
+			t.Fatalf("policy %q challenge %q authorize %v: want ok %v, got error %v", tc.policy, tc.challenge, tc.authorize, tc.ok, err)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package role
+
+import (
+	"time"
+)
+
+const tableName = "account_role"
+
+type accountRole struct {
+	ID        int64     `gorm:"primaryKey;column:id"`
+	Uid       int64     `gorm:"uniqueIndex:idx_uid_role;column:uid;not null"`
+	Role      string    `gorm:"uniqueIndex:idx_uid_role;column:role;size:64;not null"`
+	GrantedAt time.Time `gorm:"column:granted_at"`
+}
+
+func (*accountRole) TableName() string { return tableName }
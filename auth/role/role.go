@@ -0,0 +1,142 @@
+package role
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gopherd/doge/erron"
+	"github.com/gopherd/doge/service/module"
+
+	"github.com/gopherd/gopherd/auth"
+	"github.com/gopherd/gopherd/auth/api"
+	"github.com/gopherd/gopherd/auth/config"
+	"github.com/gopherd/gopherd/auth/scope"
+)
+
+const (
+	fieldUid  = "uid"
+	fieldRole = "role"
+)
+
+type Service interface {
+	Config() *config.Config
+	OOSModule() auth.OOSModule
+}
+
+// New creates an auth.RoleModule
+func New(service Service) interface {
+	module.Module
+	auth.RoleModule
+} {
+	return newRoleModule(service)
+}
+
+// roleModule implements auth.RoleModule
+type roleModule struct {
+	*module.BasicModule
+	service Service
+}
+
+func newRoleModule(service Service) *roleModule {
+	return &roleModule{
+		BasicModule: module.NewBasicModule("role"),
+		service:     service,
+	}
+}
+
+func (mod *roleModule) Init() error {
+	if err := mod.BasicModule.Init(); err != nil {
+		return err
+	}
+	return mod.service.OOSModule().CreateSchema(new(accountRole))
+}
+
+func (mod *roleModule) find(name string) *config.Role {
+	roles := mod.service.Config().Roles
+	for i := range roles {
+		if roles[i].Name == name {
+			return &roles[i]
+		}
+	}
+	return nil
+}
+
+// Grants implements auth.RoleModule Grants method
+func (mod *roleModule) Grants(uid int64) (*auth.Grants, error) {
+	var rows []*accountRole
+	if err := mod.service.OOSModule().Query(&rows,
+		"SELECT * FROM `"+tableName+"` WHERE `uid` = ? ORDER BY `id`", uid); err != nil {
+		return nil, err
+	}
+	grants := new(auth.Grants)
+	permissions := make(map[string]bool)
+	for _, row := range rows {
+		role := mod.find(row.Role)
+		if role == nil {
+			continue
+		}
+		grants.Roles = append(grants.Roles, role.Name)
+		s, _ := scope.Parse(role.Scope)
+		grants.Scope |= s
+		for _, p := range role.Permissions {
+			if !permissions[p] {
+				permissions[p] = true
+				grants.Permissions = append(grants.Permissions, p)
+			}
+		}
+	}
+	sort.Strings(grants.Permissions)
+	return grants, nil
+}
+
+func by(uid int64, role string) []auth.Field {
+	return []auth.Field{
+		{Name: fieldUid, Value: strconv.FormatInt(uid, 10)},
+		{Name: fieldRole, Value: role},
+	}
+}
+
+// Grant implements auth.RoleModule Grant method
+func (mod *roleModule) Grant(uid int64, role string) (bool, error) {
+	if mod.find(role) == nil {
+		return false, erron.Errnof(api.BadArgument, "unknown role: %s", role)
+	}
+	oos := mod.service.OOSModule()
+	found, err := oos.HasObject(tableName, by(uid, role)...)
+	if err != nil || found {
+		return false, err
+	}
+	if err := oos.InsertObject(&accountRole{
+		Uid:       uid,
+		Role:      role,
+		GrantedAt: time.Now(),
+	}); err != nil {
+		// granted concurrently
+		if errors.Is(err, auth.ErrDuplicateKey) {
+			return false, nil
+		}
+		return false, err
+	}
+	mod.Logger().Info().
+		Int64("uid", uid).
+		String("role", role).
+		Print("role granted")
+	return true, nil
+}
+
+// Revoke implements auth.RoleModule Revoke method
+func (mod *roleModule) Revoke(uid int64, role string) (bool, error) {
+	n, err := mod.service.OOSModule().DeleteObjects(new(accountRole), by(uid, role)...)
+	if err != nil {
+		return false, err
+	}
+	if n > 0 {
+		mod.Logger().Info().
+			Int64("uid", uid).
+			String("role", role).
+			Print("role revoked")
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,103 @@
+package role
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/gopherd/gopherd/auth"
+	"github.com/gopherd/gopherd/auth/config"
+	"github.com/gopherd/gopherd/auth/scope"
+)
+
+// testOOS stores roles of accounts in memory, inserts are rejected by
+// duplicate key errors if dup is set, as if granted concurrently
+type testOOS struct {
+	auth.OOSModule
+	rows []*accountRole
+	dup  bool
+}
+
+func (oos *testOOS) Query(dst any, sql string, args ...any) error {
+	*dst.(*[]*accountRole) = oos.rows
+	return nil
+}
+
+func (oos *testOOS) HasObject(tableName string, by ...auth.Field) (bool, error) {
+	return false, nil
+}
+
+func (oos *testOOS) InsertObject(obj auth.Object) error {
+	if oos.dup {
+		return fmt.Errorf("%w: uid and role", auth.ErrDuplicateKey)
+	}
+	oos.rows = append(oos.rows, obj.(*accountRole))
+	return nil
+}
+
+type testService struct {
+	config *config.Config
+	oos    *testOOS
+}
+
+func (s *testService) Config() *config.Config    { return s.config }
+func (s *testService) OOSModule() auth.OOSModule { return s.oos }
+
+func newTestModule(rows ...string) (*roleModule, *testOOS) {
+	cfg := new(config.Config).Default().(*config.Config)
+	cfg.Roles = []config.Role{
+		{Name: "gm", Permissions: []string{"gm.kick", "gm.item"}, Scope: "admin"},
+		{Name: "support", Permissions: []string{"gm.kick", "support.ticket"}, Scope: "readonly"},
+		{Name: "tester", Scope: "game"},
+	}
+	oos := new(testOOS)
+	for _, role := range rows {
+		oos.rows = append(oos.rows, &accountRole{Uid: 1, Role: role})
+	}
+	return newRoleModule(&testService{config: cfg, oos: oos}), oos
+}
+
+func TestGrants(t *testing.T) {
+	for _, tc := range []struct {
+		rows        []string
+		roles       []string
+		permissions []string
+		scope       scope.Scope
+	}{
+		{nil, nil, nil, scope.None},
+		{[]string{"tester"}, []string{"tester"}, nil, scope.Game},
+		{[]string{"support", "gm"}, []string{"support", "gm"}, []string{"gm.item", "gm.kick", "support.ticket"}, scope.Admin | scope.ReadOnly},
+		{[]string{"removed", "gm"}, []string{"gm"}, []string{"gm.item", "gm.kick"}, scope.Admin},
+	} {
+		mod, _ := newTestModule(tc.rows...)
+		grants, err := mod.Grants(1)
+		if err != nil {
+			t.Fatalf("rows %v: grants error %v", tc.rows, err)
+		}
+		if !reflect.DeepEqual(grants.Roles, tc.roles) ||
+			!reflect.DeepEqual(grants.Permissions, tc.permissions) ||
+			grants.Scope != tc.scope {
+			t.Fatalf("rows %v: want %v %v %q, got %v %v %q", tc.rows, tc.roles, tc.permissions, tc.scope, grants.Roles, grants.Permissions, grants.Scope)
+		}
+	}
+}
+
+func TestGrant(t *testing.T) {
+	for _, tc := range []struct {
+		role    string
+		dup     bool
+		granted bool
+		ok      bool
+	}{
+		{"gm", false, true, true},
+		{"gm", true, false, true},
+		{"unknown", false, false, false},
+	} {
+		mod, oos := newTestModule()
+		oos.dup = tc.dup
+		granted, err := mod.Grant(1, tc.role)
+		if granted != tc.granted || (err == nil) != tc.ok {
+			t.Fatalf("role %q dup %v: want %v, ok %v, got %v, error %v", tc.role, tc.dup, tc.granted, tc.ok, granted, err)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package scope
+
+import (
+	"fmt"
+)
+
+// Range represents scopes required by message types in [From, To]
+type Range struct {
+	From  uint32 `json:"from"`
+	To    uint32 `json:"to"`
+	Scope string `json:"scope"` // space-separated scopes, any of them is required
+}
+
+// Policy determines scopes required by message types
+type Policy struct {
+	ranges []compiledRange
+	dft    Scope
+}
+
+type compiledRange struct {
+	from, to uint32
+	scope    Scope
+}
+
+// NewPolicy creates a policy by ranges and default scopes required by
+// message types out of ranges. The first matched range wins.
+func NewPolicy(ranges []Range, dft string) (*Policy, error) {
+	p := new(Policy)
+	var unknown []string
+	if p.dft, unknown = Parse(dft); len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown scopes %q", unknown)
+	}
+	for _, r := range ranges {
+		if r.From > r.To {
+			return nil, fmt.Errorf("invalid range [%d, %d]", r.From, r.To)
+		}
+		s, unknown := Parse(r.Scope)
+		if len(unknown) > 0 {
+			return nil, fmt.Errorf("unknown scopes %q of range [%d, %d]", unknown, r.From, r.To)
+		}
+		p.ranges = append(p.ranges, compiledRange{from: r.From, to: r.To, scope: s})
+	}
+	return p, nil
+}
+
+// Required returns scopes required by the message type, any of them is required.
+// None returned if no scope required.
+func (p *Policy) Required(typ uint32) Scope {
+	for _, r := range p.ranges {
+		if typ >= r.from && typ <= r.to {
+			return r.scope
+		}
+	}
+	return p.dft
+}
+
+// Allowed reports whether the granted scope allows the message type
+func (p *Policy) Allowed(granted Scope, typ uint32) bool {
+	required := p.Required(typ)
+	return required == None || granted.Any(required)
+}
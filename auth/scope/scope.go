@@ -0,0 +1,94 @@
+// Package scope implements scopes of access tokens. Scopes are carried by
+// jwt.Payload.Scope as a space-separated list, e.g. "game chat".
+package scope
+
+import (
+	"strings"
+)
+
+// Scope represents a set of scopes
+type Scope uint32
+
+const (
+	Game     Scope = 1 << iota // play games via gated
+	Chat                       // send chat messages via gated
+	Admin                      // GM and admin commands
+	ReadOnly                   // read-only access, e.g. web portals
+	Service                    // service-to-service calls
+
+	None Scope = 0
+	All        = Game | Chat | Admin | ReadOnly | Service
+)
+
+// Wildcard is the scope of tokens issued before scopes introduced, it's
+// parsed as Player
+const Wildcard = "*"
+
+// Player represents scopes of players, which are granted to tokens with
+// legacy scope Wildcard
+const Player = Game | Chat | ReadOnly
+
+var names = []struct {
+	scope Scope
+	name  string
+}{
+	{Game, "game"},
+	{Chat, "chat"},
+	{Admin, "admin"},
+	{ReadOnly, "readonly"},
+	{Service, "service"},
+}
+
+// Parse parses a space-separated list of scopes, unknown scopes are returned
+// as the second result
+func Parse(s string) (Scope, []string) {
+	var (
+		scope   Scope
+		unknown []string
+	)
+	for _, name := range strings.Fields(s) {
+		if name == Wildcard {
+			scope |= Player
+			continue
+		}
+		if x := lookup(name); x != None {
+			scope |= x
+		} else {
+			unknown = append(unknown, name)
+		}
+	}
+	return scope, unknown
+}
+
+func lookup(name string) Scope {
+	for _, x := range names {
+		if x.name == name {
+			return x.scope
+		}
+	}
+	return None
+}
+
+// String returns the space-separated list of scopes
+func (scope Scope) String() string {
+	var sb strings.Builder
+	for _, x := range names {
+		if scope&x.scope != 0 {
+			if sb.Len() > 0 {
+				sb.WriteByte(' ')
+			}
+			sb.WriteString(x.name)
+		}
+	}
+	return sb.String()
+}
+
+// Has reports whether scope contains all scopes of x
+func (scope Scope) Has(x Scope) bool {
+	return scope&x == x
+}
+
+// Any reports whether scope contains any scope of x
+func (scope Scope) Any(x Scope) bool {
+	return scope&x != 0
+}
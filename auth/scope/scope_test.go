@@ -0,0 +1,68 @@
+package scope
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		s       string
+		scope   Scope
+		unknown int
+		str     string
+	}{
+		{"", None, 0, ""},
+		{"game", Game, 0, "game"},
+		{"chat  game", Game | Chat, 0, "game chat"},
+		{"*", Player, 0, "game chat readonly"},
+		{"service admin game chat readonly", All, 0, "game chat admin readonly service"},
+		{"readonly pay", ReadOnly, 1, "readonly"},
+	} {
+		scope, unknown := Parse(tc.s)
+		if scope != tc.scope || len(unknown) != tc.unknown {
+			t.Fatalf("parse %q: want %v, %d unknown, got %v, %q", tc.s, tc.scope, tc.unknown, scope, unknown)
+		}
+		if scope.String() != tc.str {
+			t.Fatalf("%q: want string %q, got %q", tc.s, tc.str, scope.String())
+		}
+	}
+	if !(Game | Chat).Has(Game) || (Game).Has(Game|Chat) || !(Game).Any(Game|Chat) {
+		t.Fatal("unexpected Has or Any result")
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	p, err := NewPolicy([]Range{
+		{From: 1000, To: 1999, Scope: "chat"},
+		{From: 9000, To: 9999, Scope: "admin"},
+		{From: 5000, To: 5999, Scope: "game readonly"},
+		{From: 200, To: 299},
+	}, "game")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		granted Scope
+		typ     uint32
+		allowed bool
+	}{
+		{Game, 100, true},
+		{ReadOnly, 100, false},
+		{ReadOnly, 5500, true},
+		{Game, 1500, false},
+		{Chat, 1500, true},
+		{Game | Chat, 9000, false},
+		{Admin, 9999, true},
+		{None, 250, true},
+	} {
+		if got := p.Allowed(tc.granted, tc.typ); got != tc.allowed {
+			t.Fatalf("granted %q, type %d: want %v, got %v", tc.granted, tc.typ, tc.allowed, got)
+		}
+	}
+	if _, err := NewPolicy(nil, "play"); err == nil {
+		t.Fatal("unknown scope: error expected")
+	}
+	if _, err := NewPolicy([]Range{{From: 2, To: 1}}, ""); err == nil {
+		t.Fatal("invalid range: error expected")
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"net"
 	"net/http"
+	"net/url"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -11,18 +13,33 @@ import (
 
 	"github.com/gopherd/doge/erron"
 	"github.com/gopherd/doge/net/httputil"
+	"github.com/gopherd/doge/net/netutil"
 	"github.com/gopherd/doge/service"
-	"github.com/gopherd/jwt"
 	"github.com/gopherd/log"
 
 	"github.com/gopherd/gopherd/auth"
 	"github.com/gopherd/gopherd/auth/account"
+	"github.com/gopherd/gopherd/auth/api"
+	"github.com/gopherd/gopherd/auth/audit"
+	"github.com/gopherd/gopherd/auth/avatar"
+	"github.com/gopherd/gopherd/auth/challenge"
+	"github.com/gopherd/gopherd/auth/client"
 	"github.com/gopherd/gopherd/auth/config"
+	"github.com/gopherd/gopherd/auth/cors"
+	"github.com/gopherd/gopherd/auth/event"
 	"github.com/gopherd/gopherd/auth/geo"
 	"github.com/gopherd/gopherd/auth/handler"
+	"github.com/gopherd/gopherd/auth/keyring"
+	"github.com/gopherd/gopherd/auth/limiter"
+	"github.com/gopherd/gopherd/auth/metrics"
 	"github.com/gopherd/gopherd/auth/oos"
 	"github.com/gopherd/gopherd/auth/provider"
+	"github.com/gopherd/gopherd/auth/risk"
+	"github.com/gopherd/gopherd/auth/role"
+	"github.com/gopherd/gopherd/auth/session"
 	"github.com/gopherd/gopherd/auth/sms"
+	"github.com/gopherd/gopherd/auth/twofactor"
+	"github.com/gopherd/gopherd/auth/webauthn"
 )
 
 type server struct {
@@ -35,12 +52,23 @@ type server struct {
 		listener net.Listener
 		server   *httputil.HTTPServer
 	}
-	signer  *jwt.Signer
+	signer  *keyring.Signer
 	modules struct {
-		oos     auth.OOSModule
-		account auth.AccountModule
-		sms     auth.SMSModule
-		geo     auth.GeoModule
+		oos       auth.OOSModule
+		account   auth.AccountModule
+		sms       auth.SMSModule
+		geo       auth.GeoModule
+		risk      auth.RiskModule
+		event     auth.EventModule
+		avatar    auth.AvatarModule
+		twofa     auth.TwoFactorModule
+		webauthn  auth.WebAuthnModule
+		session   auth.SessionModule
+		client    auth.ClientModule
+		role      auth.RoleModule
+		audit     auth.AuditModule
+		limiter   auth.RateLimitModule
+		challenge auth.ChallengeModule
 	}
 
 	providersMu sync.RWMutex
@@ -66,6 +94,17 @@ func New(cfg *config.Config) service.Service {
 	s.modules.account = s.AddModule(account.New(s)).(auth.AccountModule)
 	s.modules.sms = s.AddModule(sms.New(s)).(auth.SMSModule)
 	s.modules.geo = s.AddModule(geo.New(s)).(auth.GeoModule)
+	s.modules.risk = s.AddModule(risk.New(s)).(auth.RiskModule)
+	s.modules.event = s.AddModule(event.New(s)).(auth.EventModule)
+	s.modules.avatar = s.AddModule(avatar.New(s)).(auth.AvatarModule)
+	s.modules.twofa = s.AddModule(twofactor.New(s)).(auth.TwoFactorModule)
+	s.modules.webauthn = s.AddModule(webauthn.New(s)).(auth.WebAuthnModule)
+	s.modules.session = s.AddModule(session.New(s)).(auth.SessionModule)
+	s.modules.client = s.AddModule(client.New(s)).(auth.ClientModule)
+	s.modules.role = s.AddModule(role.New(s)).(auth.RoleModule)
+	s.modules.audit = s.AddModule(audit.New(s)).(auth.AuditModule)
+	s.modules.limiter = s.AddModule(limiter.New(s)).(auth.RateLimitModule)
+	s.modules.challenge = s.AddModule(challenge.New(s)).(auth.ChallengeModule)
 	return s
 }
 
@@ -86,9 +125,20 @@ func (s *server) Init() error {
 		return erron.Throw(err)
 	}
 	cfg := s.Config()
-	s.signer, err = jwt.NewSigner(cfg.JWT.Filename, cfg.JWT.KeyId)
+	keys := cfg.JWT.Keys
+	if len(keys) == 0 {
+		keys = []keyring.Key{{Filename: cfg.JWT.Filename, KeyId: cfg.JWT.KeyId}}
+	}
+	active := cfg.JWT.ActiveKeyId
+	if active == "" {
+		active = cfg.JWT.KeyId
+	}
+	s.signer, err = keyring.NewSigner(keys, active)
 	if err != nil {
-		return erron.Throwf("new signer from file %q error %w", cfg.JWT.Filename, err)
+		return erron.Throwf("new signer error %w", err)
+	}
+	for lang, messages := range cfg.Messages {
+		api.AddMessages(lang, messages)
 	}
 
 	s.http.server = httputil.NewHTTPServer(cfg.HTTP)
@@ -125,17 +175,98 @@ func or(x, y string) string {
 
 func (s *server) registerHTTPHandlers() {
 	routers := s.Config().Routers
-	s.handleFunc(or(routers.Authorize, "/auth/authorize"), handler.Authorize)
-	s.handleFunc(or(routers.Link, "/auth/link"), handler.Link)
-	s.handleFunc(or(routers.SMSCode, "/auth/smscode"), handler.SMSCode)
+	s.handleFunc(routers.Authorize, api.RouteAuthorize, handler.Authorize)
+	s.handleFunc(routers.Link, api.RouteLink, handler.Link)
+	s.handleFunc(routers.SMSCode, api.RouteSmsCode, handler.SMSCode)
+	s.handleFunc(routers.Profile, api.RouteProfile, handler.Profile)
+	s.handleFunc(routers.TwoFactorEnroll, api.RouteTwoFactorEnroll, handler.TwoFactorEnroll)
+	s.handleFunc(routers.TwoFactorActivate, api.RouteTwoFactorActivate, handler.TwoFactorActivate)
+	s.handleFunc(routers.TwoFactorDisable, api.RouteTwoFactorDisable, handler.TwoFactorDisable)
+	s.handleFunc(routers.Verify2FA, api.RouteVerify2fa, handler.Verify2FA)
+	s.handleFunc(routers.WebAuthnRegister, api.RouteWebAuthnRegister, handler.WebAuthnRegister)
+	s.handleFunc(routers.WebAuthnLogin, api.RouteWebAuthnLogin, handler.WebAuthnLogin)
+	s.handleFunc(routers.Refresh, api.RouteRefresh, handler.Refresh)
+	s.handleFunc(routers.Sessions, api.RouteSessions, handler.Sessions)
+	s.handleFunc(routers.RevokeSession, api.RouteRevokeSession, handler.RevokeSession)
+	s.handleFunc(routers.Token, api.RouteToken, handler.Token)
+	s.handleFunc(routers.Introspect, api.RouteIntrospect, handler.Introspect)
+	s.handleFunc(routers.Userinfo, api.RouteUserinfo, handler.Userinfo)
+	s.handleFunc(routers.JWKS, api.RouteJWKS, handler.JWKS)
+	s.handleFunc(routers.OpenAPI, api.RouteOpenAPI, handler.OpenAPI)
+	s.handleFunc(routers.RegistrationReport, api.RouteRegistrationReport, handler.RegistrationReport)
+	s.handleFunc(routers.Roles, api.RouteRoles, handler.Roles)
+	s.handleFunc(routers.GrantRole, api.RouteGrantRole, handler.GrantRole)
+	s.handleFunc(routers.RevokeRole, api.RouteRevokeRole, handler.RevokeRole)
+	s.handleFunc(routers.Impersonate, api.RouteImpersonate, handler.Impersonate)
+	// metrics are scraped periodically, so they aren't rate limited or counted
+	s.http.server.HandleFunc(or(routers.Metrics, api.RouteMetrics), func(w http.ResponseWriter, r *http.Request) {
+		handler.Metrics(s, api.NegotiateResponseWriter(w, r), r)
+	})
 }
 
-func (s *server) handleFunc(pattern string, h func(auth.Service, http.ResponseWriter, *http.Request)) {
-	s.http.server.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+// handleFunc registers the handler at the configured route, or the default
+// route if not configured. CORS policy of the default route is applied.
+func (s *server) handleFunc(route, dft string, h func(auth.Service, http.ResponseWriter, *http.Request)) {
+	s.http.server.HandleFunc(or(route, dft), func(w http.ResponseWriter, r *http.Request) {
+		if policy := s.corsPolicy(dft); policy.Enabled() && policy.Handle(w, r) {
+			return
+		}
+		start := time.Now()
+		w = api.NegotiateResponseWriter(w, r)
+		defer func() {
+			metrics.Requests.WithLabelValues(dft, metrics.Result(api.ResponseErrno(w))).Inc()
+			metrics.RequestDuration.WithLabelValues(dft).Observe(metrics.Since(start))
+		}()
+		if !s.allow(dft, w, r) {
+			return
+		}
 		h(s, w, r)
 	})
 }
 
+func (s *server) corsPolicy(route string) *cors.Policy {
+	cfg := s.Config()
+	if policy, ok := cfg.CORS.Routes[route]; ok {
+		return &policy
+	}
+	return &cfg.CORS.Default
+}
+
+// allow limits rates of requests of the default route, TooManyRequests is
+// responded if the request is rate limited
+func (s *server) allow(route string, w http.ResponseWriter, r *http.Request) bool {
+	var form url.Values
+	var parsed bool
+	retryAfter := s.modules.limiter.Limit(r.Context(), route, func(by string) string {
+		if by == "ip" {
+			return netutil.IP(r)
+		}
+		if !parsed {
+			parsed = true
+			// errors are reported by handlers
+			form, _ = api.ParseRoute(r, route)
+		}
+		return form.Get(by)
+	})
+	if retryAfter <= 0 {
+		return true
+	}
+	seconds := int((retryAfter + time.Second - 1) / time.Second)
+	s.Logger().Info().
+		String("route", route).
+		String("ip", netutil.IP(r)).
+		Int("retry_after", seconds).
+		Print("rate limited")
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	api.Response(w, &api.RateLimitedResponse{
+		Error:       api.TooManyRequests,
+		Description: "too many requests",
+		RetryAfter:  seconds,
+		Message:     api.Localize(w, api.TooManyRequests),
+	})
+	return false
+}
+
 func (s *server) shutdownHTTPServer() {
 	if s.http.server != nil {
 		ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
@@ -202,6 +333,9 @@ func (s *server) getProvider(name string) (provider.Provider, bool) {
 }
 
 func (s *server) createProvider(name string) (provider.Provider, error) {
+	if name == webauthn.Name && s.modules.webauthn.Enabled() {
+		return s.modules.webauthn, nil
+	}
 	cfg := s.Config()
 	if cfg.Proviers == nil {
 		return nil, provider.ErrProviderNotFound
@@ -215,11 +349,22 @@ func (s *server) createProvider(name string) (provider.Provider, error) {
 	return provider.Open(name, source)
 }
 
-func (s *server) Signer() *jwt.Signer {
+func (s *server) Signer() auth.Signer {
 	return s.signer
 }
 
-func (s *server) OOSModule() auth.OOSModule         { return s.modules.oos }
-func (s *server) AccountModule() auth.AccountModule { return s.modules.account }
-func (s *server) SMSModule() auth.SMSModule         { return s.modules.sms }
-func (s *server) GeoModule() auth.GeoModule         { return s.modules.geo }
+func (s *server) OOSModule() auth.OOSModule             { return s.modules.oos }
+func (s *server) AccountModule() auth.AccountModule     { return s.modules.account }
+func (s *server) SMSModule() auth.SMSModule             { return s.modules.sms }
+func (s *server) GeoModule() auth.GeoModule             { return s.modules.geo }
+func (s *server) RiskModule() auth.RiskModule           { return s.modules.risk }
+func (s *server) EventModule() auth.EventModule         { return s.modules.event }
+func (s *server) AvatarModule() auth.AvatarModule       { return s.modules.avatar }
+func (s *server) TwoFactorModule() auth.TwoFactorModule { return s.modules.twofa }
+func (s *server) WebAuthnModule() auth.WebAuthnModule   { return s.modules.webauthn }
+func (s *server) SessionModule() auth.SessionModule     { return s.modules.session }
+func (s *server) ClientModule() auth.ClientModule       { return s.modules.client }
+func (s *server) RoleModule() auth.RoleModule           { return s.modules.role }
+func (s *server) AuditModule() auth.AuditModule         { return s.modules.audit }
+func (s *server) RateLimitModule() auth.RateLimitModule { return s.modules.limiter }
+func (s *server) ChallengeModule() auth.ChallengeModule { return s.modules.challenge }
@@ -0,0 +1,39 @@
+package session
+
+import (
+	"time"
+
+	"github.com/gopherd/gopherd/auth"
+)
+
+const tableName = "session"
+
+type session struct {
+	ID         string    `gorm:"primaryKey;column:id;size:32"`
+	Uid        int64     `gorm:"uniqueIndex:idx_uid_device;column:uid;not null"`
+	Device     string    `gorm:"uniqueIndex:idx_uid_device;column:device;size:191;not null"`
+	OS         string    `gorm:"column:os"`
+	Model      string    `gorm:"column:model"`
+	IP         string    `gorm:"column:ip"`
+	Salt       string    `gorm:"column:salt"` // salt of the latest refresh token
+	CreatedAt  time.Time `gorm:"column:created_at"`
+	LastUsedAt time.Time `gorm:"column:last_used_at"`
+	ExpiresAt  time.Time `gorm:"column:expires_at"`
+}
+
+func (*session) TableName() string { return tableName }
+
+func (s *session) toSession() *auth.Session {
+	return &auth.Session{
+		ID:         s.ID,
+		Uid:        s.Uid,
+		Device:     s.Device,
+		OS:         s.OS,
+		Model:      s.Model,
+		IP:         s.IP,
+		Salt:       s.Salt,
+		CreatedAt:  s.CreatedAt,
+		LastUsedAt: s.LastUsedAt,
+		ExpiresAt:  s.ExpiresAt,
+	}
+}
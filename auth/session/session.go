@@ -0,0 +1,313 @@
+package session
+
+import (
+	"context"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/gopherd/doge/crypto/cryptoutil"
+	"github.com/gopherd/doge/erron"
+	"github.com/gopherd/doge/mq"
+	"github.com/gopherd/doge/proto"
+	"github.com/gopherd/doge/service/discovery"
+	"github.com/gopherd/doge/service/module"
+
+	"github.com/gopherd/gopherd/auth"
+	"github.com/gopherd/gopherd/auth/api"
+	"github.com/gopherd/gopherd/auth/config"
+	"github.com/gopherd/gopherd/gate/frontend"
+	"github.com/gopherd/gopherd/proto/gatepb"
+)
+
+const (
+	fieldId     = "id"
+	fieldUid    = "uid"
+	fieldDevice = "device"
+
+	idLength   = 32
+	saltLength = 16
+)
+
+var (
+	errSessionNotFound = erron.Errnof(api.Unauthorized, "session not found")
+	errTokenReused     = erron.Errnof(api.Unauthorized, "refresh token reused")
+)
+
+type Service interface {
+	Config() *config.Config
+	OOSModule() auth.OOSModule
+	Discovery() discovery.Discovery
+	MQ() mq.Conn
+}
+
+// New creates an auth.SessionModule
+func New(service Service) interface {
+	module.Module
+	auth.SessionModule
+} {
+	return newSessionModule(service)
+}
+
+// sessionModule implements auth.SessionModule
+type sessionModule struct {
+	*module.BasicModule
+	service Service
+}
+
+func newSessionModule(service Service) *sessionModule {
+	return &sessionModule{
+		BasicModule: module.NewBasicModule("session"),
+		service:     service,
+	}
+}
+
+func (mod *sessionModule) Init() error {
+	if err := mod.BasicModule.Init(); err != nil {
+		return err
+	}
+	return mod.service.OOSModule().CreateSchema(new(session))
+}
+
+func (mod *sessionModule) ttl() time.Duration {
+	return time.Duration(mod.service.Config().RefreshTokenTTL) * time.Second
+}
+
+// Open implements auth.SessionModule Open method
+func (mod *sessionModule) Open(uid int64, device, os, model, ip string) (*auth.Session, error) {
+	oos := mod.service.OOSModule()
+	now := time.Now()
+	s := new(session)
+	found, err := oos.GetObject(s,
+		auth.Field{Name: fieldUid, Value: strconv.FormatInt(uid, 10)},
+		auth.Field{Name: fieldDevice, Value: device},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if found && s.ExpiresAt.Before(now) {
+		// expired session is reopened as a new session
+		if _, err := oos.DeleteObjects(s, auth.Field{Name: fieldId, Value: s.ID}); err != nil {
+			return nil, err
+		}
+		found = false
+	}
+	if found {
+		s.OS = os
+		s.Model = model
+		s.IP = ip
+		s.Salt = cryptoutil.GenerateSalt(saltLength)
+		s.LastUsedAt = now
+		s.ExpiresAt = now.Add(mod.ttl())
+		if _, err := oos.UpdateObject(s, "os", "model", "ip", "salt", "last_used_at", "expires_at"); err != nil {
+			return nil, err
+		}
+	} else {
+		s = &session{
+			ID:         cryptoutil.GenerateSalt(idLength),
+			Uid:        uid,
+			Device:     device,
+			OS:         os,
+			Model:      model,
+			IP:         ip,
+			Salt:       cryptoutil.GenerateSalt(saltLength),
+			CreatedAt:  now,
+			LastUsedAt: now,
+			ExpiresAt:  now.Add(mod.ttl()),
+		}
+		if err := oos.InsertObject(s); err != nil {
+			return nil, err
+		}
+		mod.evict(uid)
+	}
+	return s.toSession(), nil
+}
+
+// evict revokes least recently used sessions if too many sessions
+func (mod *sessionModule) evict(uid int64) {
+	max := mod.service.Config().Session.MaxSessions
+	if max <= 0 {
+		return
+	}
+	sessions, err := mod.list(uid)
+	if err != nil {
+		mod.Logger().Warn().
+			Int64("uid", uid).
+			Error("error", err).
+			Print("list sessions error")
+		return
+	}
+	for i := max; i < len(sessions); i++ {
+		if _, err := mod.Revoke(uid, sessions[i].ID); err != nil {
+			mod.Logger().Warn().
+				Int64("uid", uid).
+				String("session", sessions[i].ID).
+				Error("error", err).
+				Print("revoke session error")
+		}
+	}
+}
+
+func (mod *sessionModule) load(uid int64, id string) (*session, error) {
+	s := new(session)
+	found, err := mod.service.OOSModule().GetObject(s, auth.Field{Name: fieldId, Value: id})
+	if err != nil {
+		return nil, err
+	}
+	if !found || s.Uid != uid || s.ExpiresAt.Before(time.Now()) {
+		return nil, nil
+	}
+	return s, nil
+}
+
+// Active implements auth.SessionModule Active method
+func (mod *sessionModule) Active(uid int64, id string) (bool, error) {
+	s, err := mod.load(uid, id)
+	return s != nil, err
+}
+
+// Refresh implements auth.SessionModule Refresh method
+func (mod *sessionModule) Refresh(uid int64, id, salt, ip string) (*auth.Session, error) {
+	s, err := mod.load(uid, id)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return nil, errSessionNotFound
+	}
+	if s.Salt != salt {
+		// the refresh token has been rotated, it may be stolen
+		mod.Logger().Warn().
+			Int64("uid", uid).
+			String("session", id).
+			String("ip", ip).
+			Print("rotated refresh token reused, revoke the session")
+		if _, err := mod.Revoke(uid, id); err != nil {
+			return nil, err
+		}
+		return nil, errTokenReused
+	}
+	now := time.Now()
+	s.IP = ip
+	s.Salt = cryptoutil.GenerateSalt(saltLength)
+	s.LastUsedAt = now
+	s.ExpiresAt = now.Add(mod.ttl())
+	if _, err := mod.service.OOSModule().UpdateObject(s, "ip", "salt", "last_used_at", "expires_at"); err != nil {
+		return nil, err
+	}
+	return s.toSession(), nil
+}
+
+func (mod *sessionModule) list(uid int64) ([]*session, error) {
+	var sessions []*session
+	err := mod.service.OOSModule().Query(&sessions,
+		"SELECT * FROM `"+tableName+"` WHERE `uid` = ? AND `expires_at` > ? ORDER BY `last_used_at` DESC",
+		uid, time.Now())
+	return sessions, err
+}
+
+// List implements auth.SessionModule List method
+func (mod *sessionModule) List(uid int64) ([]*auth.Session, error) {
+	sessions, err := mod.list(uid)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*auth.Session, len(sessions))
+	for i := range sessions {
+		result[i] = sessions[i].toSession()
+	}
+	return result, nil
+}
+
+// Revoke implements auth.SessionModule Revoke method
+func (mod *sessionModule) Revoke(uid int64, id string) (int64, error) {
+	by := []auth.Field{{Name: fieldUid, Value: strconv.FormatInt(uid, 10)}}
+	if id != "" {
+		by = append(by, auth.Field{Name: fieldId, Value: id})
+	}
+	n, err := mod.service.OOSModule().DeleteObjects(new(session), by...)
+	if err != nil {
+		return n, err
+	}
+	// access tokens of all sessions are revoked even if no session found, since
+	// tokens may be issued without session, e.g. impersonation tokens
+	if n > 0 || id == "" {
+		mod.Logger().Info().
+			Int64("uid", uid).
+			String("session", id).
+			Int64("revoked", n).
+			Print("sessions revoked")
+		mod.revokeTokens(uid, id)
+		mod.disconnect(uid, id)
+	}
+	return n, nil
+}
+
+// revokeTokens marks access tokens of the session, or all access tokens of
+// the user if id is empty, revoked in discovery until they expire, so gated
+// rejects them
+func (mod *sessionModule) revokeTokens(uid int64, id string) {
+	if mod.service.Discovery() == nil {
+		return
+	}
+	cfg := mod.service.Config()
+	ttl := cfg.AccessTokenTTL
+	if cfg.Impersonation.TTL > ttl {
+		ttl = cfg.Impersonation.TTL
+	}
+	key := frontend.RevokedKey(cfg.Core.Project, uid, id)
+	content := strconv.FormatInt(time.Now().Unix(), 10)
+	if err := mod.service.Discovery().Register(context.Background(), "", key, content, false, time.Duration(ttl)*time.Second); err != nil {
+		mod.Logger().Warn().
+			Int64("uid", uid).
+			String("session", id).
+			Error("error", err).
+			Print("revoke access tokens error")
+	}
+}
+
+// disconnect kicks out the gated session of the user if it's logged in by
+// the revoked session, or any session if id is empty
+func (mod *sessionModule) disconnect(uid int64, id string) {
+	if mod.service.Discovery() == nil || mod.service.MQ() == nil {
+		return
+	}
+	cfg := mod.service.Config()
+	content, err := mod.service.Discovery().Find(context.Background(), "", frontend.UserKey(cfg.Core.Project, uid))
+	if err != nil || content == "" {
+		return
+	}
+	user, err := frontend.ParseLoggedUser(content)
+	if err != nil {
+		mod.Logger().Warn().
+			Int64("uid", uid).
+			String("content", content).
+			Error("error", err).
+			Print("parse logged user error")
+		return
+	}
+	if id != "" && user.Session != id {
+		return
+	}
+	m := &gatepb.Kickout{
+		Uid:    uid,
+		Reason: int32(gatepb.KickoutReason_ReasonUserLogout),
+	}
+	buf := proto.AllocBuffer()
+	defer proto.FreeBuffer(buf)
+	if err := buf.Marshal(m); err != nil {
+		mod.Logger().Warn().
+			Int64("uid", uid).
+			Error("error", err).
+			Print("marshal kickout message error")
+		return
+	}
+	topic := path.Join(cfg.Session.Gated, strconv.FormatInt(user.GID, 10))
+	if err := mod.service.MQ().Publish(topic, buf.Bytes()); err != nil {
+		mod.Logger().Warn().
+			Int64("uid", uid).
+			String("topic", topic).
+			Error("error", err).
+			Print("publish kickout message error")
+	}
+}
@@ -0,0 +1,136 @@
+// Package totp implements time-based one-time passwords (RFC 6238) compatible
+// with authenticator apps, and recovery codes
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	Digits     = 6
+	Period     = 30 // seconds
+	SecretSize = 20 // bytes
+
+	recoveryCodeSize = 10 // characters
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret generates a random base32 encoded secret
+func GenerateSecret() (string, error) {
+	b := make([]byte, SecretSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(b), nil
+}
+
+// URI returns the otpauth uri of secret which is usually shown as QR code
+func URI(issuer, account, secret string) string {
+	values := url.Values{}
+	values.Set("secret", secret)
+	values.Set("issuer", issuer)
+	values.Set("algorithm", "SHA1")
+	values.Set("digits", strconv.Itoa(Digits))
+	values.Set("period", strconv.Itoa(Period))
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	return "otpauth://totp/" + label + "?" + values.Encode()
+}
+
+// Step returns the time step of t
+func Step(t time.Time) int64 {
+	return t.Unix() / Period
+}
+
+// Code returns the code of secret at time step
+func Code(secret string, step int64) (string, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return "", err
+	}
+	return hotp(key, uint64(step), Digits), nil
+}
+
+// hotp implements HOTP of RFC 4226
+func hotp(key []byte, counter uint64, digits int) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+	h := hmac.New(sha1.New, key)
+	h.Write(msg[:])
+	sum := h.Sum(nil)
+	offset := sum[len(sum)-1] & 0xf
+	value := binary.BigEndian.Uint32(sum[offset:]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	code := strconv.FormatUint(uint64(value%mod), 10)
+	return strings.Repeat("0", digits-len(code)) + code
+}
+
+// Validate validates code at time t allowing skew steps before and after,
+// it returns the matched time step
+func Validate(secret, code string, t time.Time, skew int) (int64, bool) {
+	if len(code) != Digits {
+		return 0, false
+	}
+	step := Step(t)
+	for i := -skew; i <= skew; i++ {
+		expected, err := Code(secret, step+int64(i))
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step + int64(i), true
+		}
+	}
+	return 0, false
+}
+
+// GenerateRecoveryCodes generates n random recovery codes formatted as xxxxx-xxxxx
+func GenerateRecoveryCodes(n int) ([]string, error) {
+	const (
+		alphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+		// random bytes not less than limit are rejected to avoid modulo bias
+		limit = 256 - 256%len(alphabet)
+	)
+	var (
+		codes = make([]string, n)
+		code  = make([]byte, 0, recoveryCodeSize)
+		b     = make([]byte, recoveryCodeSize*2)
+	)
+	for i := range codes {
+		code = code[:0]
+		for len(code) < recoveryCodeSize {
+			if _, err := rand.Read(b); err != nil {
+				return nil, err
+			}
+			for _, x := range b {
+				if int(x) < limit && len(code) < recoveryCodeSize {
+					code = append(code, alphabet[int(x)%len(alphabet)])
+				}
+			}
+		}
+		codes[i] = string(code[:recoveryCodeSize/2]) + "-" + string(code[recoveryCodeSize/2:])
+	}
+	return codes, nil
+}
+
+// HashRecoveryCode returns hash of recovery code to store, the code is
+// normalized so that case and dashes are ignored
+func HashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
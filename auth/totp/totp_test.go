@@ -0,0 +1,80 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// test vectors of RFC 6238 Appendix B, SHA1
+func TestHOTP(t *testing.T) {
+	key := []byte("12345678901234567890")
+	for _, tc := range []struct {
+		unix int64
+		code string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	} {
+		if got := hotp(key, uint64(tc.unix/Period), 8); got != tc.code {
+			t.Errorf("hotp at %d: want %s, got %s", tc.unix, tc.code, got)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	now := time.Unix(1111111109, 0)
+	code, err := Code(secret, Step(now))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if code != "081804" {
+		t.Fatalf("Code: want 081804, got %s", code)
+	}
+	if step, ok := Validate(secret, code, now.Add(Period*time.Second), 1); !ok || step != Step(now) {
+		t.Errorf("Validate with skew: want step %d, got %d, %v", Step(now), step, ok)
+	}
+	if _, ok := Validate(secret, code, now.Add(2*Period*time.Second), 1); ok {
+		t.Errorf("Validate out of skew: unexpected ok")
+	}
+	if _, ok := Validate(secret, "12345", now, 1); ok {
+		t.Errorf("Validate short code: unexpected ok")
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if _, err := Code(secret, 1); err != nil {
+		t.Errorf("Code with generated secret %q: %v", secret, err)
+	}
+	uri := URI("gopherd", "alice@example.com", secret)
+	if !strings.HasPrefix(uri, "otpauth://totp/gopherd:alice@example.com?") || !strings.Contains(uri, "secret="+secret) {
+		t.Errorf("URI: unexpected %q", uri)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' || seen[code] {
+			t.Errorf("GenerateRecoveryCodes: unexpected code %q", code)
+		}
+		seen[code] = true
+	}
+	if HashRecoveryCode(codes[0]) != HashRecoveryCode(" "+strings.ToUpper(strings.ReplaceAll(codes[0], "-", ""))) {
+		t.Errorf("HashRecoveryCode: normalization mismatched")
+	}
+}
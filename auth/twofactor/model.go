@@ -0,0 +1,20 @@
+package twofactor
+
+import (
+	"time"
+)
+
+const tableName = "two_factor"
+
+type twoFactor struct {
+	Uid           int64     `gorm:"primaryKey;autoIncrement:false;column:uid"`
+	Secret        string    `gorm:"column:secret;not null"`
+	Enabled       bool      `gorm:"column:enabled"`
+	RecoveryCodes string    `gorm:"column:recovery_codes;type:text"` // comma-separated hashes
+	LastStep      int64     `gorm:"column:last_step"`                // last used time step
+	Failures      int       `gorm:"column:failures"`
+	FailedAt      time.Time `gorm:"column:failed_at"`
+	CreatedAt     time.Time `gorm:"column:created_at"`
+}
+
+func (*twoFactor) TableName() string { return tableName }
@@ -0,0 +1,238 @@
+package twofactor
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gopherd/doge/erron"
+	"github.com/gopherd/doge/service/module"
+
+	"github.com/gopherd/gopherd/auth"
+	"github.com/gopherd/gopherd/auth/api"
+	"github.com/gopherd/gopherd/auth/config"
+	"github.com/gopherd/gopherd/auth/totp"
+)
+
+const fieldUid = "uid"
+
+var (
+	errNotEnabled     = erron.Errnof(api.BadArgument, "two-factor authentication not enabled")
+	errAlreadyEnabled = erron.Errnof(api.BadArgument, "two-factor authentication already enabled")
+	errNotEnrolled    = erron.Errnof(api.BadArgument, "two-factor authentication not enrolled")
+	errInvalidCode    = erron.Errnof(api.InvalidSecondFactor, "invalid code")
+	errTooManyFailure = erron.Errnof(api.InvalidSecondFactor, "too many failures, try again later")
+)
+
+type Service interface {
+	Config() *config.Config
+	OOSModule() auth.OOSModule
+}
+
+// New creates an auth.TwoFactorModule
+func New(service Service) interface {
+	module.Module
+	auth.TwoFactorModule
+} {
+	return newTwoFactorModule(service)
+}
+
+// twoFactorModule implements auth.TwoFactorModule
+type twoFactorModule struct {
+	*module.BasicModule
+	service Service
+
+	// redeemed holds expiry of redeemed challenge tokens keyed by salt, so
+	// they can't be replayed to this instance
+	redeemedMu sync.Mutex
+	redeemed   map[string]int64
+	lastSweep  int64
+}
+
+func newTwoFactorModule(service Service) *twoFactorModule {
+	return &twoFactorModule{
+		BasicModule: module.NewBasicModule("twofactor"),
+		service:     service,
+		redeemed:    make(map[string]int64),
+	}
+}
+
+func (mod *twoFactorModule) Init() error {
+	if err := mod.BasicModule.Init(); err != nil {
+		return err
+	}
+	return mod.service.OOSModule().CreateSchema(new(twoFactor))
+}
+
+func (mod *twoFactorModule) load(uid int64) (*twoFactor, error) {
+	tf := new(twoFactor)
+	found, err := mod.service.OOSModule().GetObject(tf, auth.Field{
+		Name:  fieldUid,
+		Value: strconv.FormatInt(uid, 10),
+	})
+	if err != nil || !found {
+		return nil, err
+	}
+	return tf, nil
+}
+
+func (mod *twoFactorModule) Enabled(uid int64) (bool, error) {
+	tf, err := mod.load(uid)
+	if err != nil {
+		return false, err
+	}
+	return tf != nil && tf.Enabled, nil
+}
+
+func (mod *twoFactorModule) Enroll(uid int64, label string) (secret, uri string, recoveryCodes []string, err error) {
+	cfg := mod.service.Config()
+	tf, err := mod.load(uid)
+	if err != nil {
+		return
+	}
+	if tf != nil && tf.Enabled {
+		err = errAlreadyEnabled
+		return
+	}
+	if secret, err = totp.GenerateSecret(); err != nil {
+		return
+	}
+	if recoveryCodes, err = totp.GenerateRecoveryCodes(cfg.TwoFactor.RecoveryCodes); err != nil {
+		return
+	}
+	hashes := make([]string, len(recoveryCodes))
+	for i, code := range recoveryCodes {
+		hashes[i] = totp.HashRecoveryCode(code)
+	}
+	issuer := cfg.TwoFactor.Issuer
+	if issuer == "" {
+		issuer = cfg.JWT.Issuer
+	}
+	uri = totp.URI(issuer, label, secret)
+	if tf == nil {
+		err = mod.service.OOSModule().InsertObject(&twoFactor{
+			Uid:           uid,
+			Secret:        secret,
+			RecoveryCodes: strings.Join(hashes, ","),
+			CreatedAt:     time.Now(),
+		})
+		return
+	}
+	tf.Secret = secret
+	tf.RecoveryCodes = strings.Join(hashes, ",")
+	tf.LastStep = 0
+	tf.Failures = 0
+	tf.CreatedAt = time.Now()
+	_, err = mod.service.OOSModule().UpdateObject(tf, "secret", "recovery_codes", "last_step", "failures", "created_at")
+	return
+}
+
+func (mod *twoFactorModule) Activate(uid int64, code string) error {
+	tf, err := mod.load(uid)
+	if err != nil {
+		return err
+	}
+	if tf == nil {
+		return errNotEnrolled
+	}
+	if tf.Enabled {
+		return errAlreadyEnabled
+	}
+	if err := mod.verify(tf, code, false); err != nil {
+		return err
+	}
+	tf.Enabled = true
+	_, err = mod.service.OOSModule().UpdateObject(tf, "enabled")
+	return err
+}
+
+func (mod *twoFactorModule) Disable(uid int64, code string) error {
+	tf, err := mod.load(uid)
+	if err != nil {
+		return err
+	}
+	if tf == nil || !tf.Enabled {
+		return errNotEnabled
+	}
+	if err := mod.verify(tf, code, true); err != nil {
+		return err
+	}
+	tf.Enabled = false
+	_, err = mod.service.OOSModule().UpdateObject(tf, "enabled")
+	return err
+}
+
+func (mod *twoFactorModule) Verify(uid int64, code string) error {
+	tf, err := mod.load(uid)
+	if err != nil {
+		return err
+	}
+	if tf == nil || !tf.Enabled {
+		return errNotEnabled
+	}
+	return mod.verify(tf, code, true)
+}
+
+// Redeem implements auth.TwoFactorModule Redeem method
+func (mod *twoFactorModule) Redeem(salt string, expiredAt int64) bool {
+	now := time.Now().Unix()
+	mod.redeemedMu.Lock()
+	defer mod.redeemedMu.Unlock()
+	if now-mod.lastSweep >= 60 {
+		mod.lastSweep = now
+		for k, t := range mod.redeemed {
+			if t < now {
+				delete(mod.redeemed, k)
+			}
+		}
+	}
+	if _, ok := mod.redeemed[salt]; ok {
+		return false
+	}
+	mod.redeemed[salt] = expiredAt
+	return true
+}
+
+// verify verifies TOTP code or recovery code if allowed. A TOTP code can not
+// be reused and a recovery code would be removed after used. Verification is
+// locked for a while after too many failures.
+func (mod *twoFactorModule) verify(tf *twoFactor, code string, allowRecovery bool) error {
+	var (
+		cfg  = mod.service.Config().TwoFactor
+		now  = time.Now()
+		lock = time.Duration(cfg.LockDuration) * time.Second
+		oos  = mod.service.OOSModule()
+	)
+	if tf.Failures >= cfg.MaxFailures && now.Sub(tf.FailedAt) < lock {
+		return errTooManyFailure
+	}
+	code = strings.TrimSpace(code)
+	if step, ok := totp.Validate(tf.Secret, code, now, cfg.Skew); ok && step > tf.LastStep {
+		tf.LastStep = step
+		tf.Failures = 0
+		_, err := oos.UpdateObject(tf, "last_step", "failures")
+		return err
+	}
+	if allowRecovery && tf.RecoveryCodes != "" {
+		hash := totp.HashRecoveryCode(code)
+		hashes := strings.Split(tf.RecoveryCodes, ",")
+		for i := range hashes {
+			if hashes[i] == hash {
+				tf.RecoveryCodes = strings.Join(append(hashes[:i], hashes[i+1:]...), ",")
+				tf.Failures = 0
+				_, err := oos.UpdateObject(tf, "recovery_codes", "failures")
+				return err
+			}
+		}
+	}
+	if now.Sub(tf.FailedAt) >= lock {
+		tf.Failures = 0
+	}
+	tf.Failures++
+	tf.FailedAt = now
+	if _, err := oos.UpdateObject(tf, "failures", "failed_at"); err != nil {
+		return err
+	}
+	return errInvalidCode
+}
@@ -0,0 +1,28 @@
+package twofactor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRedeem(t *testing.T) {
+	mod := newTwoFactorModule(nil)
+	expiredAt := time.Now().Add(time.Minute).Unix()
+	if !mod.Redeem("salt", expiredAt) {
+		t.Fatal("first redemption should succeed")
+	}
+	if mod.Redeem("salt", expiredAt) {
+		t.Fatal("token redeemed twice")
+	}
+	if !mod.Redeem("another", expiredAt) {
+		t.Fatal("redemption of another token should succeed")
+	}
+
+	// expired tokens are swept
+	mod.redeemed["expired"] = time.Now().Add(-time.Minute).Unix()
+	mod.lastSweep = 0
+	mod.Redeem("salt2", expiredAt)
+	if _, ok := mod.redeemed["expired"]; ok {
+		t.Fatal("expired token not swept")
+	}
+}
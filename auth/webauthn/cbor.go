@@ -0,0 +1,158 @@
+package webauthn
+
+import (
+	"errors"
+	"math"
+)
+
+var errInvalidCBOR = errors.New("webauthn: invalid cbor")
+
+// maxCBORDepth limits nesting of cbor items
+const maxCBORDepth = 16
+
+// decodeCBOR decodes the first cbor item of data, and returns the item and
+// number of consumed bytes. It supports the subset of CBOR used by WebAuthn:
+// integers (int64), byte strings ([]byte), text strings (string), arrays ([]any),
+// maps (map[any]any), booleans, null and floats. Indefinite lengths are not supported.
+func decodeCBOR(data []byte) (any, int, error) {
+	d := cborDecoder{data: data}
+	v, err := d.decode(0)
+	return v, d.off, err
+}
+
+type cborDecoder struct {
+	data []byte
+	off  int
+}
+
+func (d *cborDecoder) read(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.off) {
+		return nil, errInvalidCBOR
+	}
+	b := d.data[d.off : d.off+int(n)]
+	d.off += int(n)
+	return b, nil
+}
+
+// head reads major type and argument of an item
+func (d *cborDecoder) head() (major byte, info byte, arg uint64, err error) {
+	b, err := d.read(1)
+	if err != nil {
+		return
+	}
+	major, info = b[0]>>5, b[0]&0x1f
+	switch {
+	case info < 24:
+		arg = uint64(info)
+	case info <= 27:
+		var x []byte
+		if x, err = d.read(1 << (info - 24)); err != nil {
+			return
+		}
+		for _, c := range x {
+			arg = arg<<8 | uint64(c)
+		}
+	default:
+		err = errInvalidCBOR
+	}
+	return
+}
+
+func (d *cborDecoder) decode(depth int) (any, error) {
+	if depth > maxCBORDepth {
+		return nil, errInvalidCBOR
+	}
+	major, info, arg, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	switch major {
+	case 0: // unsigned integer
+		if arg > math.MaxInt64 {
+			return nil, errInvalidCBOR
+		}
+		return int64(arg), nil
+	case 1: // negative integer
+		if arg > math.MaxInt64 {
+			return nil, errInvalidCBOR
+		}
+		return -1 - int64(arg), nil
+	case 2: // byte string
+		return d.read(arg)
+	case 3: // text string
+		b, err := d.read(arg)
+		return string(b), err
+	case 4: // array
+		if arg > uint64(len(d.data)) {
+			return nil, errInvalidCBOR
+		}
+		a := make([]any, 0, arg)
+		for i := uint64(0); i < arg; i++ {
+			v, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, v)
+		}
+		return a, nil
+	case 5: // map
+		if arg > uint64(len(d.data)) {
+			return nil, errInvalidCBOR
+		}
+		m := make(map[any]any, arg)
+		for i := uint64(0); i < arg; i++ {
+			k, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			switch k.(type) {
+			case int64, string:
+			default:
+				return nil, errInvalidCBOR
+			}
+			v, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = v
+		}
+		return m, nil
+	case 6: // tag, ignored
+		return d.decode(depth + 1)
+	default: // simple values and floats
+		switch info {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 22, 23:
+			return nil, nil
+		case 25:
+			return float64(halfToFloat(uint16(arg))), nil
+		case 26:
+			return float64(math.Float32frombits(uint32(arg))), nil
+		case 27:
+			return math.Float64frombits(arg), nil
+		}
+		return nil, errInvalidCBOR
+	}
+}
+
+func halfToFloat(h uint16) float32 {
+	var (
+		sign = uint32(h>>15) << 31
+		exp  = uint32(h>>10) & 0x1f
+		frac = uint32(h & 0x3ff)
+	)
+	switch exp {
+	case 0:
+		f := float32(frac) / 1024 / 16384
+		if sign != 0 {
+			return -f
+		}
+		return f
+	case 0x1f:
+		return math.Float32frombits(sign | 0xff<<23 | frac<<13)
+	}
+	return math.Float32frombits(sign | (exp+112)<<23 | frac<<13)
+}
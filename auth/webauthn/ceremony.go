@@ -0,0 +1,254 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var (
+	errInvalidChallenge = errors.New("webauthn: invalid challenge")
+	errInvalidClient    = errors.New("webauthn: invalid client data")
+	errInvalidAuthData  = errors.New("webauthn: invalid authenticator data")
+	errInvalidSignature = errors.New("webauthn: invalid signature")
+)
+
+var b64 = base64.RawURLEncoding
+
+// client data types
+const (
+	typeCreate = "webauthn.create"
+	typeGet    = "webauthn.get"
+)
+
+// authenticator data flags
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttested     = 0x40
+)
+
+// challenge kinds
+const (
+	kindRegistration   byte = 1
+	kindAuthentication byte = 2
+)
+
+const (
+	nonceSize     = 16
+	macSize       = 16
+	challengeSize = 1 + 8 + 8 + nonceSize + macSize
+)
+
+// challenger issues and verifies stateless challenges, a challenge contains
+// kind, uid, expiry time, nonce and hmac of them
+type challenger struct {
+	secret []byte
+}
+
+func (c challenger) mac(payload []byte) []byte {
+	h := hmac.New(sha256.New, c.secret)
+	h.Write(payload)
+	return h.Sum(nil)[:macSize]
+}
+
+func (c challenger) issue(kind byte, uid int64, expiresAt time.Time) (string, error) {
+	b := make([]byte, challengeSize-macSize, challengeSize)
+	b[0] = kind
+	binary.BigEndian.PutUint64(b[1:], uint64(uid))
+	binary.BigEndian.PutUint64(b[9:], uint64(expiresAt.Unix()))
+	if _, err := rand.Read(b[17:]); err != nil {
+		return "", err
+	}
+	return b64.EncodeToString(append(b, c.mac(b)...)), nil
+}
+
+// verify verifies the challenge and returns the uid
+func (c challenger) verify(kind byte, challenge string, now time.Time) (int64, error) {
+	b, err := b64.DecodeString(challenge)
+	if err != nil || len(b) != challengeSize || b[0] != kind {
+		return 0, errInvalidChallenge
+	}
+	payload := b[:challengeSize-macSize]
+	if !hmac.Equal(c.mac(payload), b[challengeSize-macSize:]) {
+		return 0, errInvalidChallenge
+	}
+	if int64(binary.BigEndian.Uint64(b[9:])) < now.Unix() {
+		return 0, errInvalidChallenge
+	}
+	return int64(binary.BigEndian.Uint64(b[1:])), nil
+}
+
+// relyingParty verifies responses of authenticators
+type relyingParty struct {
+	id                  string
+	origins             []string
+	requireVerification bool
+	allowedAlgorithms   []int
+}
+
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+func (rp *relyingParty) parseClientData(raw []byte, typ string) (*clientData, error) {
+	cd := new(clientData)
+	if err := json.Unmarshal(raw, cd); err != nil {
+		return nil, errInvalidClient
+	}
+	if cd.Type != typ || cd.Challenge == "" || cd.CrossOrigin {
+		return nil, errInvalidClient
+	}
+	for _, origin := range rp.origins {
+		if cd.Origin == origin {
+			return cd, nil
+		}
+	}
+	return nil, errInvalidClient
+}
+
+type authenticatorData struct {
+	rpIDHash     []byte
+	flags        byte
+	signCount    uint32
+	credentialID []byte
+	publicKey    []byte // COSE_Key
+}
+
+func parseAuthenticatorData(data []byte) (*authenticatorData, error) {
+	if len(data) < 37 {
+		return nil, errInvalidAuthData
+	}
+	ad := &authenticatorData{
+		rpIDHash:  data[:32],
+		flags:     data[32],
+		signCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	if ad.flags&flagAttested == 0 {
+		return ad, nil
+	}
+	// attested credential data: aaguid(16) | length(2) | credential id | public key
+	rest := data[37:]
+	if len(rest) < 18 {
+		return nil, errInvalidAuthData
+	}
+	n := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if n == 0 || n > 1023 || len(rest) < n {
+		return nil, errInvalidAuthData
+	}
+	ad.credentialID = rest[:n]
+	_, size, err := decodeCBOR(rest[n:])
+	if err != nil {
+		return nil, errInvalidAuthData
+	}
+	ad.publicKey = rest[n : n+size]
+	return ad, nil
+}
+
+func (rp *relyingParty) checkAuthenticatorData(ad *authenticatorData) error {
+	hash := sha256.Sum256([]byte(rp.id))
+	if !bytes.Equal(ad.rpIDHash, hash[:]) {
+		return errInvalidAuthData
+	}
+	if ad.flags&flagUserPresent == 0 {
+		return errInvalidAuthData
+	}
+	if rp.requireVerification && ad.flags&flagUserVerified == 0 {
+		return errInvalidAuthData
+	}
+	return nil
+}
+
+// registration is the verified result of registration ceremony
+type registration struct {
+	challenge    string
+	credentialID []byte
+	publicKey    []byte
+	alg          int
+	signCount    uint32
+}
+
+// verifyRegistration verifies response of navigator.credentials.create.
+// Attestation statements are not verified since attestation "none" is requested.
+func (rp *relyingParty) verifyRegistration(clientDataJSON, attestationObject []byte) (*registration, error) {
+	cd, err := rp.parseClientData(clientDataJSON, typeCreate)
+	if err != nil {
+		return nil, err
+	}
+	v, n, err := decodeCBOR(attestationObject)
+	if err != nil || n != len(attestationObject) {
+		return nil, errInvalidAuthData
+	}
+	m, _ := v.(map[any]any)
+	authData, _ := m["authData"].([]byte)
+	ad, err := parseAuthenticatorData(authData)
+	if err != nil {
+		return nil, err
+	}
+	if err := rp.checkAuthenticatorData(ad); err != nil {
+		return nil, err
+	}
+	if ad.credentialID == nil {
+		return nil, errInvalidAuthData
+	}
+	key, err := parsePublicKey(ad.publicKey)
+	if err != nil {
+		return nil, err
+	}
+	if !rp.allowed(key.alg) {
+		return nil, errUnsupportedKey
+	}
+	return &registration{
+		challenge:    cd.Challenge,
+		credentialID: ad.credentialID,
+		publicKey:    ad.publicKey,
+		alg:          key.alg,
+		signCount:    ad.signCount,
+	}, nil
+}
+
+func (rp *relyingParty) allowed(alg int) bool {
+	for _, x := range rp.allowedAlgorithms {
+		if x == alg {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyAssertion verifies response of navigator.credentials.get, and returns
+// the challenge and signature counter
+func (rp *relyingParty) verifyAssertion(clientDataJSON, authData, signature, publicKeyData []byte) (string, uint32, error) {
+	cd, err := rp.parseClientData(clientDataJSON, typeGet)
+	if err != nil {
+		return "", 0, err
+	}
+	ad, err := parseAuthenticatorData(authData)
+	if err != nil {
+		return "", 0, err
+	}
+	if err := rp.checkAuthenticatorData(ad); err != nil {
+		return "", 0, err
+	}
+	key, err := parsePublicKey(publicKeyData)
+	if err != nil {
+		return "", 0, err
+	}
+	hash := sha256.Sum256(clientDataJSON)
+	signed := make([]byte, 0, len(authData)+len(hash))
+	signed = append(append(signed, authData...), hash[:]...)
+	if !key.verify(signed, signature) {
+		return "", 0, errInvalidSignature
+	}
+	return cd.Challenge, ad.signCount, nil
+}
@@ -0,0 +1,225 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// pair is a key-value pair of cbor map which keeps order of keys
+type pair struct {
+	key, value any
+}
+
+// encodeCBOR encodes the subset of cbor used by tests
+func encodeCBOR(v any) []byte {
+	head := func(major byte, n uint64) []byte {
+		switch {
+		case n < 24:
+			return []byte{major<<5 | byte(n)}
+		case n <= 0xff:
+			return []byte{major<<5 | 24, byte(n)}
+		case n <= 0xffff:
+			return []byte{major<<5 | 25, byte(n >> 8), byte(n)}
+		default:
+			b := []byte{major<<5 | 26, 0, 0, 0, 0}
+			binary.BigEndian.PutUint32(b[1:], uint32(n))
+			return b
+		}
+	}
+	switch v := v.(type) {
+	case int:
+		if v < 0 {
+			return head(1, uint64(-1-v))
+		}
+		return head(0, uint64(v))
+	case []byte:
+		return append(head(2, uint64(len(v))), v...)
+	case string:
+		return append(head(3, uint64(len(v))), v...)
+	case []pair:
+		b := head(5, uint64(len(v)))
+		for _, p := range v {
+			b = append(b, encodeCBOR(p.key)...)
+			b = append(b, encodeCBOR(p.value)...)
+		}
+		return b
+	}
+	panic("unsupported type")
+}
+
+func TestDecodeCBOR(t *testing.T) {
+	data := encodeCBOR([]pair{
+		{1, 2},
+		{-1, -300},
+		{"fmt", "none"},
+		{"data", []byte{1, 2, 3}},
+	})
+	v, n, err := decodeCBOR(append(data, 0xff))
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if n != len(data) {
+		t.Fatalf("consumed %d bytes, want %d", n, len(data))
+	}
+	m := v.(map[any]any)
+	if m[int64(1)] != int64(2) || m[int64(-1)] != int64(-300) || m["fmt"] != "none" ||
+		!bytes.Equal(m["data"].([]byte), []byte{1, 2, 3}) {
+		t.Fatalf("unexpected value: %v", m)
+	}
+	for _, bad := range [][]byte{nil, {0x5a, 0xff, 0xff, 0xff, 0xff}, {0xa1, 0x01}, {0x9f}} {
+		if _, _, err := decodeCBOR(bad); err == nil {
+			t.Fatalf("decode %x: error expected", bad)
+		}
+	}
+}
+
+func TestChallenger(t *testing.T) {
+	c := challenger{secret: []byte("secret")}
+	now := time.Now()
+	challenge, err := c.issue(kindRegistration, 1001, now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("issue error: %v", err)
+	}
+	if uid, err := c.verify(kindRegistration, challenge, now); err != nil || uid != 1001 {
+		t.Fatalf("verify: uid %d, error %v", uid, err)
+	}
+	if _, err := c.verify(kindAuthentication, challenge, now); err == nil {
+		t.Fatalf("kind mismatched: error expected")
+	}
+	if _, err := c.verify(kindRegistration, challenge, now.Add(2*time.Minute)); err == nil {
+		t.Fatalf("expired: error expected")
+	}
+	other := challenger{secret: []byte("other")}
+	if _, err := other.verify(kindRegistration, challenge, now); err == nil {
+		t.Fatalf("secret mismatched: error expected")
+	}
+}
+
+// authenticator simulates a platform authenticator with a P-256 key
+type authenticator struct {
+	t            *testing.T
+	rpID         string
+	origin       string
+	credentialID []byte
+	key          *ecdsa.PrivateKey
+	signCount    uint32
+}
+
+func newAuthenticator(t *testing.T, rpID, origin string) *authenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &authenticator{
+		t:            t,
+		rpID:         rpID,
+		origin:       origin,
+		credentialID: []byte("credential-0001"),
+		key:          key,
+	}
+}
+
+func (a *authenticator) clientData(typ, challenge string) []byte {
+	data, err := json.Marshal(clientData{Type: typ, Challenge: challenge, Origin: a.origin})
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	return data
+}
+
+func (a *authenticator) authData(flags byte, attested []byte) []byte {
+	hash := sha256.Sum256([]byte(a.rpID))
+	b := append(hash[:], flags, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(b[33:], a.signCount)
+	return append(b, attested...)
+}
+
+func (a *authenticator) create(challenge string) (clientDataJSON, attestationObject []byte) {
+	x := a.key.X.FillBytes(make([]byte, 32))
+	y := a.key.Y.FillBytes(make([]byte, 32))
+	cose := encodeCBOR([]pair{
+		{coseKty, coseKtyEC2},
+		{coseAlg, AlgES256},
+		{coseCrv, coseP256},
+		{coseX, x},
+		{coseY, y},
+	})
+	attested := make([]byte, 16, 18)
+	attested = append(attested, byte(len(a.credentialID)>>8), byte(len(a.credentialID)))
+	attested = append(append(attested, a.credentialID...), cose...)
+	attestationObject = encodeCBOR([]pair{
+		{"fmt", "none"},
+		{"attStmt", []pair{}},
+		{"authData", a.authData(flagUserPresent|flagUserVerified|flagAttested, attested)},
+	})
+	return a.clientData(typeCreate, challenge), attestationObject
+}
+
+func (a *authenticator) get(challenge string) (clientDataJSON, authData, signature []byte) {
+	a.signCount++
+	clientDataJSON = a.clientData(typeGet, challenge)
+	authData = a.authData(flagUserPresent|flagUserVerified, nil)
+	hash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), hash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	return
+}
+
+func TestCeremonies(t *testing.T) {
+	rp := &relyingParty{
+		id:                  "example.com",
+		origins:             []string{"https://example.com"},
+		requireVerification: true,
+		allowedAlgorithms:   []int{AlgES256, AlgRS256},
+	}
+	a := newAuthenticator(t, "example.com", "https://example.com")
+
+	clientDataJSON, attestationObject := a.create("challenge-1")
+	reg, err := rp.verifyRegistration(clientDataJSON, attestationObject)
+	if err != nil {
+		t.Fatalf("verify registration error: %v", err)
+	}
+	if reg.challenge != "challenge-1" || !bytes.Equal(reg.credentialID, a.credentialID) || reg.alg != AlgES256 {
+		t.Fatalf("unexpected registration: %+v", reg)
+	}
+
+	clientDataJSON, authData, signature := a.get("challenge-2")
+	challenge, signCount, err := rp.verifyAssertion(clientDataJSON, authData, signature, reg.publicKey)
+	if err != nil {
+		t.Fatalf("verify assertion error: %v", err)
+	}
+	if challenge != "challenge-2" || signCount != 1 {
+		t.Fatalf("unexpected assertion: challenge %q, sign count %d", challenge, signCount)
+	}
+
+	// tampered authenticator data
+	tampered := append([]byte{}, authData...)
+	tampered[36]++
+	if _, _, err := rp.verifyAssertion(clientDataJSON, tampered, signature, reg.publicKey); err == nil {
+		t.Fatalf("tampered authenticator data: error expected")
+	}
+
+	// wrong origin
+	evil := newAuthenticator(t, "example.com", "https://evil.com")
+	clientDataJSON, attestationObject = evil.create("challenge-3")
+	if _, err := rp.verifyRegistration(clientDataJSON, attestationObject); err == nil {
+		t.Fatalf("wrong origin: error expected")
+	}
+
+	// wrong relying party
+	evil = newAuthenticator(t, "evil.com", "https://example.com")
+	clientDataJSON, attestationObject = evil.create("challenge-4")
+	if _, err := rp.verifyRegistration(clientDataJSON, attestationObject); err == nil {
+		t.Fatalf("wrong relying party: error expected")
+	}
+}
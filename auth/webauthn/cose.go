@@ -0,0 +1,101 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+)
+
+// COSE algorithms
+const (
+	AlgES256 = -7
+	AlgRS256 = -257
+)
+
+// COSE key parameters
+const (
+	coseKty    = 1
+	coseAlg    = 3
+	coseCrv    = -1 // EC2
+	coseX      = -2 // EC2
+	coseY      = -3 // EC2
+	coseN      = -1 // RSA
+	coseE      = -2 // RSA
+	coseKtyEC2 = 2
+	coseKtyRSA = 3
+	coseP256   = 1
+)
+
+var errUnsupportedKey = errors.New("webauthn: unsupported public key")
+
+// publicKey represents a credential public key
+type publicKey struct {
+	alg int
+	key crypto.PublicKey
+}
+
+// parsePublicKey parses a COSE_Key encoded public key
+func parsePublicKey(data []byte) (*publicKey, error) {
+	v, n, err := decodeCBOR(data)
+	if err != nil {
+		return nil, err
+	}
+	if n != len(data) {
+		return nil, errUnsupportedKey
+	}
+	m, ok := v.(map[any]any)
+	if !ok {
+		return nil, errUnsupportedKey
+	}
+	kty, _ := m[int64(coseKty)].(int64)
+	alg, _ := m[int64(coseAlg)].(int64)
+	switch {
+	case kty == coseKtyEC2 && alg == AlgES256:
+		crv, _ := m[int64(coseCrv)].(int64)
+		x, _ := m[int64(coseX)].([]byte)
+		y, _ := m[int64(coseY)].([]byte)
+		if crv != coseP256 || len(x) != 32 || len(y) != 32 {
+			return nil, errUnsupportedKey
+		}
+		key := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, errUnsupportedKey
+		}
+		return &publicKey{alg: AlgES256, key: key}, nil
+	case kty == coseKtyRSA && alg == AlgRS256:
+		n, _ := m[int64(coseN)].([]byte)
+		e, _ := m[int64(coseE)].([]byte)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, errUnsupportedKey
+		}
+		var exp int
+		for _, c := range e {
+			exp = exp<<8 | int(c)
+		}
+		return &publicKey{alg: AlgRS256, key: &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: exp,
+		}}, nil
+	}
+	return nil, errUnsupportedKey
+}
+
+// verify verifies signature of data
+func (k *publicKey) verify(data, signature []byte) bool {
+	hash := sha256.Sum256(data)
+	switch key := k.key.(type) {
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(key, hash[:], signature)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], signature) == nil
+	}
+	return false
+}
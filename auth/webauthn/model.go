@@ -0,0 +1,19 @@
+package webauthn
+
+import (
+	"time"
+)
+
+const tableName = "webauthn_credential"
+
+type credential struct {
+	ID         string    `gorm:"primaryKey;size:255;column:id"` // base64url encoded credential id
+	Uid        int64     `gorm:"index;column:uid;not null"`
+	Algorithm  int       `gorm:"column:algorithm"`
+	PublicKey  []byte    `gorm:"column:public_key;not null"` // COSE_Key
+	SignCount  uint32    `gorm:"column:sign_count"`
+	CreatedAt  time.Time `gorm:"column:created_at"`
+	LastUsedAt time.Time `gorm:"column:last_used_at"`
+}
+
+func (*credential) TableName() string { return tableName }
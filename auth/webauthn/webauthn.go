@@ -0,0 +1,354 @@
+// Package webauthn implements passkey registration and authentication as
+// a provider, so a passkey is linked to an account by /auth/link like other
+// providers and used to login by /auth/authorize. Registrations are accepted
+// only by /auth/link, which completes them by Register.
+//
+// Credentials of provider webauthn are JSON objects with base64url encoded fields:
+//
+//	registration:   {"client_data_json": "...", "attestation_object": "..."}
+//	authentication: {"client_data_json": "...", "authenticator_data": "...", "signature": "...", "user_handle": "..."}
+//
+// and the account is the base64url encoded credential id.
+package webauthn
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gopherd/doge/erron"
+	"github.com/gopherd/doge/service/module"
+	"github.com/gopherd/doge/time/timer"
+
+	"github.com/gopherd/gopherd/auth"
+	"github.com/gopherd/gopherd/auth/api"
+	"github.com/gopherd/gopherd/auth/config"
+	"github.com/gopherd/gopherd/auth/provider"
+)
+
+// Name is the provider name of webauthn
+const Name = "webauthn"
+
+const (
+	fieldId  = "id"
+	fieldUid = "uid"
+)
+
+var (
+	errDisabled           = erron.Errnof(api.BadArgument, "webauthn disabled")
+	errBadCredentials     = erron.Errnof(api.BadAuthorization, "invalid webauthn credentials")
+	errCredentialNotFound = erron.Errnof(api.AccountNotFoundOrPasswordMismatched, "credential not found")
+	errCredentialFound    = erron.Errnof(api.AccountFound, "credential already registered")
+	errSignCount          = erron.Errnof(api.BadAuthorization, "signature counter mismatched")
+	errRegistration       = erron.Errnof(api.BadArgument, "webauthn registration must be completed by link")
+	errCeremonyAccount    = erron.Errnof(api.BadAuthorization, "registration ceremony of another account")
+)
+
+type Service interface {
+	Config() *config.Config
+	OOSModule() auth.OOSModule
+}
+
+// New creates an auth.WebAuthnModule
+func New(service Service) interface {
+	module.Module
+	auth.WebAuthnModule
+} {
+	return newWebAuthnModule(service)
+}
+
+// webauthnModule implements auth.WebAuthnModule
+type webauthnModule struct {
+	*module.BasicModule
+	service    Service
+	rp         *relyingParty // nil if webauthn disabled
+	challenger challenger
+	ticker     *timer.Ticker
+
+	// used challenges which can't be used again until expired. They're kept
+	// in memory of this instance, so a ceremony replayed to another instance
+	// is rejected only by the signature counter, which is always 0 for some
+	// passkeys. Deployments of multiple instances should route webauthn apis
+	// of a client to the same instance if replays matter.
+	usedMu sync.Mutex
+	used   map[string]int64
+}
+
+func newWebAuthnModule(service Service) *webauthnModule {
+	return &webauthnModule{
+		BasicModule: module.NewBasicModule("webauthn"),
+		service:     service,
+		used:        make(map[string]int64),
+	}
+}
+
+func (mod *webauthnModule) Init() error {
+	if err := mod.BasicModule.Init(); err != nil {
+		return err
+	}
+	cfg := mod.service.Config().WebAuthn
+	if cfg.RPID == "" {
+		return nil
+	}
+	mod.rp = &relyingParty{
+		id:                  cfg.RPID,
+		origins:             cfg.Origins,
+		requireVerification: cfg.UserVerification == "required",
+		allowedAlgorithms:   []int{AlgES256, AlgRS256},
+	}
+	// challenges issued by an instance must be verified by others, so the
+	// secret can't be generated randomly
+	if cfg.Secret == "" {
+		return erron.Throwf("webauthn secret required")
+	}
+	mod.challenger.secret = []byte(cfg.Secret)
+	mod.ticker = timer.NewTicker(time.Minute)
+	return mod.service.OOSModule().CreateSchema(new(credential))
+}
+
+// Update overrides BasicModule Update method to remove expired challenges
+func (mod *webauthnModule) Update(now time.Time, dt time.Duration) {
+	mod.BasicModule.Update(now, dt)
+	if mod.ticker != nil && mod.ticker.Next(now) {
+		mod.usedMu.Lock()
+		defer mod.usedMu.Unlock()
+		for challenge, expiresAt := range mod.used {
+			if expiresAt < now.Unix() {
+				delete(mod.used, challenge)
+			}
+		}
+	}
+}
+
+// Enabled implements auth.WebAuthnModule Enabled method
+func (mod *webauthnModule) Enabled() bool {
+	return mod.rp != nil
+}
+
+func (mod *webauthnModule) options(kind byte, uid int64) (auth.WebAuthnOptions, error) {
+	cfg := mod.service.Config().WebAuthn
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	challenge, err := mod.challenger.issue(kind, uid, time.Now().Add(timeout))
+	if err != nil {
+		return auth.WebAuthnOptions{}, err
+	}
+	return auth.WebAuthnOptions{
+		Challenge:        challenge,
+		RPID:             cfg.RPID,
+		RPName:           cfg.RPName,
+		Timeout:          timeout,
+		UserVerification: cfg.UserVerification,
+	}, nil
+}
+
+// userHandle returns user handle of the uid, which is user.id of registration options
+func userHandle(uid int64) string {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(uid))
+	return b64.EncodeToString(b[:])
+}
+
+// BeginRegistration implements auth.WebAuthnModule BeginRegistration method
+func (mod *webauthnModule) BeginRegistration(account auth.Account) (auth.WebAuthnOptions, error) {
+	if !mod.Enabled() {
+		return auth.WebAuthnOptions{}, errDisabled
+	}
+	options, err := mod.options(kindRegistration, account.GetID())
+	if err != nil {
+		return options, err
+	}
+	options.UserID = userHandle(account.GetID())
+	options.UserName = account.GetName()
+	if options.UserName == "" {
+		options.UserName = strconv.FormatInt(account.GetID(), 10)
+	}
+	options.Algorithms = mod.rp.allowedAlgorithms
+	var credentials []credential
+	if err := mod.service.OOSModule().Query(&credentials,
+		"SELECT `id` FROM `"+tableName+"` WHERE `uid` = ?", account.GetID()); err != nil {
+		return options, err
+	}
+	for i := range credentials {
+		options.Credentials = append(options.Credentials, credentials[i].ID)
+	}
+	return options, nil
+}
+
+// BeginLogin implements auth.WebAuthnModule BeginLogin method
+func (mod *webauthnModule) BeginLogin() (auth.WebAuthnOptions, error) {
+	if !mod.Enabled() {
+		return auth.WebAuthnOptions{}, errDisabled
+	}
+	return mod.options(kindAuthentication, 0)
+}
+
+// consume verifies the challenge and marks it used
+func (mod *webauthnModule) consume(kind byte, challenge string) (int64, error) {
+	now := time.Now()
+	uid, err := mod.challenger.verify(kind, challenge, now)
+	if err != nil {
+		return 0, err
+	}
+	mod.usedMu.Lock()
+	defer mod.usedMu.Unlock()
+	if _, used := mod.used[challenge]; used {
+		return 0, errInvalidChallenge
+	}
+	mod.used[challenge] = now.Add(time.Duration(mod.service.Config().WebAuthn.Timeout) * time.Second).Unix()
+	return uid, nil
+}
+
+type credentials struct {
+	ClientDataJSON    string `json:"client_data_json"`
+	AttestationObject string `json:"attestation_object"`
+	AuthenticatorData string `json:"authenticator_data"`
+	Signature         string `json:"signature"`
+	UserHandle        string `json:"user_handle"`
+}
+
+func (mod *webauthnModule) parseCredentials(secret string) (credentials, []byte, error) {
+	var c credentials
+	if err := json.Unmarshal([]byte(secret), &c); err != nil {
+		return c, nil, errBadCredentials
+	}
+	clientDataJSON, err := b64.DecodeString(c.ClientDataJSON)
+	if err != nil {
+		return c, nil, errBadCredentials
+	}
+	return c, clientDataJSON, nil
+}
+
+// Authorize implements provider.Provider Authorize method, it verifies an
+// assertion, registrations are rejected since they must be completed by Register
+func (mod *webauthnModule) Authorize(account, secret string) (*provider.UserInfo, error) {
+	if !mod.Enabled() {
+		return nil, errDisabled
+	}
+	c, clientDataJSON, err := mod.parseCredentials(secret)
+	if err != nil {
+		return nil, err
+	}
+	if c.AttestationObject != "" {
+		return nil, errRegistration
+	}
+	return mod.login(account, clientDataJSON, c)
+}
+
+// Register implements auth.WebAuthnModule Register method
+func (mod *webauthnModule) Register(account auth.Account, id, secret string) (*provider.UserInfo, error) {
+	if !mod.Enabled() {
+		return nil, errDisabled
+	}
+	c, clientDataJSON, err := mod.parseCredentials(secret)
+	if err != nil {
+		return nil, err
+	}
+	object, err := b64.DecodeString(c.AttestationObject)
+	if err != nil || len(object) == 0 {
+		return nil, errBadCredentials
+	}
+	reg, err := mod.rp.verifyRegistration(clientDataJSON, object)
+	if err != nil {
+		return nil, erron.Errno(api.BadAuthorization, err)
+	}
+	if b64.EncodeToString(reg.credentialID) != id {
+		return nil, errBadCredentials
+	}
+	uid, err := mod.consume(kindRegistration, reg.challenge)
+	if err != nil {
+		return nil, erron.Errno(api.BadAuthorization, err)
+	}
+	// the ceremony must be started by the account which links the credential
+	if uid != account.GetID() {
+		mod.Logger().Warn().
+			Int64("uid", account.GetID()).
+			Int64("ceremony_uid", uid).
+			String("credential", id).
+			Print("webauthn registration of another account")
+		return nil, errCeremonyAccount
+	}
+	oos := mod.service.OOSModule()
+	if found, err := oos.HasObject(tableName, auth.Field{Name: fieldId, Value: id}); err != nil {
+		return nil, err
+	} else if found {
+		return nil, errCredentialFound
+	}
+	now := time.Now()
+	if err := oos.InsertObject(&credential{
+		ID:         id,
+		Uid:        uid,
+		Algorithm:  reg.alg,
+		PublicKey:  reg.publicKey,
+		SignCount:  reg.signCount,
+		CreatedAt:  now,
+		LastUsedAt: now,
+	}); err != nil {
+		return nil, err
+	}
+	mod.Logger().Info().
+		Int64("uid", uid).
+		String("credential", id).
+		Int("algorithm", reg.alg).
+		Print("webauthn credential registered")
+	return &provider.UserInfo{
+		Key:    id,
+		OpenId: id,
+		Uid:    uid,
+	}, nil
+}
+
+func (mod *webauthnModule) login(id string, clientDataJSON []byte, c credentials) (*provider.UserInfo, error) {
+	authData, err := b64.DecodeString(c.AuthenticatorData)
+	if err != nil {
+		return nil, errBadCredentials
+	}
+	signature, err := b64.DecodeString(c.Signature)
+	if err != nil {
+		return nil, errBadCredentials
+	}
+	oos := mod.service.OOSModule()
+	cred := new(credential)
+	if found, err := oos.GetObject(cred, auth.Field{Name: fieldId, Value: id}); err != nil {
+		return nil, err
+	} else if !found {
+		return nil, errCredentialNotFound
+	}
+	if c.UserHandle != "" && c.UserHandle != userHandle(cred.Uid) {
+		return nil, errBadCredentials
+	}
+	challenge, signCount, err := mod.rp.verifyAssertion(clientDataJSON, authData, signature, cred.PublicKey)
+	if err != nil {
+		return nil, erron.Errno(api.BadAuthorization, err)
+	}
+	if _, err := mod.consume(kindAuthentication, challenge); err != nil {
+		return nil, erron.Errno(api.BadAuthorization, err)
+	}
+	// counters of authenticators which support it must increase, otherwise
+	// the authenticator may be cloned
+	if (signCount != 0 || cred.SignCount != 0) && signCount <= cred.SignCount {
+		mod.Logger().Warn().
+			Int64("uid", cred.Uid).
+			String("credential", id).
+			Uint("sign_count", uint(signCount)).
+			Uint("stored_sign_count", uint(cred.SignCount)).
+			Print("webauthn signature counter mismatched")
+		return nil, errSignCount
+	}
+	cred.SignCount = signCount
+	cred.LastUsedAt = time.Now()
+	if _, err := oos.UpdateObject(cred, "sign_count", "last_used_at"); err != nil {
+		return nil, err
+	}
+	return &provider.UserInfo{
+		Key:    id,
+		OpenId: id,
+		Uid:    cred.Uid,
+	}, nil
+}
+
+// Close implements provider.Provider Close method
+func (mod *webauthnModule) Close() error {
+	return nil
+}
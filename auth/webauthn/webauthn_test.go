@@ -0,0 +1,101 @@
+package webauthn
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gopherd/doge/erron"
+
+	"github.com/gopherd/gopherd/auth"
+	"github.com/gopherd/gopherd/auth/api"
+	"github.com/gopherd/gopherd/auth/config"
+)
+
+type testService struct {
+	Service
+	config *config.Config
+	oos    *testOOS
+}
+
+func (s *testService) Config() *config.Config    { return s.config }
+func (s *testService) OOSModule() auth.OOSModule { return s.oos }
+
+// testOOS stores inserted credentials in memory
+type testOOS struct {
+	auth.OOSModule
+	credentials map[string]*credential
+}
+
+func (oos *testOOS) HasObject(tableName string, by ...auth.Field) (bool, error) {
+	_, found := oos.credentials[by[0].Value]
+	return found, nil
+}
+
+func (oos *testOOS) InsertObject(obj auth.Object) error {
+	c := obj.(*credential)
+	oos.credentials[c.ID] = c
+	return nil
+}
+
+type testAccount struct {
+	auth.Account
+	id int64
+}
+
+func (a *testAccount) GetID() int64 { return a.id }
+
+func TestInitRequiresSecret(t *testing.T) {
+	cfg := new(config.Config).Default().(*config.Config)
+	cfg.WebAuthn.RPID = "example.com"
+	mod := newWebAuthnModule(&testService{config: cfg})
+	if err := mod.Init(); err == nil {
+		t.Fatal("webauthn enabled without secret")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	cfg := new(config.Config).Default().(*config.Config)
+	cfg.WebAuthn.Timeout = 60
+	oos := &testOOS{credentials: make(map[string]*credential)}
+	mod := newWebAuthnModule(&testService{config: cfg, oos: oos})
+	mod.rp = &relyingParty{
+		id:                "example.com",
+		origins:           []string{"https://example.com"},
+		allowedAlgorithms: []int{AlgES256},
+	}
+	mod.challenger.secret = []byte("secret")
+	a := newAuthenticator(t, "example.com", "https://example.com")
+	id := b64.EncodeToString(a.credentialID)
+	register := func() string {
+		options, err := mod.options(kindRegistration, 1001)
+		if err != nil {
+			t.Fatal(err)
+		}
+		clientDataJSON, attestationObject := a.create(options.Challenge)
+		secret, err := json.Marshal(credentials{
+			ClientDataJSON:    b64.EncodeToString(clientDataJSON),
+			AttestationObject: b64.EncodeToString(attestationObject),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(secret)
+	}
+
+	if _, err := mod.Authorize(id, register()); erron.GetErrno(err) != api.BadArgument {
+		t.Fatalf("registration by authorize: want errno %d, got %v", api.BadArgument, err)
+	}
+	if _, err := mod.Register(&testAccount{id: 1002}, id, register()); err != errCeremonyAccount {
+		t.Fatalf("registration of another account: want %v, got %v", errCeremonyAccount, err)
+	}
+	if len(oos.credentials) != 0 {
+		t.Fatalf("credential stored by rejected registrations")
+	}
+	user, err := mod.Register(&testAccount{id: 1001}, id, register())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Uid != 1001 || oos.credentials[id] == nil || oos.credentials[id].Uid != 1001 {
+		t.Fatalf("unexpected registration: %+v, %+v", user, oos.credentials[id])
+	}
+}
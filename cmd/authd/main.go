@@ -8,6 +8,11 @@ import (
 	_ "github.com/gopherd/redis/mq"
 	_ "github.com/gopherd/zmq"
 
+	_ "github.com/gopherd/gopherd/auth/blob/fs"
+	_ "github.com/gopherd/gopherd/auth/blob/s3"
+	_ "github.com/gopherd/gopherd/auth/captcha/siteverify"
+	_ "github.com/gopherd/gopherd/auth/ratelimit/redis"
+
 	"github.com/gopherd/gopherd/auth/config"
 	"github.com/gopherd/gopherd/auth/server"
 )
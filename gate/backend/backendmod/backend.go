@@ -16,6 +16,7 @@ import (
 	"github.com/gopherd/doge/service/module"
 	"github.com/gopherd/jwt"
 
+	"github.com/gopherd/gopherd/auth/api"
 	"github.com/gopherd/gopherd/gate/backend"
 	"github.com/gopherd/gopherd/gate/config"
 	"github.com/gopherd/gopherd/gate/frontend"
@@ -142,7 +143,13 @@ func (mod *backendModule) Forward(f *gatepb.Forward) error {
 
 // Login implements backend.Module Login method
 func (mod *backendModule) Login(claims jwt.Payload, race bool) error {
-	userdata, err := json.Marshal(claims.Values)
+	// backends authorize messages by scopes in userdata
+	values := make(map[string]any, len(claims.Values)+1)
+	for k, v := range claims.Values {
+		values[k] = v
+	}
+	values[api.ClaimScope] = claims.Scope
+	userdata, err := json.Marshal(values)
 	if err != nil {
 		return err
 	}
@@ -2,6 +2,9 @@ package config
 
 import (
 	"github.com/gopherd/doge/config"
+
+	"github.com/gopherd/gopherd/auth/geo/policy"
+	"github.com/gopherd/gopherd/auth/scope"
 )
 
 // Config represents config of gated service
@@ -24,18 +27,44 @@ type Config struct {
 	TimeoutForUnauthorizedConn  int    `json:"timeout_for_unauthorized_conn"`
 	DefaultLocationForUnknownIP string `json:"default_location_for_unknown_ip"`
 	JWT                         struct {
-		Filename string `json:"filename"`
-		Issuer   string `json:"issuer"`
-		KeyId    string `json:"key_id"`
+		Filename       string `json:"filename"`
+		Issuer         string `json:"issuer"`
+		KeyId          string `json:"key_id"`
+		KeyDir         string `json:"key_dir"`         // directory of public keys named by key ids, e.g. 2022-06.pub.p8
+		ReloadInterval int64  `json:"reload_interval"` // seconds to reload keys of key_dir, default: 60
 	} `json:"jwt"`
-	Limiter struct {
+	GeoIP struct {
+		Filepath string        `json:"filepath"`
+		Policy   policy.Config `json:"policy"` // geoip opened only if policy enabled
+	} `json:"geoip"`
+	// Scope configures scopes of access tokens required by login and message types,
+	// any one of space-separated scopes is required
+	Scope struct {
+		Login   string        `json:"login"`   // default: game
+		Default string        `json:"default"` // required by message types out of ranges, default: game
+		Ranges  []scope.Range `json:"ranges"`
+	} `json:"scope"`
+	// Permissions configures permissions granted by roles of accounts which
+	// are required by message types, e.g. GM commands
+	Permissions []PermissionRange `json:"permissions"`
+	Limiter     struct {
 		MsgInterval       int `json:"msg_interval"`
 		MsgCount          int `json:"msg_count"`
 		BroadcastInterval int `json:"broadcast_interval"`
 	} `json:"limiter"`
 }
 
+// PermissionRange represents a permission required by message types in [From, To]
+type PermissionRange struct {
+	From       uint32 `json:"from"`
+	To         uint32 `json:"to"`
+	Permission string `json:"permission"`
+}
+
 // Default implements config.Configurator Default method
 func (*Config) Default() config.Configurator {
-	return new(Config)
+	c := new(Config)
+	c.Scope.Login = "game"
+	c.Scope.Default = "game"
+	return c
 }
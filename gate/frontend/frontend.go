@@ -1,11 +1,75 @@
 package frontend
 
 import (
+	"errors"
+	"path"
+	"strconv"
+	"strings"
+
 	"github.com/gopherd/doge/proto"
 	"github.com/gopherd/gopherd/proto/gatepb"
 )
 
-const UsersTable = "gated/users"
+const (
+	UsersTable   = "gated/users"
+	RevokedTable = "gated/revoked"
+)
+
+// UserKey returns key of the logged user in discovery
+func UserKey(project string, uid int64) string {
+	return path.Join(project, UsersTable, strconv.FormatInt(uid, 10))
+}
+
+// RevokedKey returns key of revoked access tokens of the login session in
+// discovery, or of all access tokens of the user if session is empty. Content
+// of the key is the unix time of revocation, tokens issued until then are
+// rejected by gated.
+func RevokedKey(project string, uid int64, session string) string {
+	key := path.Join(project, RevokedTable, strconv.FormatInt(uid, 10))
+	if session != "" {
+		key = path.Join(key, session)
+	}
+	return key
+}
+
+// LoggedUser represents content of the logged user in discovery
+type LoggedUser struct {
+	GID     int64  // id of gated
+	SID     int64  // id of client session in gated
+	Session string // id of login session of the access token, maybe empty
+}
+
+func (u LoggedUser) String() string {
+	buf := make([]byte, 0, 64)
+	buf = strconv.AppendInt(buf, u.GID, 10)
+	buf = append(buf, ',')
+	buf = strconv.AppendInt(buf, u.SID, 10)
+	if u.Session != "" {
+		buf = append(buf, ',')
+		buf = append(buf, u.Session...)
+	}
+	return string(buf)
+}
+
+// ParseLoggedUser parses content of the logged user in discovery
+func ParseLoggedUser(content string) (LoggedUser, error) {
+	var u LoggedUser
+	fields := strings.SplitN(content, ",", 3)
+	if len(fields) < 2 {
+		return u, errors.New("invalid logged user: " + content)
+	}
+	var err error
+	if u.GID, err = strconv.ParseInt(fields[0], 10, 64); err != nil {
+		return u, err
+	}
+	if u.SID, err = strconv.ParseInt(fields[1], 10, 64); err != nil {
+		return u, err
+	}
+	if len(fields) > 2 {
+		u.Session = fields[2]
+	}
+	return u, nil
+}
 
 // Module managers client sessions
 type Module interface {
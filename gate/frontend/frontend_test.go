@@ -0,0 +1,23 @@
+package frontend
+
+import "testing"
+
+func TestLoggedUser(t *testing.T) {
+	for _, u := range []LoggedUser{
+		{GID: 1101, SID: 42},
+		{GID: 1101, SID: 42, Session: "a1b2c3"},
+	} {
+		got, err := ParseLoggedUser(u.String())
+		if err != nil {
+			t.Fatalf("parse %q error: %v", u.String(), err)
+		}
+		if got != u {
+			t.Fatalf("parse %q: want %+v, got %+v", u.String(), u, got)
+		}
+	}
+	for _, content := range []string{"", "1101", "x,42", "1101,y"} {
+		if _, err := ParseLoggedUser(content); err == nil {
+			t.Fatalf("parse %q: error expected", content)
+		}
+	}
+}
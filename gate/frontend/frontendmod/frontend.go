@@ -8,7 +8,6 @@ import (
 	"io"
 	"io/ioutil"
 	"net"
-	"path"
 	"strconv"
 	"strings"
 	"sync"
@@ -25,14 +24,22 @@ import (
 	"github.com/gopherd/doge/text/resp"
 	"github.com/gopherd/doge/time/timer"
 	"github.com/gopherd/jwt"
+	"github.com/oschwald/geoip2-golang"
 	"golang.org/x/net/websocket"
 
+	"github.com/gopherd/gopherd/auth/api"
+	"github.com/gopherd/gopherd/auth/geo/policy"
+	"github.com/gopherd/gopherd/auth/keyring"
+	"github.com/gopherd/gopherd/auth/scope"
 	"github.com/gopherd/gopherd/gate/backend"
 	"github.com/gopherd/gopherd/gate/config"
 	"github.com/gopherd/gopherd/gate/frontend"
 	"github.com/gopherd/gopherd/proto/gatepb"
 )
 
+// defaultKeyReloadInterval is default seconds to reload keys of the key directory
+const defaultKeyReloadInterval = 60
+
 // New returns a frontend moudle
 func New(service Service) interface {
 	module.Module
@@ -55,7 +62,13 @@ type frontendModule struct {
 	service      Service
 	shuttingDown int32
 
-	verifier *jwt.Verifier
+	verifier       *keyring.Verifier
+	verifierTicker *timer.Ticker
+	scopes         *scope.Policy
+	geo            struct {
+		db     *geoip2.Reader
+		policy *policy.Policy
+	}
 	server   interface{ Serve(net.Listener) error }
 	listener net.Listener
 
@@ -66,7 +79,7 @@ type frontendModule struct {
 
 func newFrontendModule(service Service) *frontendModule {
 	mod := &frontendModule{
-		BasicModule:            module.NewBasicModule("frontend"),
+		BasicModule:           module.NewBasicModule("frontend"),
 		service:               service,
 		pendingSessionsTicker: timer.NewTicker(time.Second),
 	}
@@ -82,10 +95,33 @@ func (mod *frontendModule) Init() error {
 	cfg := mod.service.Config()
 
 	// create jwt verifier
-	if verifier, err := jwt.NewVerifier(cfg.JWT.Filename, cfg.JWT.KeyId); err != nil {
+	mod.verifier = keyring.NewVerifier()
+	if err := mod.loadKeys(); err != nil {
 		return erron.Throw(err)
+	}
+	if cfg.JWT.KeyDir != "" {
+		interval := cfg.JWT.ReloadInterval
+		if interval <= 0 {
+			interval = defaultKeyReloadInterval
+		}
+		mod.verifierTicker = timer.NewTicker(time.Duration(interval) * time.Second)
+	}
+
+	// compile scope policy of message types
+	if scopes, err := scope.NewPolicy(cfg.Scope.Ranges, cfg.Scope.Default); err != nil {
+		return erron.Throwf("invalid scope policy: %w", err)
 	} else {
-		mod.verifier = verifier
+		mod.scopes = scopes
+	}
+
+	// open geoip for geo policy
+	if cfg.GeoIP.Policy.Enabled() {
+		db, err := geoip2.Open(cfg.GeoIP.Filepath)
+		if err != nil {
+			return erron.Throwf("load geoip from %q error: %w", cfg.GeoIP.Filepath, err)
+		}
+		mod.geo.db = db
+		mod.geo.policy = policy.New(cfg.GeoIP.Policy)
 	}
 
 	// init sessions
@@ -136,6 +172,10 @@ func (mod *frontendModule) Shutdown() {
 
 func (mod *frontendModule) clean() {
 	mod.sessions.shutdown()
+	if mod.geo.db != nil {
+		mod.geo.db.Close()
+		mod.geo.db = nil
+	}
 }
 
 // Update overrides BasicModule Update method
@@ -163,16 +203,71 @@ func (mod *frontendModule) Update(now time.Time, dt time.Duration) {
 			}
 		}
 		mod.sessions.clean(now)
+		if mod.verifierTicker != nil && mod.verifierTicker.Next(now) {
+			if err := mod.loadKeys(); err != nil {
+				mod.Logger().Warn().
+					String("key_dir", mod.service.Config().JWT.KeyDir).
+					Error("error", err).
+					Print("reload jwt keys error")
+			}
+		}
 	}
 }
 
+// loadKeys loads the configured key and keys of the key directory
+func (mod *frontendModule) loadKeys() error {
+	cfg := mod.service.Config()
+	var keys []keyring.Key
+	if cfg.JWT.Filename != "" {
+		keys = append(keys, keyring.Key{Filename: cfg.JWT.Filename, KeyId: cfg.JWT.KeyId})
+	}
+	if cfg.JWT.KeyDir != "" {
+		dirKeys, err := keyring.ReadDir(cfg.JWT.KeyDir)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, dirKeys...)
+	}
+	if len(keys) == 0 {
+		return errors.New("no jwt keys")
+	}
+	return mod.verifier.Load(keys)
+}
+
 // Busy implements frontend.Module Busy method
 func (mod *frontendModule) Busy() bool {
 	return mod.sessions.size() > 0
 }
 
+// allowed reports whether the ip is allowed by geo policy
+func (mod *frontendModule) allowed(ip string) bool {
+	if mod.geo.db == nil {
+		return true
+	}
+	x := net.ParseIP(ip)
+	if len(x) == 0 || x.IsUnspecified() {
+		return mod.geo.policy.Allowed("", "")
+	}
+	r, err := mod.geo.db.City(x)
+	if err != nil {
+		return mod.geo.policy.Allowed("", "")
+	}
+	var region string
+	if len(r.Subdivisions) > 0 {
+		region = r.Subdivisions[0].IsoCode
+	}
+	return mod.geo.policy.Allowed(r.Country.IsoCode, region)
+}
+
 // onOpen implements handler onOpen method
 func (mod *frontendModule) onOpen(ip string, conn net.Conn) {
+	if !mod.allowed(ip) {
+		mod.Logger().Info().
+			String("ip", ip).
+			Print("connection rejected because of region blocked")
+		conn.Close()
+		return
+	}
 	sid := mod.sessions.allocSessionId()
 	mod.Logger().Debug().
 		Int64("sid", sid).
@@ -201,7 +296,15 @@ func (mod *frontendModule) onReady(s *session) {
 
 // onClose implements handler onClose method
 func (mod *frontendModule) onClose(s *session, err error) {
-	mod.Logger().Debug().Int64("sid", s.id).Print("session closed")
+	if impersonator := s.getUser().impersonator; impersonator != "" {
+		mod.Logger().Info().
+			Int64("sid", s.id).
+			Int64("uid", s.getUid()).
+			String("impersonator", impersonator).
+			Print("impersonated session closed")
+	} else {
+		mod.Logger().Debug().Int64("sid", s.id).Print("session closed")
+	}
 	if s.getUid() > 0 {
 		mod.afterLogout(s)
 	} else {
@@ -307,15 +410,17 @@ func (mod *frontendModule) unmarshal(s *session, typ proto.Type, body proto.Body
 }
 
 func (mod *frontendModule) userKey(uid int64) string {
-	return path.Join(mod.service.Config().Core.Project, frontend.UsersTable, strconv.FormatInt(uid, 10))
+	return frontend.UserKey(mod.service.Config().Core.Project, uid)
 }
 
-func (mod *frontendModule) setUserLogged(uid, sid int64, nx bool) (bool, error) {
-	buf := make([]byte, 0, 32)
-	buf = strconv.AppendInt(buf, mod.service.ID(), 10)
-	buf = append(buf, ',')
-	buf = strconv.AppendInt(buf, sid, 10)
-	content := string(buf)
+func (mod *frontendModule) setUserLogged(s *session, nx bool) (bool, error) {
+	uid := s.getUid()
+	loginSession, _ := s.getUser().token.Values[api.ClaimSession].(string)
+	content := frontend.LoggedUser{
+		GID:     mod.service.ID(),
+		SID:     s.id,
+		Session: loginSession,
+	}.String()
 	ttl := time.Duration(mod.service.Config().UserTTL) * time.Second
 	err := mod.service.Discovery().Register(context.Background(), "", mod.userKey(uid), content, nx, ttl)
 	if err != nil {
@@ -354,6 +459,29 @@ func (mod *frontendModule) forward(s *session, typ proto.Type, body proto.Body)
 			Print("read body error")
 		return err
 	}
+	if uid := s.getUid(); uid > 0 && !mod.scopes.Allowed(s.getUser().scope, typ) {
+		mod.Logger().Info().
+			Int64("uid", uid).
+			Int("type", int(typ)).
+			String("scope", s.getUser().scope.String()).
+			Print("message dropped because of scope not granted")
+		return s.send(&gatepb.Error{
+			Errno:       api.ScopeDenied,
+			Description: "scope not granted",
+		})
+	}
+	if required, ok := permitted(s.getUser().permissions, mod.service.Config().Permissions, typ); !ok {
+		mod.Logger().Info().
+			Int64("uid", s.getUid()).
+			Int("type", int(typ)).
+			String("permission", required).
+			String("impersonator", s.getUser().impersonator).
+			Print("message dropped because of permission not granted")
+		return s.send(&gatepb.Error{
+			Errno:       api.PermissionDenied,
+			Description: "permission denied",
+		})
+	}
 	f := s.getForward()
 	f.Gid = mod.service.ID()
 	f.Uid = s.getUid()
@@ -362,6 +490,39 @@ func (mod *frontendModule) forward(s *session, typ proto.Type, body proto.Body)
 	return mod.service.Backend().Forward(f)
 }
 
+// revoked reports whether the access token has been revoked by authd, i.e.
+// it's issued until revocation of its login session or all tokens of the user
+func (mod *frontendModule) revoked(claims *jwt.Claims) bool {
+	project := mod.service.Config().Core.Project
+	keys := []string{frontend.RevokedKey(project, claims.Payload.ID, "")}
+	if session, _ := claims.Payload.Values[api.ClaimSession].(string); session != "" {
+		keys = append(keys, frontend.RevokedKey(project, claims.Payload.ID, session))
+	}
+	for _, key := range keys {
+		// not found keys are reported as errors by some drivers
+		content, err := mod.service.Discovery().Find(context.Background(), "", key)
+		if err != nil || content == "" {
+			continue
+		}
+		if revokedAt, err := strconv.ParseInt(content, 10, 64); err == nil && claims.IssuedAt <= revokedAt {
+			return true
+		}
+	}
+	return false
+}
+
+// permitted reports whether permissions contain the permission required by
+// the message type, the required permission is returned, empty string
+// returned if no permission required
+func permitted(permissions map[string]bool, ranges []config.PermissionRange, typ proto.Type) (string, bool) {
+	for _, r := range ranges {
+		if uint32(typ) >= r.From && uint32(typ) <= r.To {
+			return r.Permission, permissions[r.Permission]
+		}
+	}
+	return "", true
+}
+
 // ping handles Ping message
 func (mod *frontendModule) ping(s *session, typ proto.Type, body proto.Body) error {
 	if mod.service.Config().ForwardPing {
@@ -371,7 +532,7 @@ func (mod *frontendModule) ping(s *session, typ proto.Type, body proto.Body) err
 	} else {
 		ttl := int64(mod.service.Config().UserTTL) * 1000
 		if s.trySetLastUpdateSidTime(ttl/2, time.Now().UnixNano()/1e6) {
-			_, err := mod.setUserLogged(s.getUid(), s.id, false)
+			_, err := mod.setUserLogged(s, false)
 			if err != nil {
 				return err
 			}
@@ -412,11 +573,66 @@ func (mod *frontendModule) login(s *session, req *gatepb.LoginRequest) error {
 		String("ip", claims.Payload.IP).
 		Print("user logging")
 
+	// tokens of service clients have no account
+	if _, ok := claims.Payload.Values[api.ClaimClient]; ok || claims.Payload.ID <= 0 {
+		mod.Logger().Warn().
+			Int64("sid", s.id).
+			Int64("uid", claims.Payload.ID).
+			Print("user login denied because of token without account")
+		s.send(&gatepb.Error{
+			Errno:       api.Unauthorized,
+			Description: "unauthorized",
+		})
+		s.Close(nil)
+		return nil
+	}
+	if mod.revoked(claims) {
+		mod.Logger().Info().
+			Int64("sid", s.id).
+			Int64("uid", claims.Payload.ID).
+			Print("user login denied because of token revoked")
+		s.send(&gatepb.Error{
+			Errno:       api.Unauthorized,
+			Description: "token revoked",
+		})
+		s.Close(nil)
+		return nil
+	}
+
 	// overrides ip
 	if claims.Payload.IP != "" {
 		s.ip = claims.Payload.IP
 	}
 
+	granted, _ := scope.Parse(claims.Payload.Scope)
+	if required, _ := scope.Parse(cfg.Scope.Login); required != scope.None && !granted.Any(required) {
+		mod.Logger().Info().
+			Int64("sid", s.id).
+			Int64("uid", claims.Payload.ID).
+			String("scope", claims.Payload.Scope).
+			Print("user login denied because of scope not granted")
+		s.send(&gatepb.Error{
+			Errno:       api.ScopeDenied,
+			Description: "scope not granted",
+		})
+		s.Close(nil)
+		return nil
+	}
+
+	if !mod.allowed(s.ip) {
+		mod.Logger().Info().
+			Int64("sid", s.id).
+			Int64("uid", claims.Payload.ID).
+			String("ip", s.ip).
+			Print("user login denied because of region blocked")
+		s.send(&gatepb.Error{
+			Errno:       api.RegionBlocked,
+			Description: "region blocked",
+		})
+		s.Close(nil)
+		return nil
+	}
+
 	if !mod.sessions.recordIP(s.id, s.ip) {
 		mod.Logger().Warn().
 			Int64("sid", s.id).
@@ -426,11 +642,32 @@ func (mod *frontendModule) login(s *session, req *gatepb.LoginRequest) error {
 		return errors.New("ip limited")
 	}
 
+	var permissions map[string]bool
+	if v, _ := claims.Payload.Values[api.ClaimPermissions].(string); v != "" {
+		permissions = make(map[string]bool)
+		for _, p := range strings.Fields(v) {
+			permissions[p] = true
+		}
+	}
+	impersonator, _ := claims.Payload.Values[api.ClaimImpersonator].(string)
+	if impersonator != "" {
+		operatorIP, _ := claims.Payload.Values[api.ClaimOperatorIP].(string)
+		mod.Logger().Warn().
+			Int64("sid", s.id).
+			Int64("uid", claims.Payload.ID).
+			String("unverified_impersonator", impersonator).
+			String("operator_ip", operatorIP).
+			String("ip", s.ip).
+			Print("impersonated user logging")
+	}
 	s.setUser(user{
-		token: claims.Payload,
+		token:        claims.Payload,
+		scope:        granted,
+		permissions:  permissions,
+		impersonator: impersonator,
 	})
 
-	if ok, err := mod.setUserLogged(claims.Payload.ID, s.id, true); err != nil {
+	if ok, err := mod.setUserLogged(s, true); err != nil {
 		return err
 	} else if !ok {
 		mod.pendingSessions.Store(s.id, &pendingSession{
@@ -459,7 +696,7 @@ func (mod *frontendModule) retryLogin(sid int64, ps *pendingSession, now int64)
 			Print("session state is not pending")
 		return true
 	}
-	if ok, err := mod.setUserLogged(ps.uid, sid, true); err != nil {
+	if ok, err := mod.setUserLogged(s, true); err != nil {
 		mod.Logger().Debug().
 			Int64("sid", sid).
 			Int64("uid", ps.uid).
@@ -0,0 +1,100 @@
+package frontendmod
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gopherd/doge/proto"
+	"github.com/gopherd/doge/service/discovery"
+	"github.com/gopherd/jwt"
+
+	"github.com/gopherd/gopherd/auth/api"
+	"github.com/gopherd/gopherd/gate/config"
+	"github.com/gopherd/gopherd/gate/frontend"
+)
+
+// testDiscovery finds contents of keys, not found keys are reported as errors
+type testDiscovery struct {
+	discovery.Discovery
+	contents map[string]string
+}
+
+func (d *testDiscovery) Find(ctx context.Context, name, id string) (string, error) {
+	if content, ok := d.contents[id]; ok {
+		return content, nil
+	}
+	return "", errors.New("not found")
+}
+
+type testService struct {
+	Service
+	config    *config.Config
+	discovery *testDiscovery
+}
+
+func (s *testService) Config() *config.Config         { return s.config }
+func (s *testService) Discovery() discovery.Discovery { return s.discovery }
+
+func TestPermitted(t *testing.T) {
+	ranges := []config.PermissionRange{
+		{From: 9000, To: 9099, Permission: "gm.kick"},
+		{From: 9100, To: 9199, Permission: "gm.item"},
+		{From: 9150, To: 9150, Permission: "gm.shadowed"},
+	}
+	gm := map[string]bool{"gm.kick": true}
+	for _, tc := range []struct {
+		permissions map[string]bool
+		typ         proto.Type
+		required    string
+		ok          bool
+	}{
+		{nil, 100, "", true},
+		{nil, 9000, "gm.kick", false},
+		{gm, 9000, "gm.kick", true},
+		{gm, 9099, "gm.kick", true},
+		{gm, 9100, "gm.item", false},
+		{gm, 9150, "gm.item", false},
+		{map[string]bool{"gm.shadowed": true}, 9150, "gm.item", false},
+		{gm, 9200, "", true},
+	} {
+		required, ok := permitted(tc.permissions, ranges, tc.typ)
+		if required != tc.required || ok != tc.ok {
+			t.Fatalf("permissions %v, type %d: want %q, %v, got %q, %v", tc.permissions, tc.typ, tc.required, tc.ok, required, ok)
+		}
+	}
+}
+
+func TestRevoked(t *testing.T) {
+	cfg := new(config.Config).Default().(*config.Config)
+	cfg.Core.Project = "test"
+	d := &testDiscovery{contents: map[string]string{
+		frontend.RevokedKey("test", 1, ""):   "1000",
+		frontend.RevokedKey("test", 2, "s1"): "2000",
+	}}
+	mod := newFrontendModule(&testService{config: cfg, discovery: d})
+	for _, tc := range []struct {
+		uid      int64
+		session  string
+		issuedAt int64
+		revoked  bool
+	}{
+		{1, "", 999, true},
+		{1, "s1", 1000, true},
+		{1, "s1", 1001, false},
+		{2, "s1", 1999, true},
+		{2, "s2", 1999, false},
+		{2, "", 1999, false},
+		{3, "s1", 1, false},
+	} {
+		claims := new(jwt.Claims)
+		claims.IssuedAt = tc.issuedAt
+		claims.Payload.ID = tc.uid
+		if tc.session != "" {
+			claims.Payload.Values = map[string]any{api.ClaimSession: tc.session}
+		}
+		if got := mod.revoked(claims); got != tc.revoked {
+			t.Fatalf("uid %d session %q issued at %d: want revoked %v, got %v", tc.uid, tc.session, tc.issuedAt, tc.revoked, got)
+		}
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/gopherd/jwt"
 	"github.com/gopherd/log"
 
+	"github.com/gopherd/gopherd/auth/scope"
 	"github.com/gopherd/gopherd/proto/gatepb"
 )
 
@@ -38,7 +39,10 @@ const (
 
 // userdata of session
 type user struct {
-	token jwt.Payload
+	token        jwt.Payload
+	scope        scope.Scope
+	permissions  map[string]bool // permissions granted by roles of the user
+	impersonator string          // operator who impersonates the user, empty if not impersonated
 }
 
 // session event handler
@@ -196,6 +200,10 @@ func (s *session) getUser() user {
 func (s *session) setUser(user user) {
 	s.internal.user = user
 	atomic.StoreInt64(&s.internal.uid, user.token.ID)
+	if user.impersonator != "" {
+		// tag logs of impersonated sessions
+		s.logger = log.Prefix(s.logger.Logger(), path.Join(s.logger.Prefix(), "impersonated_by", user.impersonator))
+	}
 }
 
 func (s *session) getLastKeepaliveTime() int64 {
@@ -3,6 +3,8 @@ module github.com/gopherd/gopherd
 go 1.18
 
 require (
+	github.com/go-redis/redis/v8 v8.10.0
+	github.com/go-sql-driver/mysql v1.6.0
 	github.com/gopherd/doge v0.1.2
 	github.com/gopherd/gorm_logger_wrapper v0.0.2
 	github.com/gopherd/jwt v0.0.5
@@ -10,7 +12,9 @@ require (
 	github.com/gopherd/redis v0.0.15
 	github.com/gopherd/zmq v0.0.9
 	github.com/oschwald/geoip2-golang v1.5.0
+	github.com/prometheus/client_golang v1.10.0
 	golang.org/x/net v0.0.0-20210805182204-aaa1db679c0d
+	golang.org/x/text v0.3.6
 	google.golang.org/api v0.52.0
 	google.golang.org/protobuf v1.27.1
 	gorm.io/driver/mysql v1.1.2
@@ -19,25 +23,27 @@ require (
 
 require (
 	cloud.google.com/go v0.88.0 // indirect
+	github.com/beorn7/perks v1.0.1 // indirect
 	github.com/cespare/xxhash/v2 v2.1.1 // indirect
 	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
-	github.com/go-redis/redis/v8 v8.10.0 // indirect
-	github.com/go-sql-driver/mysql v1.6.0 // indirect
 	github.com/golang-jwt/jwt/v4 v4.2.0 // indirect
 	github.com/golang/groupcache v0.0.0-20200121045136-8c9f03a8e57e // indirect
 	github.com/golang/protobuf v1.5.2 // indirect
 	github.com/google/uuid v1.2.0 // indirect
 	github.com/jinzhu/inflection v1.0.0 // indirect
 	github.com/jinzhu/now v1.1.2 // indirect
+	github.com/matttproud/golang_protobuf_extensions v1.0.1 // indirect
 	github.com/oschwald/maxminddb-golang v1.8.0 // indirect
 	github.com/pebbe/zmq4 v1.2.7 // indirect
+	github.com/prometheus/client_model v0.2.0 // indirect
+	github.com/prometheus/common v0.18.0 // indirect
+	github.com/prometheus/procfs v0.6.0 // indirect
 	go.opencensus.io v0.23.0 // indirect
 	go.opentelemetry.io/otel v0.20.0 // indirect
 	go.opentelemetry.io/otel/metric v0.20.0 // indirect
 	go.opentelemetry.io/otel/trace v0.20.0 // indirect
 	golang.org/x/oauth2 v0.0.0-20210628180205-a41e5a781914 // indirect
 	golang.org/x/sys v0.0.0-20210630005230-0f9fa26af87c // indirect
-	golang.org/x/text v0.3.6 // indirect
 	google.golang.org/appengine v1.6.7 // indirect
 	google.golang.org/genproto v0.0.0-20210722135532-667f2b7c528f // indirect
 	google.golang.org/grpc v1.39.0 // indirect